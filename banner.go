@@ -0,0 +1,79 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"runtime/debug"
+	"strings"
+	"text/template"
+
+	plugin "github.com/golang/protobuf/protoc-gen-go/plugin"
+)
+
+// version is the version of the plugin, set with
+// -ldflags "-X main.version=v1.2.3" or taken from the module version when
+// installed with go install.
+var version string
+
+// compilerVersion is the version of protoc that invoked the plugin.
+var compilerVersion = "(unknown)"
+
+const defaultBanner = `This file has been generated by https://github.com/horizon-games/protoc-gen-twirp_ts.
+Do not edit.
+
+versions:
+  protoc-gen-twirp_ts {{.Version}}
+  protoc {{.CompilerVersion}}
+{{- with .Source}}
+source: {{.}}
+{{- end}}`
+
+func pluginVersion() string {
+	if version != "" {
+		return version
+	}
+	if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" && info.Main.Version != "(devel)" {
+		return info.Main.Version
+	}
+	return "(unknown)"
+}
+
+func formatCompilerVersion(v *plugin.Version) string {
+	if v == nil {
+		return "(unknown)"
+	}
+	s := fmt.Sprintf("v%d.%d.%d", v.GetMajor(), v.GetMinor(), v.GetPatch())
+	if v.GetSuffix() != "" {
+		s += "-" + v.GetSuffix()
+	}
+	return s
+}
+
+// banner returns the comment at the top of generated files, executing the
+// banner parameter with the versions of the plugin and protoc and the name of
+// the .proto file source, which is empty for the runtime and index files.
+func banner(source string) (string, error) {
+	if params.Banner == "none" {
+		return "", nil
+	}
+
+	t, err := template.New("banner").Parse(params.Banner)
+	if err != nil {
+		return "", err
+	}
+	buf := bytes.NewBuffer(nil)
+	err = t.Execute(buf, struct {
+		Version         string
+		CompilerVersion string
+		Source          string
+	}{pluginVersion(), compilerVersion, source})
+	if err != nil {
+		return "", err
+	}
+
+	var lines []string
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		lines = append(lines, strings.TrimRight("// "+line, " "))
+	}
+	return strings.Join(lines, "\n"), nil
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+)
+
+// batchValues describes the batch method that calls of a batchable method
+// are collected into, set with the option named by the batch_option
+// parameter.
+type batchValues struct {
+	// Method is the name of the batch method, InputType the type of its
+	// input. Requests and Responses are the repeated fields of its input and
+	// output holding the requests and responses of the batched method, in
+	// the same order.
+	Method    string
+	InputType string
+	Requests  string
+	Responses string
+}
+
+// batchMethod returns the name of the batch method of a method, set with the
+// option named by the batch_option parameter, or "" without one.
+func batchMethod(options []*optionValue) (string, error) {
+	if params.BatchOption == "" {
+		return "", nil
+	}
+	for _, o := range options {
+		if o.Name != params.BatchOption {
+			continue
+		}
+		var name string
+		if err := json.Unmarshal([]byte(o.Value), &name); err != nil {
+			return "", fmt.Errorf("option %s must be a string naming a method", o.Name)
+		}
+		return name, nil
+	}
+	return "", nil
+}
+
+// Batch resolves the batch method name of method in service. Its input must
+// have a repeated field of the input type of method and its output a repeated
+// field of the output type of method.
+func (d *dependencyResolver) Batch(service *descriptor.ServiceDescriptorProto, method *descriptor.MethodDescriptorProto, name string) (*batchValues, error) {
+	if method.GetInputType() == emptyTypeName {
+		return nil, fmt.Errorf("method %s.%s without input can't be batched", service.GetName(), method.GetName())
+	}
+	var batch *descriptor.MethodDescriptorProto
+	for _, m := range service.GetMethod() {
+		if m.GetName() == name {
+			batch = m
+		}
+	}
+	if batch == nil {
+		return nil, fmt.Errorf("batch method %s of %s.%s not found", name, service.GetName(), method.GetName())
+	}
+
+	repeatedField := func(message, typeName string) (string, error) {
+		msg, ok := d.messages[message]
+		if ok {
+			for _, field := range msg.GetField() {
+				if isRepeated(field) && field.GetTypeName() == typeName {
+					if generateClasses() {
+						return fieldName(field.GetName()), nil
+					}
+					return field.GetName(), nil
+				}
+			}
+		}
+		return "", fmt.Errorf("%s of batch method %s.%s has no repeated %s field", message, service.GetName(), name, typeName)
+	}
+	requests, err := repeatedField(batch.GetInputType(), method.GetInputType())
+	if err != nil {
+		return nil, err
+	}
+	responses, err := repeatedField(batch.GetOutputType(), method.GetOutputType())
+	if err != nil {
+		return nil, err
+	}
+
+	inputType := d.TypeName(batch.GetInputType())
+	if !generateClasses() {
+		inputType = typeToJSONInterface(inputType)
+	}
+	return &batchValues{
+		Method:    batch.GetName(),
+		InputType: inputType,
+		Requests:  requests,
+		Responses: responses,
+	}, nil
+}
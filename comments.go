@@ -0,0 +1,111 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+)
+
+// leadingComments returns the comments before the element at path in fd,
+// without the comment markers, or an empty string without source info.
+func leadingComments(fd *descriptor.FileDescriptorProto, path []int32) string {
+	for _, loc := range fd.GetSourceCodeInfo().GetLocation() {
+		if samePath(loc.GetPath(), path) {
+			return loc.GetLeadingComments()
+		}
+	}
+	return ""
+}
+
+// sourceLocation returns the position of the element at path in fd for the
+// comments of the source_locations parameter, or an empty string without it.
+func sourceLocation(fd *descriptor.FileDescriptorProto, path []int32) string {
+	if !params.SourceLocations {
+		return ""
+	}
+	return sourcePosition(fd, path)
+}
+
+// docComment returns the TSDoc comment of an element from its proto comments
+// doc, or an empty string when there's nothing to document. The fenced code
+// blocks of doc become @example sections after the description, so editors
+// show them in hovers, and deprecated adds a @deprecated tag.
+func docComment(doc string, deprecated bool) string {
+	var description, examples []string
+	var fence string
+	for _, line := range dedent(doc) {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case fence == "" && (strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~")):
+			fence = trimmed[:3]
+			examples = append(examples, "", "@example", trimmed)
+		case fence != "":
+			examples = append(examples, line)
+			if trimmed == fence {
+				fence = ""
+			}
+		default:
+			description = append(description, line)
+		}
+	}
+	if fence != "" {
+		examples = append(examples, fence)
+	}
+
+	lines := trimBlankLines(description)
+	if len(lines) == 0 && len(examples) > 0 {
+		examples = examples[1:]
+	}
+	lines = append(lines, examples...)
+	if deprecated {
+		if len(lines) == 0 {
+			return "/** @deprecated */"
+		}
+		lines = append(lines, "", "@deprecated")
+	}
+	if len(lines) == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	b.WriteString("/**\n")
+	for _, line := range lines {
+		b.WriteString(strings.TrimRight(" * "+strings.Replace(line, "*/", "*\\/", -1), " ") + "\n")
+	}
+	b.WriteString(" */")
+	return b.String()
+}
+
+// dedent returns the lines of the comments s without the indentation they
+// have in common, protoc keeps the space after the comment markers.
+func dedent(s string) []string {
+	lines := strings.Split(strings.TrimRight(s, " \n"), "\n")
+	indent := -1
+	for _, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		n := len(line) - len(strings.TrimLeft(line, " \t"))
+		if indent < 0 || n < indent {
+			indent = n
+		}
+	}
+	for i, line := range lines {
+		if len(line) >= indent && indent > 0 {
+			line = line[indent:]
+		}
+		lines[i] = strings.TrimRight(line, " \t")
+	}
+	return lines
+}
+
+// trimBlankLines removes the blank lines at the start and end of lines.
+func trimBlankLines(lines []string) []string {
+	for len(lines) > 0 && lines[0] == "" {
+		lines = lines[1:]
+	}
+	for len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// defaultConfigFile is read when no config parameter is given. Like the
+// config parameter, it's relative to the working directory of protoc rather
+// than to the .proto files, whose directories protoc doesn't pass to plugins.
+const defaultConfigFile = "twirp-ts.yaml"
+
+// readConfig reads the parameters set in a config file, for example:
+//
+//	long: string
+//	timestamp: date
+//	emit_defaults: true
+//
+// Keys are parameter names, lists set a parameter once per value and maps set
+// the parameters named by the key followed by the map keys, e.g. M imports:
+//
+//	M:
+//	  foo/bar.proto: "@scope/pkg/bar"
+//
+// A missing file is only an error if it was passed explicitly.
+func readConfig(path string, explicit bool) ([]keyValue, error) {
+	b, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) && !explicit {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var config yaml.MapSlice
+	if err := yaml.Unmarshal(b, &config); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+
+	var kvs []keyValue
+	for _, item := range config {
+		key := fmt.Sprint(item.Key)
+		switch v := item.Value.(type) {
+		case []interface{}:
+			for _, e := range v {
+				kvs = append(kvs, keyValue{key, fmt.Sprint(e)})
+			}
+		case yaml.MapSlice:
+			for _, e := range v {
+				kvs = append(kvs, keyValue{key + fmt.Sprint(e.Key), fmt.Sprint(e.Value)})
+			}
+		case nil:
+			kvs = append(kvs, keyValue{key, ""})
+		default:
+			kvs = append(kvs, keyValue{key, fmt.Sprint(v)})
+		}
+	}
+	return kvs, nil
+}
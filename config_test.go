@@ -0,0 +1,42 @@
+package main
+
+import (
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestReadConfig(t *testing.T) {
+	want := []keyValue{{"long", "string"}, {"emit_defaults", "true"}, {"Mfoo/bar.proto", "@acme/bar"}}
+
+	// Paths are relative to the working directory, the directory of the
+	// package in tests.
+	got, err := readConfig("testdata/twirp-ts.yaml", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("readConfig(testdata/twirp-ts.yaml) = %v, want %v", got, want)
+	}
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir("testdata"); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+	got, err = readConfig(defaultConfigFile, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("readConfig(%s) in testdata = %v, want %v", defaultConfigFile, got, want)
+	}
+
+	got, err = readConfig("missing.yaml", false)
+	if got != nil || err != nil {
+		t.Errorf("readConfig(missing.yaml) = %v, %v, want no parameters", got, err)
+	}
+}
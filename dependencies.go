@@ -2,22 +2,140 @@ package main
 
 import (
 	"errors"
+	"fmt"
+	"sort"
+	"strings"
 
 	"github.com/golang/protobuf/protoc-gen-go/descriptor"
 )
 
 type dependencyResolver struct {
 	v map[string]*descriptor.FileDescriptorProto
+
+	files    map[string]*descriptor.FileDescriptorProto
+	packages map[string]map[string]struct{}
+	enums    map[string]*descriptor.EnumDescriptorProto
+	messages map[string]*descriptor.DescriptorProto
+
+	// extensions maps extended types to their extension fields by number,
+	// they're used to decode custom options.
+	extensions map[string]map[int32]*extensionField
+
+	// identifiers maps fully qualified proto names to the flattened names
+	// they're generated as, e.g. .pkg.Message.Enum to Message_Enum.
+	identifiers map[string]string
+
+	// aliases maps the types imported by the file being generated to the
+	// names they're imported as when their name is already used, see
+	// UseFile.
+	aliases map[string]string
 }
 
-func (d *dependencyResolver) Set(fd *descriptor.FileDescriptorProto, messageName string) {
+// AddFile records the package level dependencies of fd, they're used to
+// detect import cycles between the generated package index files.
+func (d *dependencyResolver) AddFile(fd *descriptor.FileDescriptorProto) {
+	if d.files == nil {
+		d.files = make(map[string]*descriptor.FileDescriptorProto)
+		d.packages = make(map[string]map[string]struct{})
+		d.enums = make(map[string]*descriptor.EnumDescriptorProto)
+		d.messages = make(map[string]*descriptor.DescriptorProto)
+		d.extensions = make(map[string]map[int32]*extensionField)
+		d.identifiers = make(map[string]string)
+	}
 	if d.v == nil {
 		d.v = make(map[string]*descriptor.FileDescriptorProto)
 	}
-	typeName := fullTypeName(fd, messageName)
-	//log.Printf("-> typeName: %v (%v)", typeName, fd.GetName())
+	d.files[fd.GetName()] = fd
+
+	for _, enum := range fd.GetEnumType() {
+		d.addEnum(fd, []string{enum.GetName()}, enum)
+	}
+	for _, ext := range fd.GetExtension() {
+		d.addExtension(fullTypeName(fd, ext.GetName()), ext)
+	}
+	var addMessage func(msg *descriptor.DescriptorProto, parents []string)
+	addMessage = func(msg *descriptor.DescriptorProto, parents []string) {
+		parents = append(parents[:len(parents):len(parents)], msg.GetName())
+		d.addType(fd, parents)
+		d.messages[fullTypeName(fd, strings.Join(parents, "."))] = msg
+		for _, ext := range msg.GetExtension() {
+			d.addExtension(fullTypeName(fd, strings.Join(append(parents[:len(parents):len(parents)], ext.GetName()), ".")), ext)
+		}
+		for _, enum := range msg.GetEnumType() {
+			d.addEnum(fd, append(parents[:len(parents):len(parents)], enum.GetName()), enum)
+		}
+		for _, nested := range msg.GetNestedType() {
+			addMessage(nested, parents)
+		}
+	}
+	for _, msg := range fd.GetMessageType() {
+		addMessage(msg, nil)
+	}
+	for _, service := range fd.GetService() {
+		d.addType(fd, []string{service.GetName()})
+	}
+
+	from := tsImportPath(fd)
+	if d.packages[from] == nil {
+		d.packages[from] = make(map[string]struct{})
+	}
+	for _, dep := range fd.GetDependency() {
+		if dfd, ok := d.files[dep]; ok {
+			d.packages[from][tsImportPath(dfd)] = struct{}{}
+		}
+	}
+}
+
+// addType registers a type by its path of names within fd, nested types are
+// flattened into Outer_Inner.
+func (d *dependencyResolver) addType(fd *descriptor.FileDescriptorProto, path []string) {
+	name := fullTypeName(fd, strings.Join(path, "."))
+	d.v[name] = fd
+	d.identifiers[name] = safeIdentifier(strings.Join(path, "_"))
+}
+
+func (d *dependencyResolver) addExtension(name string, field *descriptor.FieldDescriptorProto) {
+	if d.extensions[field.GetExtendee()] == nil {
+		d.extensions[field.GetExtendee()] = make(map[int32]*extensionField)
+	}
+	d.extensions[field.GetExtendee()][field.GetNumber()] = &extensionField{
+		Name:  strings.TrimPrefix(name, "."),
+		Field: field,
+	}
+}
 
-	d.v[typeName] = fd
+func (d *dependencyResolver) addEnum(fd *descriptor.FileDescriptorProto, path []string, enum *descriptor.EnumDescriptorProto) {
+	d.addType(fd, path)
+	d.enums[fullTypeName(fd, strings.Join(path, "."))] = enum
+}
+
+// IsCyclic reports whether importing imprt from fd through its package index
+// would create a circular import, in which case classes may be used before
+// they're defined while the modules initialize.
+func (d *dependencyResolver) IsCyclic(fd *descriptor.FileDescriptorProto, imprt *descriptor.FileDescriptorProto) bool {
+	from, to := tsImportPath(fd), tsImportPath(imprt)
+	if from == to {
+		return true
+	}
+
+	visited := make(map[string]bool)
+	var reaches func(pkg string) bool
+	reaches = func(pkg string) bool {
+		if pkg == from {
+			return true
+		}
+		if visited[pkg] {
+			return false
+		}
+		visited[pkg] = true
+		for dep := range d.packages[pkg] {
+			if reaches(dep) {
+				return true
+			}
+		}
+		return false
+	}
+	return reaches(to)
 }
 
 func (d *dependencyResolver) Resolve(typeName string) (*descriptor.FileDescriptorProto, error) {
@@ -25,18 +143,113 @@ func (d *dependencyResolver) Resolve(typeName string) (*descriptor.FileDescripto
 	if fp == nil {
 		return nil, errors.New("no such type")
 	}
-	if typeName == ".google.protobuf.Timestamp" {
+	if typeName == timestampTypeName {
 		return nil, errors.New("type is replaced by native Date")
 	}
+	if isPlainJSONType(typeName) {
+		return nil, errors.New("type is replaced by plain JSON value")
+	}
 	return fp, nil
 }
 
-func (d *dependencyResolver) TypeName(fd *descriptor.FileDescriptorProto, typeName string) string {
-	orig, err := d.Resolve(fullTypeName(fd, typeName))
-	if err == nil {
-		if fd.GetPackage() != orig.GetPackage() {
-			return importName(orig) + "." + typeName
+// TypeName returns the name of a fully qualified proto type in the file
+// being generated: its generated name, e.g. .pkg.Outer.Inner is generated as
+// Outer_Inner, or the alias it's imported as.
+func (d *dependencyResolver) TypeName(typeName string) string {
+	if alias, ok := d.aliases[typeName]; ok {
+		return alias
+	}
+	return d.ExportedName(typeName)
+}
+
+// ExportedName returns the generated name of a fully qualified proto type.
+func (d *dependencyResolver) ExportedName(typeName string) string {
+	if id, ok := d.identifiers[typeName]; ok {
+		return id
+	}
+	return safeIdentifier(typeName[strings.LastIndex(typeName, ".")+1:])
+}
+
+// UseFile sets up the names of the types used by fd for generating it.
+// Imported types whose name is already used, by a type of fd or another
+// imported type, are aliased with their package as prefix, e.g. Status from
+// billing.types is imported as billing_types_Status.
+func (d *dependencyResolver) UseFile(fd *descriptor.FileDescriptorProto) {
+	used := make(map[string]bool)
+	for _, name := range runtimeNames {
+		used[name] = true
+	}
+	for name, id := range d.identifiers {
+		if d.v[name] == fd {
+			used[id] = true
+		}
+	}
+
+	imported := make(map[string]bool)
+	addType := func(typeName string) {
+		if fp, err := d.Resolve(typeName); err == nil && !sameFile(fp, fd) {
+			imported[typeName] = true
+		}
+	}
+	var addMessage func(msg *descriptor.DescriptorProto)
+	addMessage = func(msg *descriptor.DescriptorProto) {
+		for _, field := range msg.GetField() {
+			addType(field.GetTypeName())
+		}
+		for _, ext := range msg.GetExtension() {
+			addType(ext.GetExtendee())
+			addType(ext.GetTypeName())
+		}
+		for _, nested := range msg.GetNestedType() {
+			addMessage(nested)
 		}
 	}
-	return typeName
+	for _, msg := range fd.GetMessageType() {
+		addMessage(msg)
+	}
+	for _, ext := range fd.GetExtension() {
+		addType(ext.GetExtendee())
+		addType(ext.GetTypeName())
+	}
+	for _, service := range fd.GetService() {
+		for _, method := range service.GetMethod() {
+			addType(method.GetInputType())
+			addType(method.GetOutputType())
+		}
+	}
+
+	var names []string
+	for name := range imported {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	d.aliases = make(map[string]string)
+	for _, name := range names {
+		id := d.ExportedName(name)
+		if !used[id] {
+			used[id] = true
+			continue
+		}
+
+		prefix := strings.Replace(d.v[name].GetPackage(), ".", "_", -1)
+		if prefix == "" {
+			prefix = nonIdentifierChars.ReplaceAllString(strings.TrimSuffix(d.v[name].GetName(), ".proto"), "_")
+		}
+		alias := prefix + "_" + id
+		for i := 2; used[alias]; i++ {
+			alias = fmt.Sprintf("%s_%s%d", prefix, id, i)
+		}
+		used[alias] = true
+		d.aliases[name] = alias
+	}
+}
+
+// EnumDefault returns the name of the zero value of an enum.
+func (d *dependencyResolver) EnumDefault(typeName string) string {
+	enum := d.enums[typeName]
+	if enum == nil || len(enum.GetValue()) == 0 {
+		return ""
+	}
+	return enum.GetValue()[0].GetName()
 }
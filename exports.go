@@ -0,0 +1,192 @@
+package main
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// exportFile is a generated file re-exported by its package index, Names
+// are its exported values and Types its exported types, aliased as
+// "Name as Alias" on conflicts. Types are re-exported with export type, the
+// isolatedModules compiler option rejects re-exporting them as values.
+type exportFile struct {
+	Path  string
+	Names []string
+	Types []string
+}
+
+// exportName is a name exported by a generated file, Type is set for types
+// without a value, e.g. interfaces.
+type exportName struct {
+	Name string
+	Type bool
+}
+
+// Exports lists the names exported by the generated file.
+func (pf *protoFile) Exports() []exportName {
+	var names []exportName
+	values := func(ns ...string) {
+		for _, n := range ns {
+			names = append(names, exportName{Name: n})
+		}
+	}
+	types := func(ns ...string) {
+		for _, n := range ns {
+			names = append(names, exportName{Name: n, Type: true})
+		}
+	}
+	addEnum := func(ev *enumValues) {
+		values(ev.Name)
+		if !declarationsOnly() {
+			values(ev.Name+"Values", ev.Name+"Names")
+		}
+	}
+	for _, ev := range pf.Enums {
+		addEnum(ev)
+	}
+	for _, mv := range pf.Messages {
+		types(mv.Interface)
+		for _, ev := range mv.NestedEnums {
+			addEnum(ev)
+		}
+		types(mv.JSONInterface)
+		if readonly() {
+			types(mv.ReadonlyInterface)
+		}
+		if generateClasses() {
+			values(mv.Name)
+		}
+		if params.Builders {
+			values(mv.Name + "Builder")
+		}
+		if functional() {
+			values("create"+mv.Name, "decode"+mv.Name, "encode"+mv.Name)
+		}
+		if params.IoTs {
+			values(typeToJSONCodec(mv.Name), mv.Name+"Codec")
+		}
+	}
+	for _, ev := range pf.Extensions {
+		values(ev.Name)
+	}
+	for _, sv := range pf.Services {
+		types(sv.Interface, sv.Name+"Methods")
+		for _, m := range sv.Methods {
+			types(sv.Name+m.Name+"Request", sv.Name+m.Name+"Response")
+		}
+		if !functional() {
+			values(sv.Name)
+			continue
+		}
+		for _, m := range sv.Methods {
+			values(methodName(sv.Name) + m.Name)
+		}
+	}
+	return names
+}
+
+var nonIdentifierChars = regexp.MustCompile(`[^A-Za-z0-9_$]`)
+
+// packageExports returns the exports of the package index of files, ordered
+// by file name. A name exported by several files is exported as-is by the
+// first one and aliased with the file name as prefix for the others, e.g.
+// Status as billing_Status. The aliases are added to aliases by file and
+// name.
+func packageExports(files []*protoFile, aliases map[string]map[string]string) []*exportFile {
+	files = append([]*protoFile(nil), files...)
+	sort.Slice(files, func(i, j int) bool { return files[i].Output < files[j].Output })
+
+	used := make(map[string]bool)
+	for _, pf := range files {
+		for _, export := range pf.Exports() {
+			used[export.Name] = true
+		}
+	}
+
+	var exports []*exportFile
+	exported := make(map[string]bool)
+	for _, pf := range files {
+		stem := strings.TrimSuffix(path.Base(pf.Output), tsExtension())
+		ef := &exportFile{Path: stem}
+		for _, export := range pf.Exports() {
+			name, list := export.Name, &ef.Names
+			if export.Type {
+				list = &ef.Types
+			}
+			if !exported[name] {
+				exported[name] = true
+				*list = append(*list, name)
+				continue
+			}
+
+			alias := nonIdentifierChars.ReplaceAllString(stem, "_") + "_" + name
+			for i := 2; used[alias]; i++ {
+				alias = fmt.Sprintf("%s_%s%d", nonIdentifierChars.ReplaceAllString(stem, "_"), name, i)
+			}
+			used[alias] = true
+			if aliases[pf.Output] == nil {
+				aliases[pf.Output] = make(map[string]string)
+			}
+			aliases[pf.Output][name] = alias
+			*list = append(*list, name+" as "+alias)
+		}
+		exports = append(exports, ef)
+	}
+	return exports
+}
+
+// aliasTypes imports the types that their package index exports under an
+// alias by that alias, see packageExports.
+func (iv *importValues) aliasTypes(aliases map[string]map[string]string) {
+	for _, local := range iv.Types {
+		if alias, ok := aliases[iv.Files[local]][iv.Names[local]]; ok {
+			iv.Names[local] = alias
+		}
+	}
+}
+
+// packageNamespaces returns the namespaces of the packages of files exported
+// by their index, and the imports of the types they alias. The types are
+// imported with the package as prefix, e.g. Item as _foo_bar_Item, their
+// names would be shadowed by the members of the namespaces.
+func packageNamespaces(files []*protoFile) ([]*importValues, []*namespaceValues) {
+	files = append([]*protoFile(nil), files...)
+	sort.Slice(files, func(i, j int) bool { return files[i].Output < files[j].Output })
+
+	var imports []*importValues
+	var namespaces []*namespaceValues
+	byPackage := make(map[string]*namespaceValues)
+	for _, pf := range files {
+		if pf.Package == "" || len(pf.PackageMembers) == 0 {
+			continue
+		}
+		nv, ok := byPackage[pf.Package]
+		if !ok {
+			parts := strings.Split(pf.Package, ".")
+			for i, part := range parts {
+				parts[i] = safeIdentifier(part)
+			}
+			nv = &namespaceValues{Name: strings.Join(parts, ".")}
+			byPackage[pf.Package] = nv
+			namespaces = append(namespaces, nv)
+		}
+
+		iv := &importValues{
+			RelativeImportBase: "./",
+			Path:               strings.TrimSuffix(path.Base(pf.Output), tsExtension()) + jsExtension(),
+			Names:              make(map[string]string),
+		}
+		prefix := "_" + strings.Replace(pf.Package, ".", "_", -1) + "_"
+		for _, member := range pf.PackageMembers {
+			local := prefix + member.Type
+			iv.Types = append(iv.Types, local)
+			iv.Names[local] = member.Type
+			nv.Members = append(nv.Members, &namespaceMember{Name: member.Name, Type: local})
+		}
+		imports = append(imports, iv)
+	}
+	return imports, namespaces
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPackageExports(t *testing.T) {
+	params = defaultParameters()
+	message := func(name string) *messageValues {
+		return &messageValues{Name: name, Interface: "I" + name, JSONInterface: "I" + name + "JSON"}
+	}
+	files := []*protoFile{
+		{Output: "acme/users/users.ts", Messages: []*messageValues{message("Status"), message("User")}},
+		{Output: "acme/users/billing.ts", Messages: []*messageValues{message("Status")}},
+	}
+
+	aliases := make(map[string]map[string]string)
+	got := packageExports(files, aliases)
+	want := []*exportFile{
+		{Path: "billing", Names: []string{"Status"}, Types: []string{"IStatus", "IStatusJSON"}},
+		{Path: "users", Names: []string{"Status as users_Status", "User"}, Types: []string{
+			"IStatus as users_IStatus", "IStatusJSON as users_IStatusJSON", "IUser", "IUserJSON",
+		}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("packageExports() =\n%+v\nwant:\n%+v", got, want)
+	}
+	wantAliases := map[string]map[string]string{
+		"acme/users/users.ts": {"Status": "users_Status", "IStatus": "users_IStatus", "IStatusJSON": "users_IStatusJSON"},
+	}
+	if !reflect.DeepEqual(aliases, wantAliases) {
+		t.Errorf("packageExports() aliases = %v, want %v", aliases, wantAliases)
+	}
+}
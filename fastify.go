@@ -0,0 +1,67 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+)
+
+// jsonSchema is the JSON Schema validating the requests of a method in the
+// Fastify plugins of services, see server_framework.
+type jsonSchema struct {
+	Type                 interface{}            `json:"type,omitempty"`
+	Properties           map[string]*jsonSchema `json:"properties,omitempty"`
+	Items                *jsonSchema            `json:"items,omitempty"`
+	AdditionalProperties *jsonSchema            `json:"additionalProperties,omitempty"`
+}
+
+// BodySchema returns the JSON Schema of the JSON of messages of typeName, as
+// a JavaScript object literal. Only the types of the fields of the message
+// itself are checked, the fields of nested messages are left to fromJSON.
+func (d *dependencyResolver) BodySchema(typeName string) string {
+	schema := &jsonSchema{Type: "object"}
+	if msg, ok := d.messages[typeName]; ok && !isPlainJSONType(typeName) {
+		schema.Properties = make(map[string]*jsonSchema)
+		for _, field := range msg.GetField() {
+			schema.Properties[field.GetName()] = d.fieldSchema(field)
+		}
+	}
+	b, _ := json.Marshal(schema)
+	return string(b)
+}
+
+func (d *dependencyResolver) fieldSchema(field *descriptor.FieldDescriptorProto) *jsonSchema {
+	if field.GetType() == descriptor.FieldDescriptorProto_TYPE_MESSAGE {
+		if entry, ok := d.messages[field.GetTypeName()]; ok && entry.GetOptions().GetMapEntry() {
+			return &jsonSchema{Type: "object", AdditionalProperties: d.valueSchema(mapEntryField(entry, 2))}
+		}
+	}
+	if isRepeated(field) {
+		return &jsonSchema{Type: "array", Items: d.valueSchema(field)}
+	}
+	return d.valueSchema(field)
+}
+
+// valueSchema returns the schema of a single value of field. The proto3 JSON
+// mapping accepts numbers as strings too, the well-known types are left
+// unchecked.
+func (d *dependencyResolver) valueSchema(field *descriptor.FieldDescriptorProto) *jsonSchema {
+	switch t := field.GetType(); {
+	case t == descriptor.FieldDescriptorProto_TYPE_STRING, t == descriptor.FieldDescriptorProto_TYPE_BYTES:
+		return &jsonSchema{Type: "string"}
+	case t == descriptor.FieldDescriptorProto_TYPE_BOOL:
+		return &jsonSchema{Type: "boolean"}
+	case isFloat(t):
+		return &jsonSchema{Type: []string{"number", "string"}}
+	case isInt32(t), isLong(t):
+		return &jsonSchema{Type: []string{"integer", "string"}}
+	case t == descriptor.FieldDescriptorProto_TYPE_ENUM:
+		return &jsonSchema{Type: []string{"string", "integer"}}
+	case field.GetTypeName() == timestampTypeName:
+		return &jsonSchema{Type: "string"}
+	case isPlainJSONType(field.GetTypeName()), strings.HasPrefix(field.GetTypeName(), ".google.protobuf."):
+		return &jsonSchema{}
+	}
+	return &jsonSchema{Type: "object"}
+}
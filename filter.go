@@ -0,0 +1,134 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+	plugin "github.com/golang/protobuf/protoc-gen-go/plugin"
+)
+
+// filesToGenerate returns the files to generate: the files given to protoc,
+// and with generate_dependencies the files they import too.
+func filesToGenerate(req *plugin.CodeGeneratorRequest) []*descriptor.FileDescriptorProto {
+	if params.GenerateDependencies {
+		return req.GetProtoFile()
+	}
+
+	names := make(map[string]bool)
+	for _, name := range req.GetFileToGenerate() {
+		names[name] = true
+	}
+	var files []*descriptor.FileDescriptorProto
+	for _, fd := range req.GetProtoFile() {
+		if names[fd.GetName()] {
+			files = append(files, fd)
+		}
+	}
+	return files
+}
+
+// includeService reports whether a service is generated, see the services
+// parameter.
+func includeService(fd *descriptor.FileDescriptorProto, service *descriptor.ServiceDescriptorProto) bool {
+	if len(params.Services) == 0 {
+		return true
+	}
+	for _, name := range params.Services {
+		if name == service.GetName() || name == strings.TrimPrefix(fullTypeName(fd, service.GetName()), ".") {
+			return true
+		}
+	}
+	return false
+}
+
+// includedTypes returns the fully qualified names of the messages and enums
+// to generate: the messages not matched by exclude_messages, all enums and
+// the messages used by the services of files, including the messages of
+// their error meta, along with all the types they
+// and their extensions reference. With prune only the types used by the
+// services are generated.
+func includedTypes(resolver *dependencyResolver, files []*descriptor.FileDescriptorProto) (map[string]bool, error) {
+	var exclude *regexp.Regexp
+	if params.ExcludeMessages != "" {
+		var err error
+		exclude, err = regexp.Compile("^(?:" + params.ExcludeMessages + ")$")
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var queue []string
+	if !params.Prune {
+		for name := range resolver.messages {
+			if exclude == nil || !exclude.MatchString(strings.TrimPrefix(name, ".")) {
+				queue = append(queue, name)
+			}
+		}
+		for name := range resolver.enums {
+			queue = append(queue, name)
+		}
+	}
+
+	found := make(map[string]bool)
+	for _, fd := range files {
+		for _, service := range fd.GetService() {
+			if !includeService(fd, service) {
+				continue
+			}
+			found[service.GetName()] = true
+			found[strings.TrimPrefix(fullTypeName(fd, service.GetName()), ".")] = true
+			meta, err := resolver.ErrorMeta(fd, serviceOptionsTypeName, service.GetOptions())
+			if err != nil {
+				return nil, err
+			}
+			queue = append(queue, meta)
+			for _, method := range service.GetMethod() {
+				queue = append(queue, method.GetInputType(), method.GetOutputType())
+				meta, err := resolver.ErrorMeta(fd, methodOptionsTypeName, method.GetOptions())
+				if err != nil {
+					return nil, err
+				}
+				queue = append(queue, meta)
+			}
+		}
+	}
+	for _, name := range params.Services {
+		if !found[name] {
+			return nil, fmt.Errorf("service %s not found", name)
+		}
+	}
+
+	included := make(map[string]bool)
+	for len(queue) > 0 {
+		name := queue[0]
+		queue = queue[1:]
+		if included[name] {
+			continue
+		}
+		if _, ok := resolver.enums[name]; ok {
+			included[name] = true
+			// Nested enums are generated with their message.
+			queue = append(queue, name[:strings.LastIndex(name, ".")])
+			continue
+		}
+		msg, ok := resolver.messages[name]
+		if !ok {
+			continue
+		}
+		included[name] = true
+		for _, field := range msg.GetField() {
+			if field.GetTypeName() != "" {
+				queue = append(queue, field.GetTypeName())
+			}
+		}
+		// Extensions are generated along with the messages they extend.
+		for _, ext := range resolver.extensions[name] {
+			if ext.Field.GetTypeName() != "" {
+				queue = append(queue, ext.Field.GetTypeName())
+			}
+		}
+	}
+	return included, nil
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"strings"
+)
+
+// formatTypeScript normalizes the layout of generated TypeScript. Lines keep
+// the indentation of the templates relative to the line opening their
+// bracket, lines indented less than one level inside it are indented to that
+// level, closing lines at least to their opening line, and the lines of block
+// comments are aligned on their /*. Trailing whitespace is removed, consecutive
+// blank lines are collapsed and blank lines at the start and end of blocks
+// are removed. Template literals are left as-is.
+func formatTypeScript(src string) string {
+	// open is a bracket opened by a line with the given indentation,
+	// shifted by shift from its indentation in src.
+	type open struct{ indent, shift int }
+	var stack []open
+	var state scanState
+	commentIndent := 0
+	var out []string
+	blank := false
+
+	for _, line := range strings.Split(src, "\n") {
+		if state.quote == '`' {
+			// Inside a template literal the whitespace is part of the string.
+			out = append(out, line)
+			state = scanBrackets(line, state, func(c byte) {})
+			continue
+		}
+
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" {
+			blank = len(out) > 0
+			continue
+		}
+		original := len(line) - len(strings.TrimLeft(line, " \t"))
+
+		indent, shift := original, 0
+		closers := 0
+		if !state.inComment {
+			for closers < len(trimmed) && closers < len(stack) && strings.IndexByte(")]}", trimmed[closers]) >= 0 {
+				closers++
+			}
+		}
+		switch {
+		case state.inComment && trimmed[0] == '*':
+			indent = commentIndent + 1
+		case closers > 0:
+			opener := stack[len(stack)-closers]
+			indent = original + opener.shift
+			if indent < opener.indent {
+				indent = opener.indent
+			}
+		case len(stack) > 0:
+			top := stack[len(stack)-1]
+			indent = original + top.shift
+			if min := top.indent + 2; indent < min {
+				indent = min
+			}
+		}
+		shift = indent - original
+
+		if blank && !strings.HasSuffix(out[len(out)-1], "{") && strings.IndexByte(")]}", trimmed[0]) < 0 {
+			out = append(out, "")
+		}
+		blank = false
+		out = append(out, strings.Repeat(" ", indent)+trimmed)
+
+		wasComment := state.inComment
+		state = scanBrackets(trimmed, state, func(c byte) {
+			switch c {
+			case '(', '[', '{':
+				stack = append(stack, open{indent, shift})
+			default:
+				if len(stack) > 0 {
+					stack = stack[:len(stack)-1]
+				}
+			}
+		})
+		if state.inComment && !wasComment {
+			commentIndent = indent
+		}
+	}
+
+	return strings.Join(out, "\n") + "\n"
+}
+
+// scanState is the state of scanBrackets at the end of a line: whether it's
+// inside a block comment, or the quote of the string it's inside, only
+// template literals spanning lines.
+type scanState struct {
+	inComment bool
+	quote     byte
+}
+
+// scanBrackets calls bracket for the brackets of line outside of strings,
+// regular expressions and comments, starting in state, and returns the state
+// at the end of the line.
+func scanBrackets(line string, state scanState, bracket func(c byte)) scanState {
+	inComment, quote := state.inComment, state.quote
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case inComment:
+			if c == '*' && i+1 < len(line) && line[i+1] == '/' {
+				inComment = false
+				i++
+			}
+		case quote != 0:
+			if c == '\\' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"', c == '\'', c == '`':
+			quote = c
+		case c == '/' && i+1 < len(line) && line[i+1] == '/':
+			return scanState{}
+		case c == '/' && i+1 < len(line) && line[i+1] == '*':
+			inComment = true
+			i++
+		case c == '/' && isRegexpStart(line[:i]):
+			quote = '/'
+		case strings.IndexByte("()[]{}", c) >= 0:
+			bracket(c)
+		}
+	}
+	// Only template literals span lines.
+	if quote != '`' {
+		quote = 0
+	}
+	return scanState{inComment, quote}
+}
+
+// isRegexpStart reports whether a slash following prefix starts a regular
+// expression rather than being a division.
+func isRegexpStart(prefix string) bool {
+	prefix = strings.TrimRight(prefix, " ")
+	if prefix == "" {
+		return true
+	}
+	return strings.IndexByte("(,=:[!&|?{};+-*%<>~^", prefix[len(prefix)-1]) >= 0
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestFormatTypeScript(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{
+			name: "blank lines",
+			src:  "class A {\n\n  a() {}\n\n\n  b() {}  \n\n}\n\n\nconst c = 1;\n",
+			want: "class A {\n  a() {}\n\n  b() {}\n}\n\nconst c = 1;\n",
+		},
+		{
+			name: "switch",
+			src:  "switch (code) {\n  case \"a\":\n    return 1;\n  default:\n    return 2;\n}\n",
+			want: "switch (code) {\n  case \"a\":\n    return 1;\n  default:\n    return 2;\n}\n",
+		},
+		{
+			name: "continuation lines",
+			src:  "const chunk = reader\n  ? reader.read()\n  : open().then(res => {\n      return res;\n    });\n",
+			want: "const chunk = reader\n  ? reader.read()\n  : open().then(res => {\n      return res;\n    });\n",
+		},
+		{
+			name: "conditional type",
+			src:  "export type DeepPartial<T> = T extends object\n  ? { [K in keyof T]?: DeepPartial<T[K]> }\n  : T;\n",
+			want: "export type DeepPartial<T> = T extends object\n  ? { [K in keyof T]?: DeepPartial<T[K]> }\n  : T;\n",
+		},
+		{
+			name: "under-indented block",
+			src:  "export namespace A {\nexport const b = {\nc: 1\n};\n}\n",
+			want: "export namespace A {\n  export const b = {\n    c: 1\n  };\n}\n",
+		},
+		{
+			name: "shifted block",
+			src:  "function a() {\nif (b) {\n  c();\n}\n}\n",
+			want: "function a() {\n  if (b) {\n    c();\n  }\n}\n",
+		},
+		{
+			name: "block comment",
+			src:  "interface A {\n  /**\n * Doc.\n */\n  a: string;\n}\n",
+			want: "interface A {\n  /**\n   * Doc.\n   */\n  a: string;\n}\n",
+		},
+		{
+			name: "template literal",
+			src:  "const a = `{\n\n    b\n`;\nconst c = {\n  d: 1\n};\n",
+			want: "const a = `{\n\n    b\n`;\nconst c = {\n  d: 1\n};\n",
+		},
+		{
+			name: "brackets in strings and comments",
+			src:  "const a = \"{\" + '(' + /[(]/.test(b); // {\nconst c = 1;\n",
+			want: "const a = \"{\" + '(' + /[(]/.test(b); // {\nconst c = 1;\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := formatTypeScript(tt.src); got != tt.want {
+				t.Errorf("formatTypeScript(%q) =\n%s\nwant:\n%s", tt.src, got, tt.want)
+			}
+		})
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"path"
 	"strings"
 
+	"github.com/golang/protobuf/proto"
 	"github.com/golang/protobuf/protoc-gen-go/descriptor"
 	plugin "github.com/golang/protobuf/protoc-gen-go/plugin"
 )
@@ -15,183 +16,584 @@ func sameFile(a *descriptor.FileDescriptorProto, b *descriptor.FileDescriptorPro
 }
 
 func fullTypeName(fd *descriptor.FileDescriptorProto, typeName string) string {
+	if fd.GetPackage() == "" {
+		return "." + typeName
+	}
 	return fmt.Sprintf(".%s.%s", fd.GetPackage(), typeName)
 }
 
 func generate(req *plugin.CodeGeneratorRequest) (*plugin.CodeGeneratorResponse, error) {
+	var err error
+	params, err = parseParameters(req.GetParameter())
+	if err != nil {
+		return nil, err
+	}
+	compilerVersion = formatCompilerVersion(req.GetCompilerVersion())
+	if params.Templates != "" {
+		if err := loadTemplates(params.Templates); err != nil {
+			return nil, err
+		}
+	}
+
 	resolver := dependencyResolver{}
 
 	res := &plugin.CodeGeneratorResponse{
-		File: []*plugin.CodeGeneratorResponse_File{
-			{
-				Name:    &twirpFileName,
-				Content: &twirpSource,
-			},
-		},
+		SupportedFeatures: proto.Uint64(uint64(plugin.CodeGeneratorResponse_FEATURE_PROTO3_OPTIONAL)),
+	}
+	if !declarationsOnly() && params.RuntimePackage == "" {
+		content, err := compileAndExecute(twirpTemplate, params)
+		if err != nil {
+			return nil, err
+		}
+		if params.Protobuf {
+			codecs, err := compileAndExecute(protobufTemplate, params)
+			if err != nil {
+				return nil, err
+			}
+			content += codecs
+		}
+		res.File = append(res.File, &plugin.CodeGeneratorResponse_File{
+			Name:    &twirpFileName,
+			Content: &content,
+		})
 	}
 
 	outputFiles := make(map[string][]*protoFile)
 	protoFiles := req.GetProtoFile()
 	for _, file := range protoFiles {
+		resolver.AddFile(file)
+	}
+	// Protoc reports the error of the response like a compile error.
+	if err := checkUnsupported(filesToGenerate(req)); err != nil {
+		return &plugin.CodeGeneratorResponse{Error: proto.String(err.Error())}, nil
+	}
+	if err := checkFileNames(filesToGenerate(req)); err != nil {
+		return &plugin.CodeGeneratorResponse{Error: proto.String(err.Error())}, nil
+	}
+	if err := checkTarget(filesToGenerate(req)); err != nil {
+		return &plugin.CodeGeneratorResponse{Error: proto.String(err.Error())}, nil
+	}
+	generated := filesToGenerate(req)
+	generatedNames := make(map[string]bool)
+	for _, file := range generated {
+		generatedNames[file.GetName()] = true
+	}
+	included, err := includedTypes(&resolver, generated)
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range generated {
 		pfile := &protoFile{
+			Source:             file.GetName(),
 			Output:             tsFileName(file),
 			RelativeImportBase: relativeImportBase(file),
+			Package:            file.GetPackage(),
 			Imports:            map[string]*importValues{},
 			Messages:           []*messageValues{},
 			Services:           []*serviceValues{},
 			Enums:              []*enumValues{},
 		}
 		outputFiles[tsImportPath(file)] = append(outputFiles[tsImportPath(file)], pfile)
+		resolver.UseFile(file)
+
+		// newField resolves the type of a field, importing it when it's
+		// defined in another file.
+		newField := func(field *descriptor.FieldDescriptorProto) *fieldValues {
+			typeName := singularFieldType(&resolver, field)
+			fp, err := resolver.Resolve(field.GetTypeName())
+			if err == nil {
+				exported := resolver.ExportedName(field.GetTypeName())
+				switch {
+				case sameFile(fp, file):
+				case !generateClasses() && field.GetType() != descriptor.FieldDescriptorProto_TYPE_ENUM:
+					// Without classes, interfaces reference the interfaces
+					// of other messages, converted by their functions in
+					// functional mode.
+					pfile.AddImport(fp, typeToInterface(exported), typeToInterface(typeName), resolver.IsCyclic(file, fp))
+					pfile.AddImport(fp, typeToJSONInterface(exported), typeToJSONInterface(typeName), resolver.IsCyclic(file, fp))
+					if functional() {
+						pfile.AddImport(fp, "decode"+exported, "decode"+typeName, resolver.IsCyclic(file, fp))
+						pfile.AddImport(fp, "encode"+exported, "encode"+typeName, resolver.IsCyclic(file, fp))
+					}
+				default:
+					pfile.AddImport(fp, exported, typeName, resolver.IsCyclic(file, fp))
+					if field.GetType() == descriptor.FieldDescriptorProto_TYPE_ENUM && (generateClasses() || functional()) {
+						pfile.AddImport(fp, exported+"Names", typeName+"Names", resolver.IsCyclic(file, fp))
+						pfile.AddImport(fp, exported+"Values", typeName+"Values", resolver.IsCyclic(file, fp))
+					}
+					if params.IoTs && hasJSONCodec(field) {
+						pfile.AddImport(fp, typeToJSONCodec(exported), typeToJSONCodec(typeName), resolver.IsCyclic(file, fp))
+					}
+				}
+				if readonly() && !sameFile(fp, file) && hasReadonlyInterface(field) {
+					pfile.AddImport(fp, typeToReadonlyInterface(exported), typeToReadonlyInterface(typeName), resolver.IsCyclic(file, fp))
+				}
+			}
+
+			return &fieldValues{
+				Name:  field.GetName(),
+				Field: fieldName(field.GetName()),
+				Alias: fieldAlias(field.GetName()),
+
+				Type:       typeName,
+				ProtoType:  field.GetType(),
+				IsEnum:     field.GetType() == descriptor.FieldDescriptorProto_TYPE_ENUM,
+				IsRepeated: isRepeated(field),
+				IsOptional: field.GetProto3Optional(),
+				IsOneof:    field.OneofIndex != nil && !field.GetProto3Optional(),
+				Deprecated: field.GetOptions().GetDeprecated(),
+
+				HasPresence: hasPresence(file, field),
+
+				IsPlainJSON: isPlainJSONType(field.GetTypeName()),
+				IsTimestamp: field.GetTypeName() == timestampTypeName,
+				EnumDefault: resolver.EnumDefault(field.GetTypeName()),
+
+				Number:   field.GetNumber(),
+				TypeName: field.GetTypeName(),
+				IsPacked: isPacked(file, field),
+			}
+		}
+
+		// errorMetaType returns the type of the error meta described by the
+		// message typeName, importing it, or "" without one.
+		errorMetaType := func(typeName string) string {
+			if typeName == "" {
+				return ""
+			}
+			fp, err := resolver.Resolve(typeName)
+			if err == nil && !sameFile(fp, file) {
+				pfile.AddImport(fp, typeToJSONInterface(resolver.ExportedName(typeName)), typeToJSONInterface(resolver.TypeName(typeName)), resolver.IsCyclic(file, fp))
+			}
+			return typeToJSONInterface(resolver.TypeName(typeName))
+		}
+
+		// addExtension adds an extension field declared in the scope of a
+		// message, or at the top level of the file when scope is empty.
+		addExtension := func(scope, fullScope string, field *descriptor.FieldDescriptorProto) {
+			// Extensions are read through the message classes.
+			if !generateClasses() || !included[field.GetExtendee()] {
+				return
+			}
+
+			name, jsonName := field.GetName(), field.GetName()
+			if scope != "" {
+				name = scope + "_" + name
+				jsonName = fullScope + "." + jsonName
+			}
+			if file.GetPackage() != "" {
+				jsonName = file.GetPackage() + "." + jsonName
+			}
+
+			extendee := typeToInterface(resolver.TypeName(field.GetExtendee()))
+			fp, err := resolver.Resolve(field.GetExtendee())
+			if err == nil && !sameFile(fp, file) {
+				if _, mapped := params.ImportMap[fp.GetName()]; generatedNames[fp.GetName()] || mapped {
+					pfile.AddImport(fp, typeToInterface(resolver.ExportedName(field.GetExtendee())), extendee, resolver.IsCyclic(file, fp))
+				} else {
+					// The extended message isn't generated, e.g. the options
+					// of descriptor.proto without generate_dependencies.
+					extendee = "object"
+				}
+			}
+
+			fv := newField(field)
+			// Extensions are serialized as "[full.name]" members of the
+			// extended message.
+			fv.Name = "[" + jsonName + "]"
+
+			pfile.Extensions = append(pfile.Extensions, &extensionValues{
+				Name:     safeIdentifier(name),
+				Number:   field.GetNumber(),
+				Extendee: extendee,
+				Field:    fv,
+			})
+		}
 
 		// Add enum
-		for _, enum := range file.GetEnumType() {
-			resolver.Set(file, enum.GetName())
+		for i, enum := range file.GetEnumType() {
+			if !included[fullTypeName(file, enum.GetName())] {
+				continue
+			}
+			name := resolver.TypeName(fullTypeName(file, enum.GetName()))
 
 			v := &enumValues{
-				Name:   enum.GetName(),
-				Values: []*enumKeyVal{},
+				Name:       name,
+				Values:     []*enumKeyVal{},
+				Deprecated: enum.GetOptions().GetDeprecated(),
+				Source:     sourceLocation(file, []int32{fileEnumTypePath, int32(i)}),
 			}
 
 			for _, value := range enum.GetValue() {
 				v.Values = append(v.Values, &enumKeyVal{
-					Name:  value.GetName(),
-					Value: value.GetNumber(),
+					Name:       value.GetName(),
+					Value:      value.GetNumber(),
+					Deprecated: value.GetOptions().GetDeprecated(),
 				})
 			}
 
 			pfile.Enums = append(pfile.Enums, v)
+			if params.Namespaces {
+				pfile.PackageMembers = append(pfile.PackageMembers, namespaceMemberOf(enum.GetName(), name))
+			}
 		}
 
 		// Add messages
 		type collectMsg struct {
-			Name string
-			FD   *descriptor.DescriptorProto
+			Name     string
+			FullName string
+			FD       *descriptor.DescriptorProto
+			// Path is the source code path of the message, for its
+			// comments.
+			Path []int32
 		}
 		var allMsgs []collectMsg
+		mapEntries := make(map[string]*descriptor.DescriptorProto)
 		// Recurse through message definitions first
-		var collectMsgDefs func(msg *descriptor.DescriptorProto, parents []string)
-		collectMsgDefs = func(msg *descriptor.DescriptorProto, parents []string) {
+		var collectMsgDefs func(msg *descriptor.DescriptorProto, parents []string, path []int32)
+		collectMsgDefs = func(msg *descriptor.DescriptorProto, parents []string, path []int32) {
 			parents = append(parents, msg.GetName())
+			// Map entries are synthesized by protoc, they're rendered as
+			// index signatures on the owning field instead of as messages.
+			if msg.GetOptions().GetMapEntry() {
+				mapEntries[fullTypeName(file, strings.Join(parents, "."))] = msg
+				return
+			}
 			allMsgs = append(allMsgs, collectMsg{
-				Name: strings.Join(parents, "_"),
-				FD:   msg,
+				Name:     resolver.TypeName(fullTypeName(file, strings.Join(parents, "."))),
+				FullName: strings.Join(parents, "."),
+				FD:       msg,
+				Path:     path,
 			})
-			for _, m := range msg.GetNestedType() {
-				collectMsgDefs(m, parents)
+			for i, m := range msg.GetNestedType() {
+				collectMsgDefs(m, parents, appendPath(path, messageNestedPath, int32(i)))
 			}
 		}
-		for _, msg := range file.GetMessageType() {
-			collectMsgDefs(msg, nil)
+		for i, msg := range file.GetMessageType() {
+			collectMsgDefs(msg, nil, []int32{fileMessageTypePath, int32(i)})
 		}
+		// addNamespaceMember adds a type to the namespace merged with its
+		// parent message, or to the namespace of the package for top-level
+		// types. Types nested in messages left out by the filters aren't
+		// aliased.
+		namespaces := make(map[string]*namespaceValues)
+		addNamespaceMember := func(parent, protoName, name string) {
+			member := namespaceMemberOf(protoName, name)
+			if parent == "" {
+				pfile.PackageMembers = append(pfile.PackageMembers, member)
+				return
+			}
+			if !included[fullTypeName(file, parent)] {
+				return
+			}
+			nv, ok := namespaces[parent]
+			if !ok {
+				nv = &namespaceValues{Name: resolver.TypeName(fullTypeName(file, parent))}
+				if !generateClasses() {
+					nv.Name = typeToInterface(nv.Name)
+				}
+				namespaces[parent] = nv
+				pfile.Namespaces = append(pfile.Namespaces, nv)
+			}
+			nv.Members = append(nv.Members, member)
+		}
+
 		// Parse them all in flattened form and add to the list
 		for _, collect := range allMsgs {
+			if !included[fullTypeName(file, collect.FullName)] {
+				continue
+			}
+
 			message := collect.FD
 			name := collect.Name
 			tsInterface := typeToInterface(name)
 			jsonInterface := typeToJSONInterface(name)
 
-			resolver.Set(file, name)
-			resolver.Set(file, tsInterface)
-			resolver.Set(file, jsonInterface)
-
 			v := &messageValues{
-				Name:          name,
-				Interface:     tsInterface,
-				JSONInterface: jsonInterface,
+				Name:              name,
+				FullName:          strings.TrimPrefix(fullTypeName(file, collect.FullName), "."),
+				Interface:         tsInterface,
+				JSONInterface:     jsonInterface,
+				ReadonlyInterface: typeToReadonlyInterface(name),
+				Deprecated:        message.GetOptions().GetDeprecated(),
+				Doc:               leadingComments(file, collect.Path),
+				Source:            sourceLocation(file, collect.Path),
+				Extendable:        len(message.GetExtensionRange()) > 0,
 
 				Fields:      []*fieldValues{},
 				NestedTypes: []*messageValues{},
 				NestedEnums: []*enumValues{},
 			}
 
+			v.Options, err = resolver.Options(messageOptionsTypeName, message.GetOptions())
+			if err != nil {
+				return nil, err
+			}
+
 			// Add nested enums
-			for _, enum := range message.GetEnumType() {
+			for i, enum := range message.GetEnumType() {
 				e := &enumValues{
-					Name:   fmt.Sprintf("%s_%s", message.GetName(), enum.GetName()),
-					Values: []*enumKeyVal{},
+					Name:       resolver.TypeName(fullTypeName(file, collect.FullName+"."+enum.GetName())),
+					Values:     []*enumKeyVal{},
+					Deprecated: enum.GetOptions().GetDeprecated(),
+					Source:     sourceLocation(file, appendPath(collect.Path, messageEnumTypePath, int32(i))),
 				}
 
 				for _, value := range enum.GetValue() {
 					e.Values = append(e.Values, &enumKeyVal{
-						Name:  value.GetName(),
-						Value: value.GetNumber(),
+						Name:       value.GetName(),
+						Value:      value.GetNumber(),
+						Deprecated: value.GetOptions().GetDeprecated(),
 					})
 				}
 
 				v.NestedEnums = append(v.NestedEnums, e)
+				if params.Namespaces {
+					addNamespaceMember(collect.FullName, enum.GetName(), e.Name)
+				}
 			}
 
 			// Add message fields
 			for _, field := range message.GetField() {
-				typeName := resolver.TypeName(file, singularFieldType(message, field))
-				fp, err := resolver.Resolve(field.GetTypeName())
-				if err == nil {
-					if !sameFile(fp, file) {
-						pfile.AddImport(fp, typeName)
-					}
+				fv := newField(field)
+
+				if entry, ok := mapEntries[field.GetTypeName()]; ok {
+					fv.IsRepeated = false
+					fv.IsMap = true
+					fv.MapKey = newField(mapEntryField(entry, 1))
+					fv.MapValue = newField(mapEntryField(entry, 2))
 				}
+				fv.Required, err = resolver.isRequired(field, fv)
+				if err != nil {
+					return nil, err
+				}
+
+				v.Fields = append(v.Fields, fv)
+			}
+			if generateClasses() {
+				renamePresenceClashes(v.Fields)
+			}
 
-				v.Fields = append(v.Fields, &fieldValues{
-					Name:  field.GetName(),
-					Field: camelCase(field.GetName()),
+			pfile.Messages = append(pfile.Messages, v)
+			if params.Namespaces {
+				typ := v.Interface
+				if generateClasses() {
+					typ = v.Name
+				}
+				parent := ""
+				if i := strings.LastIndex(collect.FullName, "."); i >= 0 {
+					parent = collect.FullName[:i]
+				}
+				addNamespaceMember(parent, collect.FullName, typ)
+			}
 
-					Type:       typeName,
-					IsEnum:     field.GetType() == descriptor.FieldDescriptorProto_TYPE_ENUM,
-					IsRepeated: isRepeated(field),
+			if params.JSONSchema {
+				typeName := fullTypeName(file, collect.FullName)
+				schema, err := resolver.MessageSchema(typeName)
+				if err != nil {
+					return nil, err
+				}
+				name := path.Join(tsImportPath(file), schemaFileName(typeName))
+				res.File = append(res.File, &plugin.CodeGeneratorResponse_File{
+					Name:    &name,
+					Content: &schema,
 				})
 			}
 
-			pfile.Messages = append(pfile.Messages, v)
+			for _, ext := range message.GetExtension() {
+				addExtension(name, collect.FullName, ext)
+			}
+		}
+
+		for _, ext := range file.GetExtension() {
+			addExtension("", "", ext)
 		}
 
 		// Add services
-		for _, service := range file.GetService() {
-			resolver.Set(file, service.GetName())
+		for i, service := range file.GetService() {
+			// Service clients need the runtime.
+			if declarationsOnly() {
+				break
+			}
+			if !includeService(file, service) {
+				continue
+			}
+
+			name := resolver.TypeName(fullTypeName(file, service.GetName()))
 
 			v := &serviceValues{
 				Package:   file.GetPackage(),
-				Name:      service.GetName(),
-				Interface: typeToInterface(service.GetName()),
+				Name:      name,
+				FullName:  strings.TrimPrefix(fullTypeName(file, service.GetName()), "."),
+				Path:      "/twirp/" + strings.TrimPrefix(fullTypeName(file, service.GetName()), ".") + "/",
+				Interface: typeToInterface(name),
 				Methods:   []*serviceMethodValues{},
+				Source:    sourceLocation(file, []int32{fileServicePath, int32(i)}),
 			}
 
-			for _, method := range service.GetMethod() {
-				inputType := resolver.TypeName(file, removePkg(method.GetInputType()))
-				outputType := resolver.TypeName(file, removePkg(method.GetOutputType()))
+			v.Options, err = resolver.Options(serviceOptionsTypeName, service.GetOptions())
+			if err != nil {
+				return nil, err
+			}
+			meta, err := resolver.ErrorMeta(file, serviceOptionsTypeName, service.GetOptions())
+			if err != nil {
+				return nil, err
+			}
+			v.ErrorMeta = errorMetaType(meta)
+			v.Headers, err = requiredHeaders(v.Options)
+			if err != nil {
+				return nil, err
+			}
+
+			for j, method := range service.GetMethod() {
+				inputType := resolver.TypeName(method.GetInputType())
+				outputType := resolver.TypeName(method.GetOutputType())
+				exportedInput := resolver.ExportedName(method.GetInputType())
+				exportedOutput := resolver.ExportedName(method.GetOutputType())
+				inputName, outputName := inputType, outputType
+				switch {
+				case functional():
+					// The messages are converted by the functions of
+					// their interfaces.
+					inputType = typeToInterface(inputType)
+					outputType = typeToInterface(outputType)
+					exportedInput = typeToInterface(exportedInput)
+					exportedOutput = typeToInterface(exportedOutput)
+				case !generateClasses():
+					// The JSON is sent and returned as-is.
+					inputType = typeToJSONInterface(inputType)
+					outputType = typeToJSONInterface(outputType)
+					exportedInput = typeToJSONInterface(exportedInput)
+					exportedOutput = typeToJSONInterface(exportedOutput)
+				}
+				// Fastify types the JSON of requests.
+				inputJSONType, bodySchema := inputType, ""
+				if fastify() {
+					bodySchema = resolver.BodySchema(method.GetInputType())
+					if generateClasses() || functional() {
+						inputJSONType = typeToJSONInterface(inputType)
+					}
+				}
 				{
 					fp, err := resolver.Resolve(method.GetInputType())
 					if err == nil {
 						if !sameFile(fp, file) {
-							pfile.AddImport(fp, inputType)
+							pfile.AddImport(fp, exportedInput, inputType, resolver.IsCyclic(file, fp))
+							if inputJSONType != inputType && !method.GetServerStreaming() && method.GetInputType() != emptyTypeName {
+								pfile.AddImport(fp, typeToJSONInterface(exportedInput), inputJSONType, resolver.IsCyclic(file, fp))
+							}
+							if functional() && method.GetInputType() != emptyTypeName {
+								exported := resolver.ExportedName(method.GetInputType())
+								pfile.AddImport(fp, "encode"+exported, "encode"+inputName, resolver.IsCyclic(file, fp))
+								// Servers and fakes decode the requests.
+								if params.Server || params.Fakes || params.Msw {
+									pfile.AddImport(fp, "decode"+exported, "decode"+inputName, resolver.IsCyclic(file, fp))
+								}
+							}
 						}
 					}
 				}
 
+				// The results of methods are typed with the readonly
+				// interfaces of their outputs with readonly=responses.
+				resultType := outputType
+				if params.Readonly == "responses" && !isPlainJSONType(method.GetOutputType()) && method.GetOutputType() != timestampTypeName {
+					resultType = typeToReadonlyInterface(outputType)
+				}
 				{
 					fp, err := resolver.Resolve(method.GetOutputType())
 					if err == nil {
 						if !sameFile(fp, file) {
-							pfile.AddImport(fp, outputType)
+							pfile.AddImport(fp, exportedOutput, outputType, resolver.IsCyclic(file, fp))
+							if functional() && method.GetOutputType() != emptyTypeName {
+								exported := resolver.ExportedName(method.GetOutputType())
+								pfile.AddImport(fp, "decode"+exported, "decode"+outputName, resolver.IsCyclic(file, fp))
+							}
+							if resultType != outputType {
+								pfile.AddImport(fp, typeToReadonlyInterface(exportedOutput), resultType, resolver.IsCyclic(file, fp))
+							}
 						}
 					}
 				}
 
+				options, err := resolver.Options(methodOptionsTypeName, method.GetOptions())
+				if err != nil {
+					return nil, err
+				}
+				meta, err := resolver.ErrorMeta(file, methodOptionsTypeName, method.GetOptions())
+				if err != nil {
+					return nil, err
+				}
+				subscribe, err := subscribePath(method.GetName(), options)
+				if err != nil {
+					return nil, err
+				}
+				batchName, err := batchMethod(options)
+				if err != nil {
+					return nil, err
+				}
+				headers, err := requiredHeaders(options)
+				if err != nil {
+					return nil, err
+				}
+				var batch *batchValues
+				if batchName != "" {
+					batch, err = resolver.Batch(service, method, batchName)
+					if err != nil {
+						return nil, err
+					}
+				}
+
 				v.Methods = append(v.Methods, &serviceMethodValues{
-					Name:       method.GetName(),
-					InputType:  inputType,
-					OutputType: outputType,
+					Name:          method.GetName(),
+					Options:       options,
+					ErrorMeta:     errorMetaType(meta),
+					InputType:     inputType,
+					OutputType:    outputType,
+					InputName:     inputName,
+					OutputName:    outputName,
+					ResultType:    resultType,
+					InputJSONType: inputJSONType,
+					BodySchema:    bodySchema,
+					Deprecated:    method.GetOptions().GetDeprecated(),
+					Doc:           leadingComments(file, []int32{fileServicePath, int32(i), serviceMethodPath, int32(j)}),
+					Source:        sourceLocation(file, []int32{fileServicePath, int32(i), serviceMethodPath, int32(j)}),
+
+					InputIsEmpty:  method.GetInputType() == emptyTypeName,
+					OutputIsEmpty: method.GetOutputType() == emptyTypeName,
+
+					ServerStreaming: method.GetServerStreaming() && !method.GetClientStreaming(),
+					Subscribe:       subscribe,
+					ReadOnly:        noSideEffects(method, options),
+					Get:             params.HTTPGet && noSideEffects(method, options) && !method.GetServerStreaming(),
+					Batch:           batch,
+					Headers:         mergeHeaders(v.Headers, headers),
 				})
 			}
+			v.resolveHeaders()
 
 			pfile.Services = append(pfile.Services, v)
 		}
 	}
 
+	// Names exported by several files of a package are aliased by its
+	// index, their importers need the aliases before they're compiled.
+	aliases := make(map[string]map[string]string)
+	exports := make(map[string][]*exportFile)
+	for tsPath, pff := range outputFiles {
+		exports[tsPath] = packageExports(pff, aliases)
+	}
+
 	for tsPath, pff := range outputFiles {
-		ev := &exportValues{}
+		ev := &exportValues{Files: exports[tsPath]}
+		if params.Namespaces {
+			ev.Imports, ev.Namespaces = packageNamespaces(pff)
+		}
 
 		for _, pf := range pff {
-			ev.Exports = append(ev.Exports, strings.TrimSuffix(path.Base(pf.Output), ".ts"))
+			for _, iv := range pf.Imports {
+				iv.aliasTypes(aliases)
+			}
 
 			// Compile to typescript
 			content, err := pf.Compile()
@@ -204,6 +606,18 @@ func generate(req *plugin.CodeGeneratorRequest) (*plugin.CodeGeneratorResponse,
 				Name:    &pf.Output,
 				Content: &content,
 			})
+
+			if len(pf.EnumMaps()) > 0 {
+				content, err := pf.CompileEnumMaps()
+				if err != nil {
+					log.Fatal("could not compile template: ", err)
+				}
+				name := strings.TrimSuffix(pf.Output, ".ts") + ".enums.ts"
+				res.File = append(res.File, &plugin.CodeGeneratorResponse_File{
+					Name:    &name,
+					Content: &content,
+				})
+			}
 		}
 
 		content, err := ev.Compile()
@@ -211,7 +625,7 @@ func generate(req *plugin.CodeGeneratorRequest) (*plugin.CodeGeneratorResponse,
 			log.Fatal("could not compile template: ", err)
 		}
 
-		name := path.Join(tsPath, "index.ts")
+		name := path.Join(tsPath, "index"+tsExtension())
 		res.File = append(res.File, &plugin.CodeGeneratorResponse_File{
 			Name:    &name,
 			Content: &content,
@@ -219,8 +633,15 @@ func generate(req *plugin.CodeGeneratorRequest) (*plugin.CodeGeneratorResponse,
 	}
 
 	for i := range res.File {
+		if params.Format == "on" && !strings.HasSuffix(res.File[i].GetName(), ".json") {
+			content := formatTypeScript(res.File[i].GetContent())
+			res.File[i].Content = &content
+		}
 		log.Printf("wrote: %v", *res.File[i].Name)
 	}
+	if params.SizeReport {
+		reportSizes(res.File)
+	}
 
 	return res, nil
 }
@@ -229,10 +650,72 @@ func isRepeated(field *descriptor.FieldDescriptorProto) bool {
 	return field.Label != nil && *field.Label == descriptor.FieldDescriptorProto_LABEL_REPEATED
 }
 
-func removePkg(s string) string {
-	p := strings.SplitN(s, ".", 3)
-	c := strings.Split(p[len(p)-1], ".")
-	return strings.Join(c, "_")
+// mapEntryField returns the key (1) or value (2) field of a synthesized map
+// entry message.
+func mapEntryField(entry *descriptor.DescriptorProto, number int32) *descriptor.FieldDescriptorProto {
+	for _, field := range entry.GetField() {
+		if field.GetNumber() == number {
+			return field
+		}
+	}
+	return nil
+}
+
+// renamePresenceClashes suffixes with underscores the accessors of fields
+// named like the has and clear methods of the fields with presence of the
+// same message class, e.g. has_name when name has presence.
+func renamePresenceClashes(fields []*fieldValues) {
+	methods := map[string]bool{}
+	for _, fv := range fields {
+		if fv.HasPresence {
+			name := upperCaseFirst(camelCase(fv.Name))
+			methods["has"+name] = true
+			methods["clear"+name] = true
+		}
+	}
+	taken := func(name string) bool {
+		if methods[name] {
+			return true
+		}
+		for _, fv := range fields {
+			if fv.Field == name || fv.Alias == name {
+				return true
+			}
+		}
+		return false
+	}
+	rename := func(name string) string {
+		if !methods[name] {
+			return name
+		}
+		name += "_"
+		for taken(name) {
+			name += "_"
+		}
+		return name
+	}
+	for _, fv := range fields {
+		fv.Field, fv.Alias = rename(fv.Field), rename(fv.Alias)
+	}
+}
+
+// fieldName returns the name of the accessors and interface member of a
+// field, see the field_naming parameter. The JSON always uses the original
+// name.
+func fieldName(name string) string {
+	if params.FieldNaming == "original" {
+		return safeIdentifier(name)
+	}
+	return safeIdentifier(camelCase(name))
+}
+
+// fieldAlias returns the original name of a field with field_naming=both,
+// unless it's the same as its camel case name.
+func fieldAlias(name string) string {
+	if params.FieldNaming != "both" || camelCase(name) == name {
+		return ""
+	}
+	return safeIdentifier(name)
 }
 
 func upperCaseFirst(s string) string {
@@ -243,9 +726,8 @@ func camelCase(s string) string {
 	parts := strings.Split(s, "_")
 
 	for i, p := range parts {
-		if i == 0 {
-			parts[i] = p
-		} else {
+		// Consecutive, leading and trailing underscores split empty parts.
+		if i > 0 && p != "" {
 			parts[i] = strings.ToUpper(p[0:1]) + p[1:]
 		}
 	}
@@ -262,48 +744,297 @@ func tsImportName(name string) string {
 	return base[0 : len(base)-len(path.Ext(base))]
 }
 
+// tsImportPath returns the output directory of a file, see the paths
+// parameter. Directories are derived from the package, or from the directory
+// of the .proto file when it has none.
 func tsImportPath(fd *descriptor.FileDescriptorProto) string {
+	switch {
+	case params.Paths == "flat":
+		return ""
+	case params.Paths == "source_relative", fd.GetPackage() == "":
+		if dir := path.Dir(fd.GetName()); dir != "." {
+			return dir
+		}
+		return ""
+	}
 	return path.Join(strings.Split(fd.GetPackage(), ".")...)
 }
 
 func relativeImportBase(fd *descriptor.FileDescriptorProto) string {
+	if tsImportPath(fd) == "" {
+		return "./"
+	}
 	return strings.Repeat("../", len(strings.Split(tsImportPath(fd), "/")))
 }
 
+// tsFileName is the name of the file generated for fd, the .proto file
+// name with its extension replaced by the file_suffix parameter.
 func tsFileName(fd *descriptor.FileDescriptorProto) string {
-	filename := strings.TrimSuffix(path.Base(fd.GetName()), path.Ext(fd.GetName())) + ".ts"
+	filename := strings.TrimSuffix(path.Base(fd.GetName()), path.Ext(fd.GetName())) +
+		strings.TrimSuffix(params.FileSuffix, ".ts") + tsExtension()
 	return path.Join(tsImportPath(fd), filename)
 }
 
-func singularFieldType(m *descriptor.DescriptorProto, f *descriptor.FieldDescriptorProto) string {
+// checkFileNames returns an error when files would be generated to the same
+// file, e.g. acme/common/types.proto and acme/billing/types.proto with
+// paths=flat, rather than letting one overwrite the other.
+func checkFileNames(files []*descriptor.FileDescriptorProto) error {
+	sources := map[string]string{}
+	if !declarationsOnly() && params.RuntimePackage == "" {
+		sources[twirpFileName] = "the twirp runtime"
+	}
+	var problems []string
+	for _, fd := range files {
+		name := tsFileName(fd)
+		if source, ok := sources[name]; ok {
+			problems = append(problems, fmt.Sprintf("%s and %s are both generated to %s", source, fd.GetName(), name))
+			continue
+		}
+		sources[name] = fd.GetName()
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("conflicting output files with paths=%s, rename the files or use another paths parameter:\n%s",
+			params.Paths, strings.Join(problems, "\n"))
+	}
+	return nil
+}
+
+// tsExtension is the extension of the generated files.
+func tsExtension() string {
+	if declarationsOnly() {
+		return ".d.ts"
+	}
+	return ".ts"
+}
+
+// jsExtension is the extension of relative imports, ES modules must import
+// files by their full name.
+func jsExtension() string {
+	if params.Module == "esm" {
+		return ".js"
+	}
+	return ""
+}
+
+// queryFields returns the QueryFields entries of fields, see queryURL in
+// twirp.ts: the QueryFields of nested messages, sent as parameters of their
+// own, and the kinds of the values of the other fields that aren't single
+// strings, decoded by servers.
+func queryFields(fields []*fieldValues) []string {
+	var entries []string
+	for _, f := range fields {
+		message := f.ProtoType == descriptor.FieldDescriptorProto_TYPE_MESSAGE || f.ProtoType == descriptor.FieldDescriptorProto_TYPE_GROUP
+		if message && !f.IsRepeated && !f.IsMap && !f.IsTimestamp && !f.IsPlainJSON {
+			entries = append(entries, fmt.Sprintf("%s: () => %s.queryFields", f.Name, f.Type))
+		} else if kind := queryKind(f); kind != "string" {
+			entries = append(entries, fmt.Sprintf("%s: %q", f.Name, kind))
+		}
+	}
+	return entries
+}
+
+// queryKind returns how the values of a field are decoded from query
+// strings: "number", "boolean", "string" or "json" for maps, messages and
+// other values sent as JSON, followed by [] for repeated fields.
+func queryKind(f *fieldValues) string {
+	if f.IsMap {
+		return "json"
+	}
+	kind := "string"
+	switch {
+	case f.IsTimestamp:
+	case f.IsPlainJSON:
+		t, _ := plainJSONType(f.TypeName)
+		switch t = strings.TrimSuffix(t, " | null"); t {
+		case "number", "boolean", "string":
+			kind = t
+		default:
+			kind = "json"
+		}
+	case f.IsEnum:
+		if params.EnumsAsInts {
+			kind = "number"
+		}
+	default:
+		switch f.ProtoType {
+		case descriptor.FieldDescriptorProto_TYPE_MESSAGE, descriptor.FieldDescriptorProto_TYPE_GROUP:
+			kind = "json"
+		case descriptor.FieldDescriptorProto_TYPE_BOOL:
+			kind = "boolean"
+		case descriptor.FieldDescriptorProto_TYPE_STRING, descriptor.FieldDescriptorProto_TYPE_BYTES:
+		case descriptor.FieldDescriptorProto_TYPE_INT64, descriptor.FieldDescriptorProto_TYPE_UINT64,
+			descriptor.FieldDescriptorProto_TYPE_SINT64, descriptor.FieldDescriptorProto_TYPE_FIXED64,
+			descriptor.FieldDescriptorProto_TYPE_SFIXED64:
+			kind = params.LongType
+		default:
+			kind = "number"
+		}
+	}
+	if f.IsRepeated {
+		kind += "[]"
+	}
+	return kind
+}
+
+// fastify reports whether services are served by Fastify plugins, see the
+// server_framework parameter.
+func fastify() bool {
+	return params.Server && params.ServerFramework == "fastify"
+}
+
+// readonly reports whether the readonly interfaces of messages are generated,
+// see the readonly parameter.
+func readonly() bool {
+	return params.Readonly != "none"
+}
+
+// declarationsOnly reports whether only type declarations are generated, see
+// the mode parameter.
+func declarationsOnly() bool {
+	return params.Mode == "declarations"
+}
+
+// constEnums reports whether enums are generated as const enums, which have
+// no runtime object, see the enum_style parameter.
+func constEnums() bool {
+	return params.EnumStyle == "const" || declarationsOnly()
+}
+
+// properties reports whether the fields of message classes are plain
+// properties instead of accessors, see the class_style parameter.
+func properties() bool {
+	return params.ClassStyle == "properties" && generateClasses()
+}
+
+// generateClasses reports whether messages are generated as classes, or only
+// as interfaces.
+func generateClasses() bool {
+	return params.Mode == "classes"
+}
+
+// functional reports whether messages are converted and methods called by
+// standalone functions instead of classes, see the mode parameter.
+func functional() bool {
+	return params.Mode == "functional"
+}
+
+// hasPresence reports whether a field distinguishes unset from its zero
+// value: messages, members of oneofs, proto3 optional fields and proto2
+// fields.
+func hasPresence(fd *descriptor.FileDescriptorProto, field *descriptor.FieldDescriptorProto) bool {
+	if isRepeated(field) {
+		return false
+	}
+	return field.GetProto3Optional() || field.OneofIndex != nil ||
+		field.GetType() == descriptor.FieldDescriptorProto_TYPE_MESSAGE ||
+		field.GetType() == descriptor.FieldDescriptorProto_TYPE_GROUP ||
+		fd.GetSyntax() != "proto3"
+}
+
+// isPacked reports whether a repeated field is encoded packed: by default in
+// proto3, with [packed = true] in proto2.
+func isPacked(fd *descriptor.FileDescriptorProto, field *descriptor.FieldDescriptorProto) bool {
+	if !isRepeated(field) || !isPackable(field.GetType()) {
+		return false
+	}
+	if field.GetOptions() != nil && field.GetOptions().Packed != nil {
+		return field.GetOptions().GetPacked()
+	}
+	return fd.GetSyntax() == "proto3"
+}
+
+func isFloat(t descriptor.FieldDescriptorProto_Type) bool {
+	return t == descriptor.FieldDescriptorProto_TYPE_DOUBLE || t == descriptor.FieldDescriptorProto_TYPE_FLOAT
+}
+
+func isLong(t descriptor.FieldDescriptorProto_Type) bool {
+	switch t {
+	case descriptor.FieldDescriptorProto_TYPE_FIXED64,
+		descriptor.FieldDescriptorProto_TYPE_SFIXED64,
+		descriptor.FieldDescriptorProto_TYPE_INT64,
+		descriptor.FieldDescriptorProto_TYPE_SINT64,
+		descriptor.FieldDescriptorProto_TYPE_UINT64:
+		return true
+	}
+	return false
+}
+
+// isInt32 reports whether t is a 32-bit integer type.
+func isInt32(t descriptor.FieldDescriptorProto_Type) bool {
+	switch t {
+	case descriptor.FieldDescriptorProto_TYPE_FIXED32,
+		descriptor.FieldDescriptorProto_TYPE_SFIXED32,
+		descriptor.FieldDescriptorProto_TYPE_INT32,
+		descriptor.FieldDescriptorProto_TYPE_SINT32,
+		descriptor.FieldDescriptorProto_TYPE_UINT32:
+		return true
+	}
+	return false
+}
+
+// isBranded reports whether fields of type t use a branded number type.
+func isBranded(t descriptor.FieldDescriptorProto_Type) bool {
+	return params.BrandedInts && isInt32(t)
+}
+
+// brandedType returns the branded number type of 32-bit integer fields.
+func brandedType(t descriptor.FieldDescriptorProto_Type) string {
+	switch t {
+	case descriptor.FieldDescriptorProto_TYPE_FIXED32,
+		descriptor.FieldDescriptorProto_TYPE_UINT32:
+		return "UInt32"
+	}
+	return "Int32"
+}
+
+func singularFieldType(resolver *dependencyResolver, f *descriptor.FieldDescriptorProto) string {
 	switch f.GetType() {
 	case descriptor.FieldDescriptorProto_TYPE_DOUBLE,
-		descriptor.FieldDescriptorProto_TYPE_FIXED32,
-		descriptor.FieldDescriptorProto_TYPE_FIXED64,
+		descriptor.FieldDescriptorProto_TYPE_FLOAT:
+		return "number"
+	case descriptor.FieldDescriptorProto_TYPE_FIXED32,
+		descriptor.FieldDescriptorProto_TYPE_SFIXED32,
 		descriptor.FieldDescriptorProto_TYPE_INT32,
+		descriptor.FieldDescriptorProto_TYPE_SINT32,
+		descriptor.FieldDescriptorProto_TYPE_UINT32:
+		if params.BrandedInts {
+			return brandedType(f.GetType())
+		}
+		return "number"
+	case descriptor.FieldDescriptorProto_TYPE_FIXED64,
+		descriptor.FieldDescriptorProto_TYPE_SFIXED64,
 		descriptor.FieldDescriptorProto_TYPE_INT64,
-		descriptor.FieldDescriptorProto_TYPE_UINT32,
+		descriptor.FieldDescriptorProto_TYPE_SINT64,
 		descriptor.FieldDescriptorProto_TYPE_UINT64:
-		return "number"
+		// jsonpb encodes 64-bit integers as strings, numbers lose precision
+		// above 2^53.
+		return params.LongType
 	case descriptor.FieldDescriptorProto_TYPE_ENUM:
-		return removePkg(f.GetTypeName())
+		return resolver.TypeName(f.GetTypeName())
 	case descriptor.FieldDescriptorProto_TYPE_STRING:
 		return "string"
 	case descriptor.FieldDescriptorProto_TYPE_BOOL:
 		return "boolean"
-	case descriptor.FieldDescriptorProto_TYPE_MESSAGE:
+	case descriptor.FieldDescriptorProto_TYPE_MESSAGE,
+		// proto2 groups are generated as nested messages, like protoc-gen-go.
+		descriptor.FieldDescriptorProto_TYPE_GROUP:
 		name := f.GetTypeName()
 
 		// Google WKT Timestamp is a special case here:
 		//
-		// Currently the value will just be left as jsonpb RFC 3339 string.
-		// JSON.stringify already handles serializing Date to its RFC 3339 format.
+		// jsonpb encodes it as an RFC 3339 string, which is either left as-is
+		// or converted to a Date or a Timestamp object, see the timestamp
+		// parameter.
 		//
-		if name == ".google.protobuf.Timestamp" {
-			return "Date"
+		if name == timestampTypeName {
+			return timestampType()
+		}
+
+		if t, ok := plainJSONType(name); ok {
+			return t
 		}
 
-		return removePkg(name)
+		return resolver.TypeName(name)
 	default:
 		//log.Printf("unknown type %q in field %q", f.GetType(), f.GetName())
 		return "string"
@@ -311,13 +1042,117 @@ func singularFieldType(m *descriptor.DescriptorProto, f *descriptor.FieldDescrip
 
 }
 
-func fieldType(f *fieldValues) string {
+// memberType is the type of a field's interface member and accessors, proto3
+// optional fields can additionally be explicitly unset.
+func memberType(f *fieldValues) string {
+	if f.IsOptional {
+		return fieldType(f) + " | undefined"
+	}
+	return fieldType(f)
+}
+
+// accessorType is the type of the accessors of a field in the class of its
+// message, which return undefined for absent fields without a zero value.
+func accessorType(f *fieldValues) string {
+	if f.IsOptional || f.IsRepeated || f.IsMap || hasZeroValue(*f) {
+		return memberType(f)
+	}
+	return fieldType(f) + " | undefined"
+}
+
+// propertyType is the type of the property of a field in message classes
+// with class_style=properties. The fields with presence are undefined when
+// they're unset, instead of returning their zero value like accessors.
+func propertyType(f *fieldValues) string {
+	if f.HasPresence && !f.IsOptional && !f.Required {
+		return fieldType(f) + " | undefined"
+	}
+	return accessorType(f)
+}
+
+// interfaceMemberType is the type of a field's member in the message
+// interfaces, which reference the interfaces of other messages instead of
+// their classes when there are none.
+func interfaceMemberType(f *fieldValues, json bool) string {
+	if generateClasses() {
+		return memberType(f)
+	}
+
+	declared := *f
+	if f.IsMap {
+		value := *f.MapValue
+		value.Type = declaredType(&value, json)
+		declared.MapValue = &value
+	} else {
+		declared.Type = declaredType(f, json)
+	}
+	return memberType(&declared)
+}
+
+// readonlyMemberType is the type of a field's member in the readonly
+// interfaces of messages, which reference the readonly interfaces of other
+// messages.
+func readonlyMemberType(f *fieldValues) string {
+	if f.IsOptional {
+		return readonlyType(f) + " | undefined"
+	}
+	return readonlyType(f)
+}
+
+func readonlyType(f *fieldValues) string {
+	if f.IsMap {
+		return fmt.Sprintf("{ readonly [key: %s]: %s }", mapKeyType(f.MapKey), readonlyType(f.MapValue))
+	}
 	t := f.Type
-	if t == "Date" {
-		t = "string"
+	if (f.ProtoType == descriptor.FieldDescriptorProto_TYPE_MESSAGE || f.ProtoType == descriptor.FieldDescriptorProto_TYPE_GROUP) && !f.IsPlainJSON && !f.IsTimestamp {
+		t = typeToReadonlyInterface(t)
+	}
+	if f.IsRepeated {
+		return "ReadonlyArray<" + t + ">"
+	}
+	return t
+}
+
+// hasReadonlyInterface reports whether the type of field is a message with a
+// readonly interface, well-known types mapped to plain JSON don't have one.
+func hasReadonlyInterface(field *descriptor.FieldDescriptorProto) bool {
+	switch field.GetType() {
+	case descriptor.FieldDescriptorProto_TYPE_MESSAGE, descriptor.FieldDescriptorProto_TYPE_GROUP:
+		return !isPlainJSONType(field.GetTypeName()) && field.GetTypeName() != timestampTypeName
+	}
+	return false
+}
+
+func declaredType(f *fieldValues, json bool) string {
+	switch {
+	case f.ProtoType != descriptor.FieldDescriptorProto_TYPE_MESSAGE && f.ProtoType != descriptor.FieldDescriptorProto_TYPE_GROUP,
+		f.IsPlainJSON, f.IsTimestamp:
+		return f.Type
+	case json:
+		return typeToJSONInterface(f.Type)
 	}
+	return typeToInterface(f.Type)
+}
+
+func fieldType(f *fieldValues) string {
+	if f.IsMap {
+		return fmt.Sprintf("{ [key: %s]: %s }", mapKeyType(f.MapKey), fieldType(f.MapValue))
+	}
+	t := f.Type
 	if f.IsRepeated {
+		if strings.Contains(t, "|") {
+			t = "(" + t + ")"
+		}
 		return t + "[]"
 	}
 	return t
 }
+
+// mapKeyType returns the index signature type for a map key. JSON object keys
+// are always strings, integral keys are exposed as numbers for convenience.
+func mapKeyType(key *fieldValues) string {
+	if key.Type == "number" || isBranded(key.ProtoType) {
+		return "number"
+	}
+	return "string"
+}
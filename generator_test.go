@@ -0,0 +1,214 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+	plugin "github.com/golang/protobuf/protoc-gen-go/plugin"
+)
+
+//go:generate sh testdata/generate.sh
+
+var update = flag.Bool("update", false, "update the golden files of testdata/golden")
+
+// goldenTests generate the files of .proto files of testdata with a
+// parameter and compare them with the files of testdata/golden/<name>. The
+// twirp.ts runtime is only compared by the tests setting runtime.
+var goldenTests = []struct {
+	name      string
+	files     []string
+	parameter string
+	runtime   bool
+}{
+	{name: "classes", files: []string{"users.proto"}, runtime: true},
+	{name: "long_string", files: []string{"users.proto"}, parameter: "long=string"},
+	{name: "names", files: []string{"names.proto"}},
+	{name: "extensions", files: []string{"extensions.proto"}},
+	{name: "emit_defaults", files: []string{"users.proto"}, parameter: "emit_defaults=true"},
+	{name: "enums_as_ints", files: []string{"users.proto"}, parameter: "enums_as_ints=true"},
+	{name: "timestamp_date", files: []string{"users.proto"}, parameter: "timestamp=date"},
+	{name: "branded_ints", files: []string{"users.proto"}, parameter: "branded_ints=true"},
+	{name: "source_relative", files: []string{"acme/common/types.proto", "acme/billing/types.proto"}, parameter: "paths=source_relative"},
+	{name: "declarations", files: []string{"users.proto"}, parameter: "mode=declarations"},
+	{name: "esm", files: []string{"users.proto"}, parameter: "module=esm"},
+	{name: "interfaces", files: []string{"users.proto"}, parameter: "mode=interfaces"},
+	{name: "services", files: []string{"users.proto"}, parameter: "services=Users,exclude_messages=^ListUsers"},
+	{name: "names_original", files: []string{"names.proto"}, parameter: "field_naming=original"},
+	{name: "protobuf", files: []string{"users.proto"}, parameter: "protobuf=true"},
+	{name: "streaming", files: []string{"stream.proto"}},
+	{name: "websocket", files: []string{"users.proto"}, parameter: "websocket=true", runtime: true},
+	{name: "batching", files: []string{"batch.proto"}, parameter: "batch_option=acme.batch.batch", runtime: true},
+	{name: "mocks", files: []string{"users.proto"}, parameter: "mocks=true"},
+	{name: "fakes", files: []string{"users.proto"}, parameter: "fakes=true", runtime: true},
+	{name: "server", files: []string{"users.proto"}, parameter: "server=true", runtime: true},
+	{name: "server_fastify", files: []string{"users.proto"}, parameter: "server=true,server_framework=fastify", runtime: true},
+	{name: "msw", files: []string{"users.proto"}, parameter: "msw=true", runtime: true},
+	{name: "vue", files: []string{"users.proto"}, parameter: "vue=true", runtime: true},
+	{name: "edge", files: []string{"users.proto"}, parameter: "edge=true", runtime: true},
+	{name: "http_get", files: []string{"users.proto"}, parameter: "http_get=true,server=true,fakes=true,msw=true", runtime: true},
+	{name: "twirp_version", files: []string{"users.proto"}, parameter: "twirp_version=v5", runtime: true},
+	{name: "readonly", files: []string{"users.proto"}, parameter: "readonly=interfaces"},
+	{name: "required_fields", files: []string{"users.proto"}, parameter: "required_fields=true"},
+	{name: "io_ts", files: []string{"users.proto"}, parameter: "io_ts=true"},
+	{name: "json_schema", files: []string{"users.proto"}, parameter: "json_schema=true"},
+	{name: "const_enums", files: []string{"users.proto"}, parameter: "enum_style=const"},
+	{name: "namespaces", files: []string{"users.proto"}, parameter: "namespaces=true"},
+	{name: "functional", files: []string{"users.proto"}, parameter: "mode=functional", runtime: true},
+	{name: "functional_emit_defaults", files: []string{"users.proto"}, parameter: "mode=functional,emit_defaults=true"},
+	{name: "es5", files: []string{"users.proto"}, parameter: "target=es5", runtime: true},
+	{name: "properties", files: []string{"users.proto"}, parameter: "class_style=properties"},
+	{name: "names_properties", files: []string{"names.proto"}, parameter: "class_style=properties,field_naming=both"},
+	{name: "immutable", files: []string{"users.proto"}, parameter: "immutable=true"},
+	{name: "builders", files: []string{"users.proto"}, parameter: "builders=true"},
+	{name: "names_builders", files: []string{"names.proto"}, parameter: "builders=true"},
+	{name: "nominal", files: []string{"users.proto"}, parameter: "nominal=true"},
+}
+
+func TestMain(m *testing.M) {
+	flag.Parse()
+	// The plugin logs the files it writes and its warnings.
+	log.SetOutput(ioutil.Discard)
+	os.Exit(m.Run())
+}
+
+func TestGolden(t *testing.T) {
+	for _, tt := range goldenTests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := generate(request(t, tt.parameter, tt.files...))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if res.Error != nil {
+				t.Fatal(res.GetError())
+			}
+
+			dir := filepath.Join("testdata", "golden", tt.name)
+			if *update {
+				if err := os.RemoveAll(dir); err != nil {
+					t.Fatal(err)
+				}
+			}
+			want := map[string]bool{}
+			for _, f := range res.File {
+				if f.GetName() == twirpFileName && !tt.runtime {
+					continue
+				}
+				want[f.GetName()] = true
+				path := filepath.Join(dir, filepath.FromSlash(f.GetName()))
+				if *update {
+					if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+						t.Fatal(err)
+					}
+					if err := ioutil.WriteFile(path, []byte(f.GetContent()), 0644); err != nil {
+						t.Fatal(err)
+					}
+					continue
+				}
+				golden, err := ioutil.ReadFile(path)
+				if err != nil {
+					t.Errorf("%s: %v, run go test -update", f.GetName(), err)
+					continue
+				}
+				if string(golden) != f.GetContent() {
+					t.Errorf("%s differs from %s, run go test -update and check the diff", f.GetName(), path)
+				}
+			}
+
+			// The golden files of files that aren't generated anymore.
+			filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+				if err == nil && !info.IsDir() {
+					name := filepath.ToSlash(strings.TrimPrefix(path, dir+string(filepath.Separator)))
+					if !want[name] {
+						t.Errorf("%s isn't generated, run go test -update", name)
+					}
+				}
+				return nil
+			})
+		})
+	}
+}
+
+func TestGenerateErrors(t *testing.T) {
+	tests := []struct {
+		files     []string
+		parameter string
+		want      string
+	}{
+		{[]string{"acme/common/types.proto", "acme/billing/types.proto"}, "paths=flat",
+			"conflicting output files with paths=flat, rename the files or use another paths parameter:\n" +
+				"acme/common/types.proto and acme/billing/types.proto are both generated to types.ts"},
+		{[]string{"users.proto"}, "strict=true",
+			"unsupported constructs:\n" +
+				"users.proto:24:3: oneof acme.users.User.contact is generated as optional fields, setting a member doesn't clear the others"},
+		{[]string{"required.proto"}, "required_option=acme.required.required",
+			"field phone can't be required with option acme.required.required, only proto3 fields without presence outside of oneofs can"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.parameter, func(t *testing.T) {
+			res, err := generate(request(t, tt.parameter, tt.files...))
+			if err == nil && res.Error != nil {
+				err = errors.New(res.GetError())
+			}
+			if err == nil || err.Error() != tt.want {
+				t.Errorf("generate(%s, %q) error = %v, want %q", strings.Join(tt.files, " "), tt.parameter, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestCamelCase(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"name", "name"},
+		{"page_size", "pageSize"},
+		{"default_", "default"},
+		{"_hidden", "Hidden"},
+		{"a__b", "aB"},
+		{"has_2fa", "has2fa"},
+	}
+
+	for _, tt := range tests {
+		if got := camelCase(tt.name); got != tt.want {
+			t.Errorf("camelCase(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+// request returns the request of protoc generating files of testdata with a
+// parameter, reading their descriptor sets, see testdata/generate.sh.
+func request(t *testing.T, parameter string, files ...string) *plugin.CodeGeneratorRequest {
+	t.Helper()
+	req := &plugin.CodeGeneratorRequest{
+		FileToGenerate: files,
+		Parameter:      proto.String(parameter),
+	}
+	seen := map[string]bool{}
+	for _, name := range files {
+		b, err := ioutil.ReadFile(filepath.Join("testdata", strings.TrimSuffix(name, ".proto")+".pb"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var set descriptor.FileDescriptorSet
+		if err := proto.Unmarshal(b, &set); err != nil {
+			t.Fatal(err)
+		}
+		for _, fd := range set.GetFile() {
+			if !seen[fd.GetName()] {
+				seen[fd.GetName()] = true
+				req.ProtoFile = append(req.ProtoFile, fd)
+			}
+		}
+	}
+	return req
+}
@@ -0,0 +1,32 @@
+package main
+
+// reservedIdentifiers are TypeScript reserved words and names of members
+// generated on every message class, they can't be used as-is for generated
+// types, accessors or methods.
+var reservedIdentifiers = map[string]struct{}{
+	"break": {}, "case": {}, "catch": {}, "class": {}, "const": {},
+	"continue": {}, "debugger": {}, "default": {}, "delete": {}, "do": {},
+	"else": {}, "enum": {}, "export": {}, "extends": {}, "false": {},
+	"finally": {}, "for": {}, "function": {}, "if": {}, "import": {},
+	"in": {}, "instanceof": {}, "new": {}, "null": {}, "return": {},
+	"super": {}, "switch": {}, "this": {}, "throw": {}, "true": {},
+	"try": {}, "typeof": {}, "var": {}, "void": {}, "while": {}, "with": {},
+
+	// Strict mode and contextual keywords.
+	"as": {}, "implements": {}, "interface": {}, "let": {}, "package": {},
+	"private": {}, "protected": {}, "public": {}, "static": {}, "yield": {},
+	"any": {}, "boolean": {}, "number": {}, "string": {}, "symbol": {},
+	"type": {}, "await": {}, "async": {}, "arguments": {}, "eval": {},
+
+	// Generated members.
+	"constructor": {}, "toJSON": {}, "fromJSON": {}, "_json": {}, "_unknown": {},
+	"clone": {}, "equals": {},
+}
+
+// safeIdentifier suffixes name with an underscore if it's reserved.
+func safeIdentifier(name string) string {
+	if _, ok := reservedIdentifiers[name]; ok {
+		return name + "_"
+	}
+	return name
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+)
+
+// typeToJSONCodec returns the name of the io-ts codec validating the proto3
+// JSON of messages of typeName, see io_ts.
+func typeToJSONCodec(typeName string) string {
+	return typeName + "JSONCodec"
+}
+
+// hasJSONCodec reports whether the type of field is a message with a
+// generated JSON codec. The well-known types are validated as plain JSON
+// values instead, see valueCodec.
+func hasJSONCodec(field *descriptor.FieldDescriptorProto) bool {
+	switch field.GetType() {
+	case descriptor.FieldDescriptorProto_TYPE_MESSAGE, descriptor.FieldDescriptorProto_TYPE_GROUP:
+		return !strings.HasPrefix(field.GetTypeName(), ".google.protobuf.") && !isPlainJSONType(field.GetTypeName())
+	}
+	return false
+}
+
+// fieldCodec returns the io-ts codec of the proto3 JSON of a field in the
+// JSON codec of its message.
+func fieldCodec(fv fieldValues) string {
+	switch {
+	case fv.IsMap:
+		return fmt.Sprintf("t.record(t.string, %s)", valueCodec(*fv.MapValue))
+	case fv.IsRepeated:
+		return fmt.Sprintf("t.array(%s)", valueCodec(fv))
+	}
+	return valueCodec(fv)
+}
+
+// valueCodec returns the codec of a single value of a field. The proto3 JSON
+// mapping accepts numbers as strings too and enums as names or numbers.
+// Messages and enums are referenced lazily, they may be declared later or in
+// a file importing this one.
+func valueCodec(fv fieldValues) string {
+	switch t := fv.ProtoType; {
+	case t == descriptor.FieldDescriptorProto_TYPE_STRING, t == descriptor.FieldDescriptorProto_TYPE_BYTES:
+		return "t.string"
+	case t == descriptor.FieldDescriptorProto_TYPE_BOOL:
+		return "t.boolean"
+	case isFloat(t), isInt32(t), isLong(t):
+		return "t.union([t.number, t.string])"
+	case t == descriptor.FieldDescriptorProto_TYPE_ENUM:
+		return fmt.Sprintf("t.recursion(%q, (): t.Mixed => t.union([t.keyof(%sValues), t.number]))", fv.Type, fv.Type)
+	case fv.IsTimestamp:
+		return "t.string"
+	case fv.IsPlainJSON:
+		return plainJSONCodec(fv.TypeName)
+	case strings.HasPrefix(fv.TypeName, ".google.protobuf."):
+		return "t.unknown"
+	}
+	return fmt.Sprintf("t.recursion(%q, (): t.Mixed => %s)", fv.Type, typeToJSONCodec(fv.Type))
+}
+
+// plainJSONCodec returns the codec of the well-known types that are plain
+// JSON values, see plainJSONType.
+func plainJSONCodec(typeName string) string {
+	switch typeName {
+	case ".google.protobuf.ListValue":
+		return "t.UnknownArray"
+	case ".google.protobuf.Value":
+		return "t.unknown"
+	case ".google.protobuf.FieldMask":
+		return "t.string"
+	case ".google.protobuf.DoubleValue",
+		".google.protobuf.FloatValue",
+		".google.protobuf.Int32Value",
+		".google.protobuf.UInt32Value",
+		".google.protobuf.Int64Value",
+		".google.protobuf.UInt64Value":
+		return "t.union([t.number, t.string, t.null])"
+	case ".google.protobuf.BoolValue":
+		return "t.union([t.boolean, t.null])"
+	case ".google.protobuf.StringValue",
+		".google.protobuf.BytesValue":
+		return "t.union([t.string, t.null])"
+	}
+	return "t.UnknownRecord"
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+)
+
+// schemaDialect is the JSON Schema draft of the .schema.json files.
+const schemaDialect = "https://json-schema.org/draft/2020-12/schema"
+
+// messageSchema is a JSON Schema of the proto3 JSON of a message, generated
+// with json_schema=true. The messages it references are in its $defs, so
+// each file can be used on its own.
+type messageSchema struct {
+	Schema          string                    `json:"$schema,omitempty"`
+	ID              string                    `json:"$id,omitempty"`
+	Ref             string                    `json:"$ref,omitempty"`
+	Title           string                    `json:"title,omitempty"`
+	Deprecated      bool                      `json:"deprecated,omitempty"`
+	Type            interface{}               `json:"type,omitempty"`
+	Format          string                    `json:"format,omitempty"`
+	Pattern         string                    `json:"pattern,omitempty"`
+	ContentEncoding string                    `json:"contentEncoding,omitempty"`
+	Enum            []interface{}             `json:"enum,omitempty"`
+	Properties      map[string]*messageSchema `json:"properties,omitempty"`
+	Items           *messageSchema            `json:"items,omitempty"`
+	Additional      *messageSchema            `json:"additionalProperties,omitempty"`
+	Defs            map[string]*messageSchema `json:"$defs,omitempty"`
+}
+
+// schemaFileName returns the name of the .schema.json file of the message
+// typeName, its full name, e.g. acme.users.User.schema.json.
+func schemaFileName(typeName string) string {
+	return strings.TrimPrefix(typeName, ".") + ".schema.json"
+}
+
+// MessageSchema returns the JSON Schema of the message typeName as indented
+// JSON.
+func (d *dependencyResolver) MessageSchema(typeName string) (string, error) {
+	defs := make(map[string]*messageSchema)
+	root := d.messageSchema(typeName, typeName, defs)
+	delete(defs, strings.TrimPrefix(typeName, "."))
+
+	root.Schema = schemaDialect
+	root.ID = schemaFileName(typeName)
+	if len(defs) > 0 {
+		root.Defs = defs
+	}
+	b, err := json.MarshalIndent(root, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(b) + "\n", nil
+}
+
+// messageSchema returns the schema of the message typeName, adding the
+// messages its fields reference to defs. References to root point at the
+// schema itself.
+func (d *dependencyResolver) messageSchema(typeName, root string, defs map[string]*messageSchema) *messageSchema {
+	msg := d.messages[typeName]
+	schema := &messageSchema{
+		Title:      strings.TrimPrefix(typeName, "."),
+		Deprecated: msg.GetOptions().GetDeprecated(),
+		Type:       "object",
+		Properties: make(map[string]*messageSchema),
+	}
+	// Recursive messages reference the schema being built.
+	defs[schema.Title] = schema
+	for _, field := range msg.GetField() {
+		s := d.fieldJSONSchema(field, root, defs)
+		s.Deprecated = field.GetOptions().GetDeprecated()
+		schema.Properties[field.GetName()] = s
+	}
+	return schema
+}
+
+func (d *dependencyResolver) fieldJSONSchema(field *descriptor.FieldDescriptorProto, root string, defs map[string]*messageSchema) *messageSchema {
+	if field.GetType() == descriptor.FieldDescriptorProto_TYPE_MESSAGE {
+		if entry, ok := d.messages[field.GetTypeName()]; ok && entry.GetOptions().GetMapEntry() {
+			return &messageSchema{Type: "object", Additional: d.valueJSONSchema(mapEntryField(entry, 2), root, defs)}
+		}
+	}
+	if isRepeated(field) {
+		return &messageSchema{Type: "array", Items: d.valueJSONSchema(field, root, defs)}
+	}
+	return d.valueJSONSchema(field, root, defs)
+}
+
+// valueJSONSchema returns the schema of a single value of field. Like in
+// valueSchema, numbers may be strings too, enums are their names or numbers.
+func (d *dependencyResolver) valueJSONSchema(field *descriptor.FieldDescriptorProto, root string, defs map[string]*messageSchema) *messageSchema {
+	typeName := field.GetTypeName()
+	switch t := field.GetType(); {
+	case t == descriptor.FieldDescriptorProto_TYPE_STRING:
+		return &messageSchema{Type: "string"}
+	case t == descriptor.FieldDescriptorProto_TYPE_BYTES:
+		return &messageSchema{Type: "string", ContentEncoding: "base64"}
+	case t == descriptor.FieldDescriptorProto_TYPE_BOOL:
+		return &messageSchema{Type: "boolean"}
+	case isFloat(t):
+		return &messageSchema{Type: []string{"number", "string"}}
+	case isInt32(t), isLong(t):
+		return &messageSchema{Type: []string{"integer", "string"}}
+	case t == descriptor.FieldDescriptorProto_TYPE_ENUM:
+		var values []interface{}
+		for _, v := range d.enums[typeName].GetValue() {
+			values = append(values, v.GetName())
+		}
+		for _, v := range d.enums[typeName].GetValue() {
+			values = append(values, v.GetNumber())
+		}
+		return &messageSchema{Enum: values}
+	case typeName == timestampTypeName:
+		return &messageSchema{Type: "string", Format: "date-time"}
+	case typeName == ".google.protobuf.Duration":
+		return &messageSchema{Type: "string", Pattern: `^-?[0-9]+(\.[0-9]{1,9})?s$`}
+	case isPlainJSONType(typeName):
+		return plainJSONSchema(typeName)
+	case strings.HasPrefix(typeName, ".google.protobuf."):
+		return &messageSchema{}
+	case typeName == root:
+		return &messageSchema{Ref: "#"}
+	}
+
+	name := strings.TrimPrefix(typeName, ".")
+	if _, ok := defs[name]; !ok {
+		d.messageSchema(typeName, root, defs)
+	}
+	return &messageSchema{Ref: "#/$defs/" + name}
+}
+
+// plainJSONSchema returns the schema of the well-known types that are plain
+// JSON values, see plainJSONType.
+func plainJSONSchema(typeName string) *messageSchema {
+	switch typeName {
+	case ".google.protobuf.ListValue":
+		return &messageSchema{Type: "array"}
+	case ".google.protobuf.Value":
+		return &messageSchema{}
+	case ".google.protobuf.FieldMask":
+		return &messageSchema{Type: "string"}
+	case ".google.protobuf.DoubleValue",
+		".google.protobuf.FloatValue":
+		return &messageSchema{Type: []string{"number", "string", "null"}}
+	case ".google.protobuf.Int32Value",
+		".google.protobuf.UInt32Value",
+		".google.protobuf.Int64Value",
+		".google.protobuf.UInt64Value":
+		return &messageSchema{Type: []string{"integer", "string", "null"}}
+	case ".google.protobuf.BoolValue":
+		return &messageSchema{Type: []string{"boolean", "null"}}
+	case ".google.protobuf.StringValue":
+		return &messageSchema{Type: []string{"string", "null"}}
+	case ".google.protobuf.BytesValue":
+		return &messageSchema{Type: []string{"string", "null"}, ContentEncoding: "base64"}
+	}
+	return &messageSchema{Type: "object"}
+}
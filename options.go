@@ -0,0 +1,436 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+)
+
+const (
+	fieldOptionsTypeName   = ".google.protobuf.FieldOptions"
+	messageOptionsTypeName = ".google.protobuf.MessageOptions"
+	serviceOptionsTypeName = ".google.protobuf.ServiceOptions"
+	methodOptionsTypeName  = ".google.protobuf.MethodOptions"
+)
+
+// extensionField is an extension field with its fully qualified name.
+type extensionField struct {
+	Name  string
+	Field *descriptor.FieldDescriptorProto
+}
+
+// optionValue is a custom option, Value is its JSON encoded value.
+type optionValue struct {
+	Name  string
+	Value string
+}
+
+// Options decodes the custom options set in opts, an options message of type
+// extendee. Custom options are extensions, which protoc passes to plugins as
+// unknown fields.
+func (d *dependencyResolver) Options(extendee string, opts proto.Message) ([]*optionValue, error) {
+	exts := d.extensions[extendee]
+	if len(exts) == 0 || reflect.ValueOf(opts).IsNil() {
+		return nil, nil
+	}
+
+	b, err := proto.Marshal(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	values := make(map[int32]interface{})
+	err = d.decodeFields(b, func(number int32) *descriptor.FieldDescriptorProto {
+		if ext, ok := exts[number]; ok {
+			return ext.Field
+		}
+		return nil
+	}, values)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", strings.TrimPrefix(extendee, "."), err)
+	}
+
+	var numbers []int32
+	for number := range values {
+		numbers = append(numbers, number)
+	}
+	sort.Slice(numbers, func(i, j int) bool { return numbers[i] < numbers[j] })
+
+	var options []*optionValue
+	for _, number := range numbers {
+		v, err := json.Marshal(values[number])
+		if err != nil {
+			return nil, err
+		}
+		options = append(options, &optionValue{
+			Name:  exts[number].Name,
+			Value: string(v),
+		})
+	}
+	return options, nil
+}
+
+// ErrorMeta returns the fully qualified name of the message describing the
+// error meta of a service or method, set with an option named by the
+// error_meta_option parameter, or "" without one. The option is a string
+// holding the name of the message, qualified or relative to the package of
+// fd.
+func (d *dependencyResolver) ErrorMeta(fd *descriptor.FileDescriptorProto, extendee string, opts proto.Message) (string, error) {
+	if len(params.ErrorMetaOptions) == 0 {
+		return "", nil
+	}
+	options, err := d.Options(extendee, opts)
+	if err != nil {
+		return "", err
+	}
+	for _, o := range options {
+		if !isErrorMetaOption(o.Name) {
+			continue
+		}
+		var name string
+		if err := json.Unmarshal([]byte(o.Value), &name); err != nil {
+			return "", fmt.Errorf("option %s must be a string naming a message", o.Name)
+		}
+		for _, typeName := range []string{"." + strings.TrimPrefix(name, "."), fullTypeName(fd, name)} {
+			if _, ok := d.messages[typeName]; ok {
+				return typeName, nil
+			}
+		}
+		return "", fmt.Errorf("message %s of option %s not found", name, o.Name)
+	}
+	return "", nil
+}
+
+func isErrorMetaOption(name string) bool {
+	for _, option := range params.ErrorMetaOptions {
+		if option == name {
+			return true
+		}
+	}
+	return false
+}
+
+// subscribePath returns the path of the server-sent events endpoint of a
+// method, relative to the route of its service, set with the option named by
+// the subscribe_option parameter, or "" without one. The option is either a
+// string holding the path or a bool, true for the method's route followed by
+// /events.
+func subscribePath(method string, options []*optionValue) (string, error) {
+	if params.SubscribeOption == "" {
+		return "", nil
+	}
+	for _, o := range options {
+		if o.Name != params.SubscribeOption {
+			continue
+		}
+		var v interface{}
+		if err := json.Unmarshal([]byte(o.Value), &v); err != nil {
+			return "", err
+		}
+		switch v := v.(type) {
+		case bool:
+			if v {
+				return method + "/events", nil
+			}
+			return "", nil
+		case string:
+			return strings.TrimPrefix(v, "/"), nil
+		}
+		return "", fmt.Errorf("option %s must be a string or a bool", o.Name)
+	}
+	return "", nil
+}
+
+// requiredHeaders returns the names of the headers required by the methods of
+// a service or by a method, set with an option named by the headers_option
+// parameter. The option is a repeated string or a string, one header each.
+func requiredHeaders(options []*optionValue) ([]string, error) {
+	for _, o := range options {
+		if !isHeadersOption(o.Name) {
+			continue
+		}
+		var headers []string
+		if err := json.Unmarshal([]byte(o.Value), &headers); err == nil {
+			return headers, nil
+		}
+		var header string
+		if err := json.Unmarshal([]byte(o.Value), &header); err != nil {
+			return nil, fmt.Errorf("option %s must be a string or a repeated string naming headers", o.Name)
+		}
+		return []string{header}, nil
+	}
+	return nil, nil
+}
+
+func isHeadersOption(name string) bool {
+	for _, option := range params.HeadersOptions {
+		if option == name {
+			return true
+		}
+	}
+	return false
+}
+
+// isRequired reports whether the member of fv is non-optional: for all the
+// fields without presence with the required_fields parameter, or for the ones
+// setting the bool option named by the required_option parameter. Fields with
+// presence, e.g. messages and oneof members, and fields without a zero value
+// can't be required.
+func (d *dependencyResolver) isRequired(field *descriptor.FieldDescriptorProto, fv *fieldValues) (bool, error) {
+	canRequire := !fv.HasPresence && defaultValue(*fv) != ""
+	required := params.RequiredFields && canRequire
+	if params.RequiredOption == "" {
+		return required, nil
+	}
+	options, err := d.Options(fieldOptionsTypeName, field.GetOptions())
+	if err != nil {
+		return false, err
+	}
+	for _, o := range options {
+		if o.Name != params.RequiredOption || o.Value != "true" {
+			continue
+		}
+		if !canRequire {
+			return false, fmt.Errorf("field %s can't be required with option %s, only proto3 fields without presence outside of oneofs can", field.GetName(), o.Name)
+		}
+		return true, nil
+	}
+	return required, nil
+}
+
+// noSideEffectsOption is the method option of Twirp marking methods without
+// side effects, like idempotency_level = NO_SIDE_EFFECTS.
+const noSideEffectsOption = "twirp.no_side_effects"
+
+// noSideEffects reports whether method has no side effects, set with its
+// idempotency_level or the (twirp.no_side_effects) option.
+func noSideEffects(method *descriptor.MethodDescriptorProto, options []*optionValue) bool {
+	if method.GetOptions().GetIdempotencyLevel() == descriptor.MethodOptions_NO_SIDE_EFFECTS {
+		return true
+	}
+	for _, o := range options {
+		if o.Name == noSideEffectsOption {
+			return o.Value == "true"
+		}
+	}
+	return false
+}
+
+// decodeFields decodes the fields of the wire encoded message b into values,
+// fields returns the descriptor of a field number or nil to skip it.
+func (d *dependencyResolver) decodeFields(b []byte, fields func(number int32) *descriptor.FieldDescriptorProto, values map[int32]interface{}) error {
+	for len(b) > 0 {
+		tag, n := binary.Uvarint(b)
+		if n <= 0 {
+			return errors.New("invalid tag")
+		}
+		b = b[n:]
+
+		number, wireType := int32(tag>>3), tag&7
+		var raw uint64
+		var bytes []byte
+		switch wireType {
+		case 0:
+			raw, n = binary.Uvarint(b)
+			if n <= 0 {
+				return errors.New("invalid varint")
+			}
+		case 1:
+			if len(b) < 8 {
+				return errors.New("invalid fixed64")
+			}
+			raw, n = binary.LittleEndian.Uint64(b), 8
+		case 2:
+			size, m := binary.Uvarint(b)
+			if m <= 0 || uint64(len(b)-m) < size {
+				return errors.New("invalid length")
+			}
+			bytes, n = b[m:m+int(size)], m+int(size)
+		case 5:
+			if len(b) < 4 {
+				return errors.New("invalid fixed32")
+			}
+			raw, n = uint64(binary.LittleEndian.Uint32(b)), 4
+		default:
+			return fmt.Errorf("unsupported wire type %d", wireType)
+		}
+		b = b[n:]
+
+		field := fields(number)
+		if field == nil {
+			continue
+		}
+
+		var decoded []interface{}
+		switch {
+		case wireType == 2 && isPackable(field.GetType()):
+			// Packed repeated scalars.
+			for len(bytes) > 0 {
+				switch field.GetType() {
+				case descriptor.FieldDescriptorProto_TYPE_DOUBLE,
+					descriptor.FieldDescriptorProto_TYPE_FIXED64,
+					descriptor.FieldDescriptorProto_TYPE_SFIXED64:
+					if len(bytes) < 8 {
+						return errors.New("invalid packed fixed64")
+					}
+					raw, n = binary.LittleEndian.Uint64(bytes), 8
+				case descriptor.FieldDescriptorProto_TYPE_FLOAT,
+					descriptor.FieldDescriptorProto_TYPE_FIXED32,
+					descriptor.FieldDescriptorProto_TYPE_SFIXED32:
+					if len(bytes) < 4 {
+						return errors.New("invalid packed fixed32")
+					}
+					raw, n = uint64(binary.LittleEndian.Uint32(bytes)), 4
+				default:
+					raw, n = binary.Uvarint(bytes)
+					if n <= 0 {
+						return errors.New("invalid packed varint")
+					}
+				}
+				bytes = bytes[n:]
+				decoded = append(decoded, d.scalarValue(field, raw))
+			}
+		case wireType == 2:
+			v, err := d.bytesValue(field, bytes)
+			if err != nil {
+				return fmt.Errorf("%s: %v", field.GetName(), err)
+			}
+			decoded = append(decoded, v)
+		default:
+			decoded = append(decoded, d.scalarValue(field, raw))
+		}
+
+		if field.GetLabel() == descriptor.FieldDescriptorProto_LABEL_REPEATED {
+			list, _ := values[number].([]interface{})
+			values[number] = append(list, decoded...)
+		} else {
+			values[number] = decoded[len(decoded)-1]
+		}
+	}
+	return nil
+}
+
+// scalarValue converts the varint or fixed raw value of a field to its JSON
+// value.
+func (d *dependencyResolver) scalarValue(field *descriptor.FieldDescriptorProto, raw uint64) interface{} {
+	var v interface{}
+	switch field.GetType() {
+	case descriptor.FieldDescriptorProto_TYPE_BOOL:
+		return raw != 0
+	case descriptor.FieldDescriptorProto_TYPE_ENUM:
+		if enum := d.enums[field.GetTypeName()]; enum != nil {
+			for _, value := range enum.GetValue() {
+				if value.GetNumber() == int32(raw) {
+					return value.GetName()
+				}
+			}
+		}
+		return int32(raw)
+	case descriptor.FieldDescriptorProto_TYPE_DOUBLE:
+		return floatValue(math.Float64frombits(raw))
+	case descriptor.FieldDescriptorProto_TYPE_FLOAT:
+		return floatValue(float64(math.Float32frombits(uint32(raw))))
+	case descriptor.FieldDescriptorProto_TYPE_INT32,
+		descriptor.FieldDescriptorProto_TYPE_SFIXED32:
+		return int32(raw)
+	case descriptor.FieldDescriptorProto_TYPE_UINT32,
+		descriptor.FieldDescriptorProto_TYPE_FIXED32:
+		return uint32(raw)
+	case descriptor.FieldDescriptorProto_TYPE_SINT32:
+		return int32(uint32(raw)>>1) ^ -int32(raw&1)
+	case descriptor.FieldDescriptorProto_TYPE_INT64,
+		descriptor.FieldDescriptorProto_TYPE_SFIXED64:
+		v = int64(raw)
+	case descriptor.FieldDescriptorProto_TYPE_SINT64:
+		v = int64(raw>>1) ^ -int64(raw&1)
+	default:
+		v = raw
+	}
+
+	// 64-bit integers follow the long parameter.
+	if params.LongType == "string" {
+		return fmt.Sprint(v)
+	}
+	return v
+}
+
+// bytesValue converts a length delimited field to its JSON value.
+func (d *dependencyResolver) bytesValue(field *descriptor.FieldDescriptorProto, b []byte) (interface{}, error) {
+	switch field.GetType() {
+	case descriptor.FieldDescriptorProto_TYPE_STRING:
+		return string(b), nil
+	case descriptor.FieldDescriptorProto_TYPE_BYTES:
+		return base64.StdEncoding.EncodeToString(b), nil
+	}
+
+	msg := d.messages[field.GetTypeName()]
+	if msg == nil {
+		return nil, fmt.Errorf("unknown type %s", field.GetTypeName())
+	}
+	values := make(map[int32]interface{})
+	err := d.decodeFields(b, func(number int32) *descriptor.FieldDescriptorProto {
+		for _, f := range msg.GetField() {
+			if f.GetNumber() == number {
+				return f
+			}
+		}
+		return nil
+	}, values)
+	if err != nil {
+		return nil, err
+	}
+
+	obj := make(map[string]interface{})
+	for _, f := range msg.GetField() {
+		name := f.GetJsonName()
+		if name == "" {
+			name = camelCase(f.GetName())
+		}
+		if v, ok := values[f.GetNumber()]; ok {
+			obj[name] = v
+		}
+	}
+	return obj, nil
+}
+
+// floatValue encodes non-finite floats as strings, like jsonpb.
+func floatValue(f float64) interface{} {
+	switch {
+	case math.IsNaN(f):
+		return "NaN"
+	case math.IsInf(f, 1):
+		return "Infinity"
+	case math.IsInf(f, -1):
+		return "-Infinity"
+	}
+	return f
+}
+
+func isPackable(t descriptor.FieldDescriptorProto_Type) bool {
+	switch t {
+	case descriptor.FieldDescriptorProto_TYPE_STRING,
+		descriptor.FieldDescriptorProto_TYPE_BYTES,
+		descriptor.FieldDescriptorProto_TYPE_MESSAGE,
+		descriptor.FieldDescriptorProto_TYPE_GROUP:
+		return false
+	}
+	return true
+}
+
+// optionsObject renders options as an object literal.
+func optionsObject(options []*optionValue, indent string) string {
+	lines := make([]string, len(options))
+	for i, o := range options {
+		lines[i] = fmt.Sprintf("%s  %q: %s", indent, o.Name, o.Value)
+	}
+	return "{\n" + strings.Join(lines, ",\n") + "\n" + indent + "}"
+}
@@ -0,0 +1,543 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+// parameters holds the options passed to the plugin, for example:
+//
+//	protoc --twirp_ts_out=long=string:./out/ service.proto
+type parameters struct {
+	// LongType is the TypeScript type used for 64-bit integer fields, either
+	// "number" or "string".
+	LongType string
+
+	// EmitDefaults makes toJSON emit zero values for unset fields, like
+	// jsonpb's EmitDefaults.
+	EmitDefaults bool
+
+	// EnumsAsInts makes toJSON emit enum numbers instead of names, like
+	// jsonpb's EnumsAsInts.
+	EnumsAsInts bool
+
+	// EnumStyle is "enum" to generate enums as TypeScript enums, or "const"
+	// for const enums without a runtime object. Their Values and Names maps
+	// are generated in a separate .enums.ts file then, see EnumMaps.
+	EnumStyle string
+
+	// ClassStyle is "accessors" to back the fields of message classes with
+	// their JSON and getters and setters, or "properties" for plain public
+	// properties converted by fromJSON and toJSON.
+	ClassStyle string
+
+	// Immutable deep-freezes the messages built by their classes, which get
+	// with methods returning modified copies instead of setters.
+	Immutable bool
+
+	// Builders generates a builder class per message with chainable setters,
+	// handy to build large messages in tests and scripts.
+	Builders bool
+
+	// Nominal brands the message interfaces with an optional $type property
+	// holding the full name of their message, so messages with the same
+	// fields aren't assignable to each other.
+	Nominal bool
+
+	// Timestamp is the representation of google.protobuf.Timestamp fields,
+	// either "string" (RFC 3339), "date" or "object" ({seconds, nanos}).
+	Timestamp string
+
+	// BrandedInts types 32-bit integer fields as the Int32 and UInt32
+	// branded number types, so they can't be mixed up with other numbers.
+	BrandedInts bool
+
+	// ImportMap maps .proto files to the module specifiers their types are
+	// imported from instead of the generated files, set with M parameters
+	// like protoc-gen-go's, e.g. Mfoo/bar.proto=@scope/pkg/bar.
+	ImportMap map[string]string
+
+	// Paths is the layout of the output files: "package" puts them in
+	// directories named after their package, "source_relative" next to
+	// their .proto file and "flat" all in the output directory.
+	Paths string
+
+	// Mode is "classes" to generate message classes and service clients,
+	// "interfaces" to generate service clients using the message interfaces
+	// without classes, "functional" to generate the message interfaces with
+	// standalone functions converting them and calling the methods, which
+	// bundlers can drop when they're unused, or "declarations" to generate
+	// .d.ts files with only the interfaces and enums.
+	Mode string
+
+	// Module is the module system of the generated code, "esm" imports
+	// relative modules by file name with a .js extension as required by
+	// Node's ES modules, "commonjs" by extensionless paths.
+	Module string
+
+	// Target is the ECMAScript version of the syntax of the generated code:
+	// "esnext" uses object spread and async iterators, "es2017" merges
+	// objects with Object.assign and doesn't support server streaming, and
+	// "es5" merges them with the assign helper of twirp.ts, see objectLiteral.
+	Target string
+
+	// Templates is a directory of templates overriding the built-in ones,
+	// see loadTemplates.
+	Templates string
+
+	// RuntimePackage is the module the twirp.ts runtime is imported from
+	// instead of generating it, e.g. a package published to npm.
+	RuntimePackage string
+
+	// FetchModule is the module the Fetch type is taken from, e.g.
+	// node-fetch, instead of the global fetch. FetchExport is the name of
+	// its fetch export.
+	FetchModule string
+	FetchExport string
+
+	// Lint selects the linters disabled in generated files: "eslint",
+	// "tslint", "both" or "none". TSNoCheck additionally disables type
+	// checking with // @ts-nocheck.
+	Lint      string
+	TSNoCheck bool
+
+	// Format is "on" to normalize the indentation and blank lines of
+	// generated files, see formatTypeScript, or "off".
+	Format string
+
+	// Banner is the template of the comment at the top of generated files,
+	// see banner, "none" omits it.
+	Banner string
+
+	// Services lists the services to generate, all of them if empty.
+	// ExcludeMessages is a regular expression matching the full names of
+	// messages to leave out unless they're referenced by generated types.
+	Services        []string
+	ExcludeMessages string
+
+	// Prune generates only the messages and enums used by the generated
+	// services, directly or through other messages.
+	Prune bool
+
+	// ErrorMetaOptions are the full names of the string service and method
+	// options naming the message that describes the meta of their errors,
+	// see ErrorMeta.
+	ErrorMetaOptions []string
+
+	// BatchOption is the full name of the string method option naming the
+	// batch method that calls of a method can be collected into, see Batch.
+	BatchOption string
+
+	// SubscribeOption is the full name of the method option marking the
+	// methods with a server-sent events endpoint, see subscribePath.
+	SubscribeOption string
+
+	// HeadersOptions are the full names of the service and method options
+	// listing the headers required by their methods, see requiredHeaders.
+	HeadersOptions []string
+
+	// FieldNaming is the naming of the accessors and interface members of
+	// message fields: "camel" (camelCase), "original" (the names in the
+	// .proto file) or "both".
+	FieldNaming string
+
+	// FileSuffix replaces the .proto extension in the names of generated
+	// files, e.g. ".pb.ts" to tell them apart from handwritten files. In
+	// declarations mode its .ts is replaced by .d.ts.
+	FileSuffix string
+
+	// Readonly generates a Readonly<Interface> variant of the interface of
+	// each message, with readonly members and ReadonlyArrays: "none",
+	// "interfaces", or "responses" to type the results of client methods
+	// with them too.
+	Readonly string
+
+	// RequiredFields makes the members of proto3 fields without presence
+	// non-optional in the message interfaces, RequiredOption is the full name
+	// of a bool field option making a field non-optional. Their zero value is
+	// set when they're absent, see fieldValues.Required.
+	RequiredFields bool
+	RequiredOption string
+
+	// GenerateDependencies generates the files imported by the files given
+	// to protoc too, they're usually generated on their own by their owners.
+	GenerateDependencies bool
+
+	// Strict fails the generation on constructs that can't be generated
+	// faithfully instead of logging warnings, see unsupported.
+	Strict bool
+
+	// WebSocket generates a WebSocket transport multiplexing the calls of
+	// clients over a single socket, see WebSocketTransport in twirp.ts.
+	WebSocket bool
+
+	// Mocks generates a mock of each service client with canned responses,
+	// for tests.
+	Mocks bool
+
+	// Fakes generates an in-memory fake of each service, serving the calls
+	// of a client from handlers, for tests.
+	Fakes bool
+
+	// Server generates the interfaces of services implemented in Node and
+	// the handlers serving them. ServerFramework is "node" for HTTP handlers
+	// of the http module and Express, see twirpHandler in twirp.ts, or
+	// "fastify" for Fastify plugins, see fastifyRoutes.
+	Server          bool
+	ServerFramework string
+
+	// Msw generates Mock Service Worker handlers stubbing the methods of
+	// each service, see mswHandlers in twirp.ts.
+	Msw bool
+
+	// Vue generates Vue 3 composables wrapping the methods of clients in
+	// reactive refs, see useTwirpCall in twirp.ts.
+	Vue bool
+
+	// TwirpVersion is the version of the Twirp protocol of the servers, "v7"
+	// or "v5": the HTTP status of resource_exhausted errors, whether error
+	// codes of other versions are kept and whether redirects are followed or
+	// rejected, see throwTwirpError in twirp.ts.
+	TwirpVersion string
+
+	// Edge generates a runtime for edge runtimes like Cloudflare Workers,
+	// leaving out the request options they reject, e.g. credentials.
+	Edge bool
+
+	// BaseURLEnv is where clients created without a baseURL look up the
+	// base URL of their service besides setBaseURL: "none", "process" for
+	// process.env or "import_meta" for import.meta.env, in the variables
+	// named after BaseURLEnvPrefix, see resolveBaseURL in twirp.ts.
+	BaseURLEnv       string
+	BaseURLEnvPrefix string
+
+	// HTTPGet calls the methods without side effects with GET requests,
+	// their message in the query string, see queryURL in twirp.ts.
+	HTTPGet bool
+
+	// Protobuf generates binary codecs for the messages, so clients can send
+	// requests in the protobuf wire format instead of JSON.
+	Protobuf bool
+
+	// IoTs generates io-ts codecs for the messages, decoding their proto3
+	// JSON into the message classes with Either results, see fieldCodec.
+	IoTs bool
+
+	// JSONSchema generates a .schema.json file with the JSON Schema of each
+	// message next to its TypeScript file, see MessageSchema.
+	JSONSchema bool
+
+	// Namespaces mirrors the proto packages and nested types with
+	// namespaces aliasing the generated types, see namespaceValues.
+	Namespaces bool
+
+	// SourceLocations adds a comment with the position in the .proto file
+	// above each generated type and client method, see sourceLocation.
+	SourceLocations bool
+
+	// SizeReport logs the size of each generated file, to keep an eye on
+	// the size of bundles, see reportSizes.
+	SizeReport bool
+}
+
+var params = defaultParameters()
+
+// envVariablePattern matches the names of environment variables.
+var envVariablePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+func defaultParameters() parameters {
+	return parameters{
+		LongType:   "number",
+		Timestamp:  "string",
+		ImportMap:  make(map[string]string),
+		Paths:      "package",
+		Mode:       "classes",
+		EnumStyle:  "enum",
+		ClassStyle: "accessors",
+		Module:     "commonjs",
+		Target:     "esnext",
+
+		FetchExport: "default",
+		Lint:        "eslint",
+		Format:      "on",
+		Banner:      defaultBanner,
+		FieldNaming: "camel",
+		FileSuffix:  ".ts",
+		Readonly:    "none",
+
+		ServerFramework:  "node",
+		TwirpVersion:     "v7",
+		BaseURLEnv:       "none",
+		BaseURLEnvPrefix: "TWIRP_BASE_URL",
+	}
+}
+
+func parseParameters(s string) (parameters, error) {
+	p := defaultParameters()
+
+	var args []keyValue
+	for _, kv := range strings.Split(s, ",") {
+		if kv == "" {
+			continue
+		}
+
+		parts := strings.SplitN(kv, "=", 2)
+		key, value := parts[0], ""
+		if len(parts) > 1 {
+			value = parts[1]
+		}
+		args = append(args, keyValue{key, value})
+	}
+
+	// The config file is applied first, parameters override it.
+	path, explicit := defaultConfigFile, false
+	for _, kv := range args {
+		if kv.Key == "config" {
+			path, explicit = kv.Value, true
+		}
+	}
+	config, err := readConfig(path, explicit)
+	if err != nil {
+		return p, err
+	}
+	for _, kv := range config {
+		if err := p.set(kv.Key, kv.Value); err != nil {
+			return p, fmt.Errorf("%s: %v", path, err)
+		}
+	}
+
+	for _, kv := range args {
+		if err := p.set(kv.Key, kv.Value); err != nil {
+			return p, err
+		}
+	}
+
+	// Declarations describe the JSON as-is, it isn't converted.
+	if p.Mode != "classes" && p.Timestamp != "string" {
+		return p, fmt.Errorf("timestamp=%s requires mode=classes", p.Timestamp)
+	}
+	// The codecs are static members of the message classes.
+	if p.Mode != "classes" && p.Protobuf {
+		return p, fmt.Errorf("protobuf requires mode=classes")
+	}
+
+	// The codecs decode into the message classes.
+	if p.Mode != "classes" && p.IoTs {
+		return p, fmt.Errorf("io_ts requires mode=classes")
+	}
+
+	// Responses are message classes only in classes mode, the JSON is
+	// returned as-is otherwise.
+	if p.Mode != "classes" && p.Readonly == "responses" {
+		return p, fmt.Errorf("readonly=responses requires mode=classes")
+	}
+
+	if p.Mode != "classes" && p.ClassStyle != "accessors" {
+		return p, fmt.Errorf("class_style=%s requires mode=classes", p.ClassStyle)
+	}
+	if p.Mode != "classes" && p.Immutable {
+		return p, fmt.Errorf("immutable requires mode=classes")
+	}
+	if p.Mode != "classes" && p.Builders {
+		return p, fmt.Errorf("builders requires mode=classes")
+	}
+
+	// The zero values of required fields are set by the message classes.
+	if p.Mode != "classes" && (p.RequiredFields || p.RequiredOption != "") {
+		return p, fmt.Errorf("required_fields and required_option require mode=classes")
+	}
+
+	// Batches, GET requests, subscriptions and WebSocket transports are
+	// methods of the client classes.
+	if p.Mode == "functional" {
+		switch {
+		case p.HTTPGet:
+			return p, fmt.Errorf("http_get isn't supported with mode=functional")
+		case p.WebSocket:
+			return p, fmt.Errorf("websocket isn't supported with mode=functional")
+		case p.BatchOption != "":
+			return p, fmt.Errorf("batch_option isn't supported with mode=functional")
+		case p.SubscribeOption != "":
+			return p, fmt.Errorf("subscribe_option isn't supported with mode=functional")
+		}
+	}
+
+	// Subscriptions return async iterators, which are ES2018.
+	if p.Target != "esnext" && p.SubscribeOption != "" {
+		return p, fmt.Errorf("subscribe_option requires target=esnext")
+	}
+
+	if p.ServerFramework != "node" && !p.Server {
+		return p, fmt.Errorf("server_framework=%s requires server=true", p.ServerFramework)
+	}
+
+	return p, nil
+}
+
+type keyValue struct {
+	Key   string
+	Value string
+}
+
+func (p *parameters) set(key, value string) error {
+	switch key {
+	case "config":
+		// Read by parseParameters.
+	case "long":
+		return parseEnum(key, value, &p.LongType, "number", "string")
+	case "timestamp":
+		return parseEnum(key, value, &p.Timestamp, "string", "date", "object")
+	case "emit_defaults":
+		return parseBool(key, value, &p.EmitDefaults)
+	case "enums_as_ints":
+		return parseBool(key, value, &p.EnumsAsInts)
+	case "branded_ints":
+		return parseBool(key, value, &p.BrandedInts)
+	case "paths":
+		return parseEnum(key, value, &p.Paths, "package", "source_relative", "flat")
+	case "mode":
+		return parseEnum(key, value, &p.Mode, "classes", "interfaces", "functional", "declarations")
+	case "enum_style":
+		return parseEnum(key, value, &p.EnumStyle, "enum", "const")
+	case "class_style":
+		return parseEnum(key, value, &p.ClassStyle, "accessors", "properties")
+	case "immutable":
+		return parseBool(key, value, &p.Immutable)
+	case "builders":
+		return parseBool(key, value, &p.Builders)
+	case "nominal":
+		return parseBool(key, value, &p.Nominal)
+	case "module":
+		return parseEnum(key, value, &p.Module, "commonjs", "esm")
+	case "target":
+		return parseEnum(key, value, &p.Target, "esnext", "es2017", "es5")
+	case "templates":
+		p.Templates = value
+	case "runtime_package":
+		p.RuntimePackage = value
+	case "fetch_module":
+		p.FetchModule = value
+	case "fetch_export":
+		p.FetchExport = value
+	case "lint":
+		return parseEnum(key, value, &p.Lint, "eslint", "tslint", "both", "none")
+	case "ts_nocheck":
+		return parseBool(key, value, &p.TSNoCheck)
+	case "format":
+		return parseEnum(key, value, &p.Format, "on", "off")
+	case "services":
+		p.Services = append(p.Services, value)
+	case "exclude_messages":
+		if _, err := regexp.Compile(value); err != nil {
+			return fmt.Errorf("invalid value for parameter %s: %v", key, err)
+		}
+		p.ExcludeMessages = value
+	case "error_meta_option":
+		p.ErrorMetaOptions = append(p.ErrorMetaOptions, value)
+	case "batch_option":
+		p.BatchOption = value
+	case "subscribe_option":
+		p.SubscribeOption = value
+	case "headers_option":
+		p.HeadersOptions = append(p.HeadersOptions, value)
+	case "field_naming":
+		return parseEnum(key, value, &p.FieldNaming, "camel", "original", "both")
+	case "file_suffix":
+		if !strings.HasSuffix(value, ".ts") {
+			return fmt.Errorf("invalid value %q for parameter %s, expected a suffix ending in .ts", value, key)
+		}
+		p.FileSuffix = value
+	case "readonly":
+		return parseEnum(key, value, &p.Readonly, "none", "interfaces", "responses")
+	case "required_fields":
+		return parseBool(key, value, &p.RequiredFields)
+	case "required_option":
+		p.RequiredOption = value
+	case "generate_dependencies":
+		return parseBool(key, value, &p.GenerateDependencies)
+	case "strict":
+		return parseBool(key, value, &p.Strict)
+	case "protobuf":
+		return parseBool(key, value, &p.Protobuf)
+	case "io_ts":
+		return parseBool(key, value, &p.IoTs)
+	case "json_schema":
+		return parseBool(key, value, &p.JSONSchema)
+	case "namespaces":
+		return parseBool(key, value, &p.Namespaces)
+	case "source_locations":
+		return parseBool(key, value, &p.SourceLocations)
+	case "size_report":
+		return parseBool(key, value, &p.SizeReport)
+	case "websocket":
+		return parseBool(key, value, &p.WebSocket)
+	case "mocks":
+		return parseBool(key, value, &p.Mocks)
+	case "fakes":
+		return parseBool(key, value, &p.Fakes)
+	case "msw":
+		return parseBool(key, value, &p.Msw)
+	case "vue":
+		return parseBool(key, value, &p.Vue)
+	case "twirp_version":
+		return parseEnum(key, value, &p.TwirpVersion, "v7", "v5")
+	case "edge":
+		return parseBool(key, value, &p.Edge)
+	case "base_url_env":
+		return parseEnum(key, value, &p.BaseURLEnv, "none", "process", "import_meta")
+	case "base_url_env_prefix":
+		if !envVariablePattern.MatchString(value) {
+			return fmt.Errorf("invalid value %q for parameter %s, expected an environment variable name", value, key)
+		}
+		p.BaseURLEnvPrefix = value
+	case "http_get":
+		return parseBool(key, value, &p.HTTPGet)
+	case "server":
+		return parseBool(key, value, &p.Server)
+	case "server_framework":
+		return parseEnum(key, value, &p.ServerFramework, "node", "fastify")
+	case "prune":
+		return parseBool(key, value, &p.Prune)
+	case "banner":
+		if _, err := template.New(key).Parse(value); err != nil {
+			return fmt.Errorf("invalid value for parameter %s: %v", key, err)
+		}
+		p.Banner = value
+	default:
+		if strings.HasPrefix(key, "M") && len(key) > 1 {
+			if value == "" {
+				return fmt.Errorf("missing import path for parameter %s", key)
+			}
+			p.ImportMap[key[1:]] = value
+			return nil
+		}
+		return fmt.Errorf("unknown parameter %q", key)
+	}
+	return nil
+}
+
+func parseBool(key, value string, dst *bool) error {
+	if value == "" {
+		*dst = true
+		return nil
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return fmt.Errorf("invalid value %q for parameter %s, expected true or false", value, key)
+	}
+	*dst = b
+	return nil
+}
+
+func parseEnum(key, value string, dst *string, allowed ...string) error {
+	for _, a := range allowed {
+		if value == a {
+			*dst = value
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid value %q for parameter %s, expected %s", value, key, strings.Join(allowed, " or "))
+}
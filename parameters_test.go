@@ -0,0 +1,80 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseParameters(t *testing.T) {
+	tests := []struct {
+		parameter string
+		// want changes the default parameters to the expected ones.
+		want func(p *parameters)
+	}{
+		{"", func(p *parameters) {}},
+		{"long=string", func(p *parameters) { p.LongType = "string" }},
+		{"emit_defaults", func(p *parameters) { p.EmitDefaults = true }},
+		{"long=string,timestamp=date", func(p *parameters) { p.LongType, p.Timestamp = "string", "date" }},
+		{"config=testdata/twirp-ts.yaml,long=number", func(p *parameters) {
+			p.EmitDefaults, p.ImportMap["foo/bar.proto"] = true, "@acme/bar"
+		}},
+		{"Mfoo/bar.proto=@acme/bar", func(p *parameters) { p.ImportMap["foo/bar.proto"] = "@acme/bar" }},
+		{"services=Users,services=acme.Billing", func(p *parameters) { p.Services = []string{"Users", "acme.Billing"} }},
+		{"file_suffix=.pb.ts", func(p *parameters) { p.FileSuffix = ".pb.ts" }},
+		{"protobuf", func(p *parameters) { p.Protobuf = true }},
+		{"protobuf=false", func(p *parameters) {}},
+		{"server=true,server_framework=fastify", func(p *parameters) { p.Server, p.ServerFramework = true, "fastify" }},
+		{"mode=functional", func(p *parameters) { p.Mode = "functional" }},
+		{"target=es5", func(p *parameters) { p.Target = "es5" }},
+		{"class_style=properties,immutable,builders", func(p *parameters) {
+			p.ClassStyle, p.Immutable, p.Builders = "properties", true, true
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.parameter, func(t *testing.T) {
+			got, err := parseParameters(tt.parameter)
+			if err != nil {
+				t.Fatalf("parseParameters(%q): %v", tt.parameter, err)
+			}
+			want := defaultParameters()
+			tt.want(&want)
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("parseParameters(%q) =\n%+v\nwant:\n%+v", tt.parameter, got, want)
+			}
+		})
+	}
+}
+
+func TestParseParametersErrors(t *testing.T) {
+	tests := []struct {
+		parameter string
+		want      string
+	}{
+		{"nope=1", `unknown parameter "nope"`},
+		{"long=int", `invalid value "int" for parameter long, expected number or string`},
+		{"timestamp=unix", `invalid value "unix" for parameter timestamp, expected string or date or object`},
+		{"config=testdata/missing.yaml", "open testdata/missing.yaml: no such file or directory"},
+		{"Mfoo.proto", "missing import path for parameter Mfoo.proto"},
+		{"mode=objects", `invalid value "objects" for parameter mode, expected classes or interfaces or functional or declarations`},
+		{"mode=interfaces,timestamp=date", "timestamp=date requires mode=classes"},
+		{"exclude_messages=(", "invalid value for parameter exclude_messages: error parsing regexp: missing closing ): `(`"},
+		{"file_suffix=.js", `invalid value ".js" for parameter file_suffix, expected a suffix ending in .ts`},
+		{"protobuf=yes", `invalid value "yes" for parameter protobuf, expected true or false`},
+		{"mode=interfaces,protobuf", "protobuf requires mode=classes"},
+		{"server_framework=fastify", "server_framework=fastify requires server=true"},
+		{"base_url_env_prefix=1X", `invalid value "1X" for parameter base_url_env_prefix, expected an environment variable name`},
+		{"mode=functional,http_get", "http_get isn't supported with mode=functional"},
+		{"target=es2017,subscribe_option=acme.subscribe", "subscribe_option requires target=esnext"},
+		{"mode=functional,builders", "builders requires mode=classes"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.parameter, func(t *testing.T) {
+			_, err := parseParameters(tt.parameter)
+			if err == nil || err.Error() != tt.want {
+				t.Errorf("parseParameters(%q) error = %v, want %q", tt.parameter, err, tt.want)
+			}
+		})
+	}
+}
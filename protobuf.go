@@ -0,0 +1,789 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+)
+
+// protobufTemplate is the part of twirp.ts encoding messages in the protobuf
+// wire format, generated with the protobuf parameter. Messages are encoded
+// from and decoded to their JSON representation, so the message classes work
+// the same with both formats.
+var protobufTemplate = `
+// ContentType selects the encoding of requests, JSON or the protobuf wire
+// format. Protobuf requires clients generated with protobuf=true.
+export type ContentType = "application/json" | "application/protobuf";
+
+// MessageCodec encodes and decodes messages in the protobuf wire format from
+// and to their JSON representation.
+export interface MessageCodec {
+  encode(json: any, w: ProtobufWriter): void;
+  decode(r: ProtobufReader, end: number): any;
+}
+
+// FieldSchema describes a field of a message: its number, its JSON name and
+// its type, a google.protobuf.FieldDescriptorProto.Type. Maps have the type
+// of their key and the schema of their value.
+export interface FieldSchema {
+  no: number;
+  name: string;
+  type: number;
+  repeated?: boolean;
+  packed?: boolean;
+  enum?: () => { [name: string]: number };
+  message?: () => MessageCodec;
+  key?: number;
+  value?: FieldSchema;
+}
+
+const enum FieldType {
+  Double = 1,
+  Float = 2,
+  Int64 = 3,
+  UInt64 = 4,
+  Int32 = 5,
+  Fixed64 = 6,
+  Fixed32 = 7,
+  Bool = 8,
+  String = 9,
+  Group = 10,
+  Message = 11,
+  Bytes = 12,
+  UInt32 = 13,
+  Enum = 14,
+  SFixed32 = 15,
+  SFixed64 = 16,
+  SInt32 = 17,
+  SInt64 = 18
+}
+
+const wireType = (type: number): number => {
+  switch (type) {
+    case FieldType.Double:
+    case FieldType.Fixed64:
+    case FieldType.SFixed64:
+      return 1;
+    case FieldType.String:
+    case FieldType.Bytes:
+    case FieldType.Message:
+      return 2;
+    case FieldType.Float:
+    case FieldType.Fixed32:
+    case FieldType.SFixed32:
+      return 5;
+  }
+  return 0;
+};
+
+const base64Decode = (s: string): Uint8Array => {
+  s = s.replace(/-/g, "+").replace(/_/g, "/").replace(/[=\s]/g, "");
+  const b = new Uint8Array(Math.floor((s.length * 3) / 4));
+  let n = 0;
+  let bits = 0;
+  let j = 0;
+  for (let i = 0; i < s.length; i++) {
+    n = (n << 6) | base64Chars.indexOf(s[i]);
+    bits += 6;
+    if (bits >= 8) {
+      bits -= 8;
+      b[j++] = (n >> bits) & 255;
+    }
+  }
+  return b;
+};
+
+// splitLong splits a 64-bit integer, a number or a decimal string, into its
+// low and high 32 bits in two's complement.
+const splitLong = (v: number | string): [number, number] => {
+  let s = String(v).trim();
+  const negative = s[0] === "-";
+  if (negative) {
+    s = s.slice(1);
+  }
+  let lo = 0;
+  let hi = 0;
+  for (let i = 0; i < s.length; i++) {
+    lo = lo * 10 + Number(s[i]);
+    const carry = Math.floor(lo / 4294967296);
+    lo -= carry * 4294967296;
+    hi = (hi * 10 + carry) >>> 0;
+  }
+  if (negative) {
+    lo = (~lo + 1) >>> 0;
+    hi = (~hi + (lo === 0 ? 1 : 0)) >>> 0;
+  }
+  return [lo, hi];
+};
+
+// joinLong formats the 64-bit integer with the low and high 32 bits lo and hi
+// as a decimal string.
+const joinLong = (lo: number, hi: number, signed: boolean): string => {
+  if (signed && hi & 0x80000000) {
+    lo = (~lo + 1) >>> 0;
+    hi = (~hi + (lo === 0 ? 1 : 0)) >>> 0;
+    return "-" + joinLong(lo, hi, false);
+  }
+  if (hi <= 0x1fffff) {
+    return String(hi * 4294967296 + lo);
+  }
+  // Base 1e7 digits, see joinUnsignedDecimalString in google-protobuf.
+  const low = lo & 0xffffff;
+  const mid = (((lo >>> 24) | (hi << 8)) >>> 0) & 0xffffff;
+  const high = (hi >> 16) & 0xffff;
+  let a = low + mid * 6777216 + high * 6710656;
+  let b = mid + high * 8147497;
+  let c = high * 2;
+  if (a >= 10000000) {
+    b += Math.floor(a / 10000000);
+    a %= 10000000;
+  }
+  if (b >= 10000000) {
+    c += Math.floor(b / 10000000);
+    b %= 10000000;
+  }
+  const pad = (d: number) => ("0000000" + d).slice(-7);
+  return c ? String(c) + pad(b) + pad(a) : String(b) + pad(a);
+};
+
+// longJSON is the JSON representation of 64-bit integers.
+const longJSON = (s: string): number | string => {
+  return {{if eq .LongType "string"}}s{{else}}Number(s){{end}};
+};
+
+// ProtobufWriter encodes values in the protobuf wire format.
+export class ProtobufWriter {
+  private buf: number[] = [];
+
+  public tag(no: number, wireType: number) {
+    this.uint32(((no << 3) | wireType) >>> 0);
+  }
+
+  public uint32(v: number) {
+    v >>>= 0;
+    while (v > 127) {
+      this.buf.push((v & 127) | 128);
+      v >>>= 7;
+    }
+    this.buf.push(v);
+  }
+
+  public int32(v: number) {
+    if (v < 0) {
+      // Negative numbers are sign extended to 64 bits.
+      this.varint64(v >>> 0, 0xffffffff);
+    } else {
+      this.uint32(v);
+    }
+  }
+
+  public varint64(lo: number, hi: number) {
+    while (hi > 0 || lo > 127) {
+      this.buf.push((lo & 127) | 128);
+      lo = ((lo >>> 7) | (hi << 25)) >>> 0;
+      hi >>>= 7;
+    }
+    this.buf.push(lo);
+  }
+
+  public fixed32(v: number) {
+    this.buf.push(v & 255, (v >>> 8) & 255, (v >>> 16) & 255, (v >>> 24) & 255);
+  }
+
+  public double(v: number) {
+    const b = new DataView(new ArrayBuffer(8));
+    b.setFloat64(0, v, true);
+    for (let i = 0; i < 8; i++) {
+      this.buf.push(b.getUint8(i));
+    }
+  }
+
+  public float(v: number) {
+    const b = new DataView(new ArrayBuffer(4));
+    b.setFloat32(0, v, true);
+    for (let i = 0; i < 4; i++) {
+      this.buf.push(b.getUint8(i));
+    }
+  }
+
+  public bytes(b: Uint8Array) {
+    this.uint32(b.length);
+    for (let i = 0; i < b.length; i++) {
+      this.buf.push(b[i]);
+    }
+  }
+
+  public string(s: string) {
+    this.bytes(new TextEncoder().encode(s));
+  }
+
+  public message(codec: MessageCodec, json: any) {
+    const w = new ProtobufWriter();
+    codec.encode(json, w);
+    this.bytes(w.finish());
+  }
+
+  public finish(): Uint8Array {
+    return new Uint8Array(this.buf);
+  }
+}
+
+// ProtobufReader decodes values in the protobuf wire format.
+export class ProtobufReader {
+  public pos = 0;
+
+  constructor(public buf: Uint8Array) {}
+
+  private byte(): number {
+    if (this.pos >= this.buf.length) {
+      throw new Error("protobuf: unexpected end of message");
+    }
+    return this.buf[this.pos++];
+  }
+
+  public varint64(): [number, number] {
+    let lo = 0;
+    let hi = 0;
+    for (let shift = 0; shift < 70; shift += 7) {
+      const b = this.byte();
+      if (shift < 28) {
+        lo |= (b & 127) << shift;
+      } else if (shift === 28) {
+        lo |= (b & 15) << 28;
+        hi |= (b & 127) >> 4;
+      } else {
+        hi |= (b & 127) << (shift - 32);
+      }
+      if (b < 128) {
+        return [lo >>> 0, hi >>> 0];
+      }
+    }
+    throw new Error("protobuf: invalid varint");
+  }
+
+  public uint32(): number {
+    return this.varint64()[0];
+  }
+
+  public fixed32(): number {
+    const b = this.buf;
+    const p = (this.pos += 4);
+    if (p > b.length) {
+      throw new Error("protobuf: unexpected end of message");
+    }
+    return (b[p - 4] | (b[p - 3] << 8) | (b[p - 2] << 16) | (b[p - 1] << 24)) >>> 0;
+  }
+
+  public double(): number {
+    const v = new DataView(this.buf.buffer, this.buf.byteOffset + this.pos, 8).getFloat64(0, true);
+    this.pos += 8;
+    return v;
+  }
+
+  public float(): number {
+    const v = new DataView(this.buf.buffer, this.buf.byteOffset + this.pos, 4).getFloat32(0, true);
+    this.pos += 4;
+    return v;
+  }
+
+  public bytes(): Uint8Array {
+    const n = this.uint32();
+    if (this.pos + n > this.buf.length) {
+      throw new Error("protobuf: unexpected end of message");
+    }
+    this.pos += n;
+    return this.buf.subarray(this.pos - n, this.pos);
+  }
+
+  public string(): string {
+    return new TextDecoder().decode(this.bytes());
+  }
+
+  public message(codec: MessageCodec): any {
+    const end = this.uint32() + this.pos;
+    const json = codec.decode(this, end);
+    this.pos = end;
+    return json;
+  }
+
+  public skip(wireType: number) {
+    switch (wireType) {
+      case 0:
+        this.varint64();
+        break;
+      case 1:
+        this.pos += 8;
+        break;
+      case 2:
+        this.bytes();
+        break;
+      case 3:
+        for (;;) {
+          const tag = this.uint32();
+          if ((tag & 7) === 4) {
+            break;
+          }
+          this.skip(tag & 7);
+        }
+        break;
+      case 5:
+        this.pos += 4;
+        break;
+      default:
+        throw new Error("protobuf: invalid wire type " + wireType);
+    }
+  }
+}
+
+const toJSONValue = (v: any): any => {
+  return v && typeof v.toJSON === "function" ? v.toJSON() : v;
+};
+
+// writeField writes a single value of f with its tag, groups are delimited by
+// start and end tags.
+const writeField = (w: ProtobufWriter, f: FieldSchema, v: any) => {
+  if (f.type === FieldType.Group) {
+    w.tag(f.no, 3);
+    f.message!().encode(toJSONValue(v), w);
+    w.tag(f.no, 4);
+    return;
+  }
+  w.tag(f.no, wireType(f.type));
+  writeValue(w, f, v);
+};
+
+// writeValue writes a single value of f, without its tag.
+const writeValue = (w: ProtobufWriter, f: FieldSchema, v: any) => {
+  switch (f.type) {
+    case FieldType.Double:
+      w.double(Number(v));
+      break;
+    case FieldType.Float:
+      w.float(Number(v));
+      break;
+    case FieldType.Int64:
+    case FieldType.UInt64: {
+      const [lo, hi] = splitLong(v);
+      w.varint64(lo, hi);
+      break;
+    }
+    case FieldType.SInt64: {
+      const [lo, hi] = splitLong(v);
+      const sign = hi >> 31;
+      w.varint64(((lo << 1) ^ sign) >>> 0, ((hi << 1) | (lo >>> 31)) ^ sign);
+      break;
+    }
+    case FieldType.Fixed64:
+    case FieldType.SFixed64: {
+      const [lo, hi] = splitLong(v);
+      w.fixed32(lo);
+      w.fixed32(hi);
+      break;
+    }
+    case FieldType.Int32:
+      w.int32(Number(v));
+      break;
+    case FieldType.UInt32:
+      w.uint32(Number(v));
+      break;
+    case FieldType.SInt32: {
+      const n = Number(v);
+      w.uint32((n << 1) ^ (n >> 31));
+      break;
+    }
+    case FieldType.Fixed32:
+    case FieldType.SFixed32:
+      w.fixed32(Number(v));
+      break;
+    case FieldType.Bool:
+      w.uint32(v === true || v === "true" ? 1 : 0);
+      break;
+    case FieldType.String:
+      w.string(String(v));
+      break;
+    case FieldType.Bytes:
+      w.bytes(base64Decode(String(v)));
+      break;
+    case FieldType.Enum:
+      w.int32(typeof v === "number" ? v : f.enum!()[v] || 0);
+      break;
+    case FieldType.Message:
+      w.message(f.message!(), toJSONValue(v));
+      break;
+    default:
+      throw new Error("protobuf: unsupported type of field " + f.name);
+  }
+};
+
+// readValue reads a single value of f, without its tag.
+const readValue = (r: ProtobufReader, f: FieldSchema): any => {
+  switch (f.type) {
+    case FieldType.Double:
+      return r.double();
+    case FieldType.Float:
+      return r.float();
+    case FieldType.Int64:
+    case FieldType.UInt64: {
+      const [lo, hi] = r.varint64();
+      return longJSON(joinLong(lo, hi, f.type === FieldType.Int64));
+    }
+    case FieldType.SInt64: {
+      const [lo, hi] = r.varint64();
+      const sign = -(lo & 1);
+      return longJSON(joinLong((((lo >>> 1) | (hi << 31)) ^ sign) >>> 0, ((hi >>> 1) ^ sign) >>> 0, true));
+    }
+    case FieldType.Fixed64:
+    case FieldType.SFixed64: {
+      const lo = r.fixed32();
+      return longJSON(joinLong(lo, r.fixed32(), f.type === FieldType.SFixed64));
+    }
+    case FieldType.Int32:
+      return r.uint32() | 0;
+    case FieldType.UInt32:
+      return r.uint32();
+    case FieldType.SInt32: {
+      const n = r.uint32();
+      return (n >>> 1) ^ -(n & 1);
+    }
+    case FieldType.Fixed32:
+      return r.fixed32();
+    case FieldType.SFixed32:
+      return r.fixed32() | 0;
+    case FieldType.Bool: {
+      const [lo, hi] = r.varint64();
+      return lo !== 0 || hi !== 0;
+    }
+    case FieldType.String:
+      return r.string();
+    case FieldType.Bytes:
+      return base64Encode(r.bytes());
+    case FieldType.Enum: {
+      const n = r.uint32() | 0;
+      const values = f.enum!();
+      for (const name of Object.keys(values)) {
+        if (values[name] === n) {
+          return name;
+        }
+      }
+      return n;
+    }
+    case FieldType.Message:
+      return r.message(f.message!());
+    case FieldType.Group:
+      // Decoded up to its end tag.
+      return f.message!().decode(r, r.buf.length);
+  }
+  throw new Error("protobuf: unsupported type of field " + f.name);
+};
+
+// zeroValue is the value of map entries without a value.
+const zeroValue = (f: FieldSchema): any => {
+  switch (f.type) {
+    case FieldType.String:
+    case FieldType.Bytes:
+      return "";
+    case FieldType.Bool:
+      return false;
+    case FieldType.Enum:
+      return Object.keys(f.enum!())[0];
+    case FieldType.Message:
+      return f.message!().decode(new ProtobufReader(new Uint8Array(0)), 0);
+  }
+  return 0;
+};
+
+const isPackable = (type: number): boolean => {
+  return type !== FieldType.String && type !== FieldType.Bytes && type !== FieldType.Message && type !== FieldType.Group;
+};
+
+// messageCodec returns the codec of a message with the fields of schema.
+export const messageCodec = (schema: FieldSchema[]): MessageCodec => {
+  const fields: { [no: number]: FieldSchema } = {};
+  schema.forEach(f => {
+    fields[f.no] = f;
+  });
+
+  return {
+    encode(json: any, w: ProtobufWriter) {
+      schema.forEach(f => {
+        const v = json[f.name];
+        if (v == null) {
+          return;
+        }
+        if (f.key) {
+          const key: FieldSchema = { no: 1, name: "key", type: f.key };
+          Object.keys(v).forEach(k => {
+            const entry = new ProtobufWriter();
+            entry.tag(1, wireType(f.key!));
+            writeValue(entry, key, k);
+            if (v[k] != null) {
+              entry.tag(2, wireType(f.value!.type));
+              writeValue(entry, f.value!, v[k]);
+            }
+            w.tag(f.no, 2);
+            w.bytes(entry.finish());
+          });
+        } else if (f.repeated && f.packed) {
+          const packed = new ProtobufWriter();
+          (<any[]>v).forEach(e => writeValue(packed, f, e));
+          w.tag(f.no, 2);
+          w.bytes(packed.finish());
+        } else if (f.repeated) {
+          (<any[]>v).forEach(e => writeField(w, f, e));
+        } else {
+          writeField(w, f, v);
+        }
+      });
+    },
+
+    decode(r: ProtobufReader, end: number): any {
+      const json: any = {};
+      while (r.pos < end) {
+        const tag = r.uint32();
+        if ((tag & 7) === 4) {
+          // The end of a group.
+          break;
+        }
+        const f = fields[tag >>> 3];
+        if (!f) {
+          r.skip(tag & 7);
+          continue;
+        }
+        if (f.key) {
+          const key: FieldSchema = { no: 1, name: "key", type: f.key };
+          const entryEnd = r.uint32() + r.pos;
+          let k: any = zeroValue(key);
+          let v: any = undefined;
+          while (r.pos < entryEnd) {
+            const entryTag = r.uint32();
+            if (entryTag >>> 3 === 1) {
+              k = readValue(r, key);
+            } else if (entryTag >>> 3 === 2) {
+              v = readValue(r, f.value!);
+            } else {
+              r.skip(entryTag & 7);
+            }
+          }
+          json[f.name] = json[f.name] || {};
+          json[f.name][String(k)] = v === undefined ? zeroValue(f.value!) : v;
+        } else if (f.repeated) {
+          const values: any[] = (json[f.name] = json[f.name] || []);
+          if ((tag & 7) === 2 && isPackable(f.type)) {
+            const packedEnd = r.uint32() + r.pos;
+            while (r.pos < packedEnd) {
+              values.push(readValue(r, f));
+            }
+          } else {
+            values.push(readValue(r, f));
+          }
+        } else {
+          json[f.name] = readValue(r, f);
+        }
+      }
+      return json;
+    }
+  };
+};
+
+// mappedCodec converts the JSON of a message before encoding it with codec
+// and after decoding it, for well-known types with a special JSON form.
+const mappedCodec = (codec: MessageCodec, toMessage: (json: any) => any, fromMessage: (json: any) => any): MessageCodec => {
+  return {
+    encode(json: any, w: ProtobufWriter) {
+      codec.encode(toMessage(json), w);
+    },
+    decode(r: ProtobufReader, end: number): any {
+      return fromMessage(codec.decode(r, end));
+    }
+  };
+};
+
+const wrapperCodec = (type: number): MessageCodec => {
+  const value: FieldSchema = { no: 1, name: "value", type };
+  return mappedCodec(
+    messageCodec([value]),
+    json => ({ value: json }),
+    m => (m.value === undefined ? zeroValue(value) : m.value)
+  );
+};
+
+const valueCodec: MessageCodec = {
+  encode(json: any, w: ProtobufWriter) {
+    if (json === null) {
+      w.tag(1, 0);
+      w.uint32(0);
+    } else if (typeof json === "number") {
+      w.tag(2, 1);
+      w.double(json);
+    } else if (typeof json === "string") {
+      w.tag(3, 2);
+      w.string(json);
+    } else if (typeof json === "boolean") {
+      w.tag(4, 0);
+      w.uint32(json ? 1 : 0);
+    } else if (Array.isArray(json)) {
+      w.tag(6, 2);
+      w.message(listValueCodec, json);
+    } else {
+      w.tag(5, 2);
+      w.message(structCodec, json);
+    }
+  },
+  decode(r: ProtobufReader, end: number): any {
+    let json: any = null;
+    while (r.pos < end) {
+      const tag = r.uint32();
+      switch (tag >>> 3) {
+        case 1:
+          r.uint32();
+          json = null;
+          break;
+        case 2:
+          json = r.double();
+          break;
+        case 3:
+          json = r.string();
+          break;
+        case 4:
+          json = r.uint32() !== 0;
+          break;
+        case 5:
+          json = r.message(structCodec);
+          break;
+        case 6:
+          json = r.message(listValueCodec);
+          break;
+        default:
+          r.skip(tag & 7);
+      }
+    }
+    return json;
+  }
+};
+
+const structCodec: MessageCodec = mappedCodec(
+  messageCodec([
+    {
+      no: 1,
+      name: "fields",
+      type: FieldType.Message,
+      key: FieldType.String,
+      value: { no: 2, name: "value", type: FieldType.Message, message: () => valueCodec }
+    }
+  ]),
+  json => ({ fields: json }),
+  m => m.fields || {}
+);
+
+const listValueCodec: MessageCodec = mappedCodec(
+  messageCodec([{ no: 1, name: "values", type: FieldType.Message, repeated: true, message: () => valueCodec }]),
+  json => ({ values: json }),
+  m => m.values || []
+);
+
+const timestampCodec: MessageCodec = mappedCodec(
+  messageCodec([
+    { no: 1, name: "seconds", type: FieldType.Int64 },
+    { no: 2, name: "nanos", type: FieldType.Int32 }
+  ]),
+  json => parseTimestamp(json),
+  m => formatTimestamp({ seconds: Number(m.seconds || 0), nanos: m.nanos || 0 })
+);
+
+// Field masks are encoded with snake_case paths, their JSON has camelCase
+// paths.
+const fieldMaskCodec: MessageCodec = mappedCodec(
+  messageCodec([{ no: 1, name: "paths", type: FieldType.String, repeated: true }]),
+  json => ({
+    paths: String(json)
+      .split(",")
+      .filter(p => p)
+      .map(p => p.replace(/[A-Z]/g, c => "_" + c.toLowerCase()))
+  }),
+  m => (m.paths || []).map((p: string) => p.replace(/_([a-z])/g, (_: string, c: string) => c.toUpperCase())).join(",")
+);
+
+// wellKnownCodecs are the codecs of the well-known types that aren't
+// generated, by full name.
+export const wellKnownCodecs: { [name: string]: MessageCodec } = {
+  "google.protobuf.Timestamp": timestampCodec,
+  "google.protobuf.Struct": structCodec,
+  "google.protobuf.Value": valueCodec,
+  "google.protobuf.ListValue": listValueCodec,
+  "google.protobuf.Empty": messageCodec([]),
+  "google.protobuf.FieldMask": fieldMaskCodec,
+  "google.protobuf.DoubleValue": wrapperCodec(FieldType.Double),
+  "google.protobuf.FloatValue": wrapperCodec(FieldType.Float),
+  "google.protobuf.Int64Value": wrapperCodec(FieldType.Int64),
+  "google.protobuf.UInt64Value": wrapperCodec(FieldType.UInt64),
+  "google.protobuf.Int32Value": wrapperCodec(FieldType.Int32),
+  "google.protobuf.UInt32Value": wrapperCodec(FieldType.UInt32),
+  "google.protobuf.BoolValue": wrapperCodec(FieldType.Bool),
+  "google.protobuf.StringValue": wrapperCodec(FieldType.String),
+  "google.protobuf.BytesValue": wrapperCodec(FieldType.Bytes),
+  "google.type.Date": messageCodec([
+    { no: 1, name: "year", type: FieldType.Int32 },
+    { no: 2, name: "month", type: FieldType.Int32 },
+    { no: 3, name: "day", type: FieldType.Int32 }
+  ]),
+  "google.type.TimeOfDay": messageCodec([
+    { no: 1, name: "hours", type: FieldType.Int32 },
+    { no: 2, name: "minutes", type: FieldType.Int32 },
+    { no: 3, name: "seconds", type: FieldType.Int32 },
+    { no: 4, name: "nanos", type: FieldType.Int32 }
+  ]),
+  "google.type.LatLng": messageCodec([
+    { no: 1, name: "latitude", type: FieldType.Double },
+    { no: 2, name: "longitude", type: FieldType.Double }
+  ]),
+  "google.type.Money": messageCodec([
+    { no: 1, name: "currency_code", type: FieldType.String },
+    { no: 2, name: "units", type: FieldType.Int64 },
+    { no: 3, name: "nanos", type: FieldType.Int32 }
+  ])
+};
+
+// encodeMessage encodes a message, or its JSON, with codec.
+export const encodeMessage = (codec: MessageCodec, message: any): Uint8Array => {
+  const w = new ProtobufWriter();
+  codec.encode(toJSONValue(message) || {}, w);
+  return w.finish();
+};
+
+// decodeMessage decodes the JSON of a message with codec.
+export const decodeMessage = (codec: MessageCodec, b: Uint8Array): any => {
+  return codec.decode(new ProtobufReader(b), b.length);
+};
+`
+
+// fieldSchema returns the FieldSchema of a field for the protobuf codecs,
+// see protobufTemplate.
+func fieldSchema(f *fieldValues) string {
+	if f.IsMap {
+		return fmt.Sprintf("{ no: %d, name: %q, type: %d, key: %d, value: %s }",
+			f.Number, f.Name, descriptor.FieldDescriptorProto_TYPE_MESSAGE, f.MapKey.ProtoType, fieldSchema(f.MapValue))
+	}
+
+	props := []string{
+		fmt.Sprintf("no: %d", f.Number),
+		fmt.Sprintf("name: %q", f.Name),
+		fmt.Sprintf("type: %d", f.ProtoType),
+	}
+	if f.IsRepeated {
+		props = append(props, "repeated: true")
+	}
+	if f.IsPacked {
+		props = append(props, "packed: true")
+	}
+	switch {
+	case f.IsEnum:
+		props = append(props, fmt.Sprintf("enum: () => %sValues", f.Type))
+	case f.IsTimestamp, f.IsPlainJSON:
+		props = append(props, fmt.Sprintf("message: () => wellKnownCodecs[%q]", strings.TrimPrefix(f.TypeName, ".")))
+	case f.ProtoType == descriptor.FieldDescriptorProto_TYPE_MESSAGE,
+		f.ProtoType == descriptor.FieldDescriptorProto_TYPE_GROUP:
+		props = append(props, fmt.Sprintf("message: () => %s.protobuf", f.Type))
+	}
+	return "{ " + strings.Join(props, ", ") + " }"
+}
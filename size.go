@@ -0,0 +1,24 @@
+package main
+
+import (
+	"log"
+	"sort"
+
+	plugin "github.com/golang/protobuf/protoc-gen-go/plugin"
+)
+
+// reportSizes logs the size in bytes of each generated file, largest first,
+// and their total, see the size_report parameter.
+func reportSizes(files []*plugin.CodeGeneratorResponse_File) {
+	files = append([]*plugin.CodeGeneratorResponse_File(nil), files...)
+	sort.SliceStable(files, func(i, j int) bool {
+		return len(files[i].GetContent()) > len(files[j].GetContent())
+	})
+
+	total := 0
+	for _, f := range files {
+		total += len(f.GetContent())
+		log.Printf("size: %8d %s", len(f.GetContent()), f.GetName())
+	}
+	log.Printf("size: %8d total", total)
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+)
+
+// Field numbers of the descriptor messages, used to build the paths of
+// source code locations.
+const (
+	fileMessageTypePath = 4
+	fileEnumTypePath    = 5
+	fileServicePath     = 6
+	messageFieldPath    = 2
+	messageNestedPath   = 3
+	messageEnumTypePath = 4
+	messageOneofPath    = 8
+	serviceMethodPath   = 2
+)
+
+// unsupported returns the constructs of files that can't be generated
+// faithfully, prefixed with their position in the .proto file. They're
+// logged as warnings, or fail the generation with the strict parameter.
+func unsupported(files []*descriptor.FileDescriptorProto) []string {
+	var problems []string
+	for _, fd := range files {
+		report := func(path []int32, format string, args ...interface{}) {
+			problems = append(problems, sourcePosition(fd, path)+": "+fmt.Sprintf(format, args...))
+		}
+
+		var checkMessage func(msg *descriptor.DescriptorProto, name string, path []int32)
+		checkMessage = func(msg *descriptor.DescriptorProto, name string, path []int32) {
+			if msg.GetOptions().GetMapEntry() {
+				return
+			}
+			for i, oneof := range msg.GetOneofDecl() {
+				if isSyntheticOneof(msg, int32(i)) {
+					continue
+				}
+				report(appendPath(path, messageOneofPath, int32(i)),
+					"oneof %s.%s is generated as optional fields, setting a member doesn't clear the others", name, oneof.GetName())
+			}
+			for i, field := range msg.GetField() {
+				fieldPath := appendPath(path, messageFieldPath, int32(i))
+				if field.DefaultValue != nil {
+					report(fieldPath, "default value of field %s.%s is ignored", name, field.GetName())
+				}
+				if !isKnownType(field.GetType()) {
+					report(fieldPath, "field %s.%s has unsupported type %s", name, field.GetName(), field.GetType())
+				}
+			}
+			for i, nested := range msg.GetNestedType() {
+				checkMessage(nested, name+"."+nested.GetName(), appendPath(path, messageNestedPath, int32(i)))
+			}
+		}
+		for i, msg := range fd.GetMessageType() {
+			checkMessage(msg, strings.TrimPrefix(fullTypeName(fd, msg.GetName()), "."), []int32{fileMessageTypePath, int32(i)})
+		}
+
+		for i, service := range fd.GetService() {
+			for j, method := range service.GetMethod() {
+				// Server streaming is supported with newline-delimited JSON.
+				if method.GetClientStreaming() {
+					report([]int32{fileServicePath, int32(i), serviceMethodPath, int32(j)},
+						"client streaming method %s.%s isn't supported by Twirp", service.GetName(), method.GetName())
+				}
+			}
+		}
+	}
+	return problems
+}
+
+// checkUnsupported logs the unsupported constructs of files, or returns them
+// as an error with the strict parameter.
+func checkUnsupported(files []*descriptor.FileDescriptorProto) error {
+	problems := unsupported(files)
+	if len(problems) == 0 {
+		return nil
+	}
+	if params.Strict {
+		return fmt.Errorf("unsupported constructs:\n%s", strings.Join(problems, "\n"))
+	}
+	for _, problem := range problems {
+		log.Printf("warning: %s", problem)
+	}
+	return nil
+}
+
+// sourcePosition returns the file:line:column of the element at path in fd,
+// or only the file name without source info.
+func sourcePosition(fd *descriptor.FileDescriptorProto, path []int32) string {
+	for _, loc := range fd.GetSourceCodeInfo().GetLocation() {
+		if len(loc.GetSpan()) < 2 || !samePath(loc.GetPath(), path) {
+			continue
+		}
+		return fmt.Sprintf("%s:%d:%d", fd.GetName(), loc.GetSpan()[0]+1, loc.GetSpan()[1]+1)
+	}
+	return fd.GetName()
+}
+
+func samePath(a, b []int32) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func appendPath(path []int32, elems ...int32) []int32 {
+	return append(path[:len(path):len(path)], elems...)
+}
+
+// isSyntheticOneof reports whether the oneof at index is the one protoc
+// synthesizes for a proto3 optional field.
+func isSyntheticOneof(msg *descriptor.DescriptorProto, index int32) bool {
+	for _, field := range msg.GetField() {
+		if field.OneofIndex != nil && field.GetOneofIndex() == index {
+			return field.GetProto3Optional()
+		}
+	}
+	return false
+}
+
+func isKnownType(t descriptor.FieldDescriptorProto_Type) bool {
+	_, ok := descriptor.FieldDescriptorProto_Type_name[int32(t)]
+	return ok
+}
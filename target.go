@@ -0,0 +1,78 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+)
+
+// assign returns the function merging objects in the generated code,
+// Object.assign, or the assign helper of twirp.ts with target=es5, which
+// lacks it.
+func assign() string {
+	if params.Target == "es5" {
+		return "assign"
+	}
+	return "Object.assign"
+}
+
+// objectLiteral returns a JavaScript object with props, the props starting
+// with ... spread the properties of another object, e.g. {{objectLiteral
+// "...init" "headers"}}. Object spread is ES2018, below target=esnext the
+// objects are merged with assign instead.
+func objectLiteral(props ...string) string {
+	if params.Target == "esnext" {
+		return "{ " + strings.Join(props, ", ") + " }"
+	}
+
+	args := []string{"{}"}
+	var literal []string
+	flush := func() {
+		if len(literal) > 0 {
+			args = append(args, "{ "+strings.Join(literal, ", ")+" }")
+			literal = nil
+		}
+	}
+	for _, prop := range props {
+		if strings.HasPrefix(prop, "...") {
+			flush()
+			args = append(args, strings.TrimPrefix(prop, "..."))
+		} else {
+			literal = append(literal, prop)
+		}
+	}
+	flush()
+
+	// A leading literal is a fresh object already.
+	if len(args) > 1 && strings.HasPrefix(args[1], "{ ") {
+		args = args[1:]
+	}
+	return assign() + "(" + strings.Join(args, ", ") + ")"
+}
+
+// checkTarget returns the methods of files that can't be generated for the
+// target parameter as an error: server streaming methods return async
+// iterators, which are ES2018.
+func checkTarget(files []*descriptor.FileDescriptorProto) error {
+	if params.Target == "esnext" {
+		return nil
+	}
+
+	var problems []string
+	for _, fd := range files {
+		for i, service := range fd.GetService() {
+			for j, method := range service.GetMethod() {
+				if method.GetServerStreaming() && !method.GetClientStreaming() {
+					problems = append(problems, fmt.Sprintf("%s: server streaming method %s.%s requires target=esnext",
+						sourcePosition(fd, []int32{fileServicePath, int32(i), serviceMethodPath, int32(j)}),
+						service.GetName(), method.GetName()))
+				}
+			}
+		}
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("unsupported with target=%s:\n%s", params.Target, strings.Join(problems, "\n"))
+	}
+	return nil
+}
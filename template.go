@@ -3,6 +3,10 @@ package main
 import (
 	"bytes"
 	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
 	"strings"
 	"text/template"
 
@@ -13,12 +17,19 @@ type importValues struct {
 	RelativeImportBase string
 	Path               string
 	TypeMap            map[string]struct{}
-	Types              []string
+	// Types are the local names of the imported types, Names the names
+	// they're exported as.
+	Types []string
+	Names map[string]string
+	// Files are the generated files of the types imported through a
+	// package index.
+	Files map[string]string
 }
 
-const importTemplate = `
+var importTemplate = `
 import { {{range $i, $t := .Types -}}
   {{- if $i}}, {{end -}}
+  {{- with index $.Names $t}}{{if ne . $t}}{{.}} as {{end}}{{end -}}
   {{- $t -}}
 {{- end}} } from "{{.RelativeImportBase}}{{.Path}}";
 `
@@ -28,33 +39,125 @@ func (iv *importValues) Compile() (string, error) {
 }
 
 type enumKeyVal struct {
-	Name  string
-	Value int32
+	Name       string
+	Value      int32
+	Deprecated bool
 }
 
 type enumValues struct {
-	Name   string
-	Values []*enumKeyVal
+	Name       string
+	Values     []*enumKeyVal
+	Deprecated bool
+	// Source is the position of the enum in the .proto file with the
+	// source_locations parameter.
+	Source string
 }
 
-const enumTemplate = `
+var enumTemplate = `
 {{$enumName := .Name}}
-export enum {{$enumName}} {
+{{- with .Source}}
+// source: {{.}}
+{{- end}}
+{{- if .Deprecated}}
+/** @deprecated */
+{{- end}}
+export {{if constEnums}}const {{end}}enum {{$enumName}} {
   {{- range $i, $v := .Values}}
   {{- if $i}},{{end}}
+  {{- if $v.Deprecated}}
+  /** @deprecated */
+  {{- end}}
   {{$v.Name}} = "{{$v.Name}}"
   {{- end}}
 }
+{{- if not constEnums}}
+
+export const {{$enumName}}Values: { [name: string]: number } = {
+  {{- range $i, $v := .Values}}
+  {{- if $i}},{{end}}
+  {{$v.Name}}: {{$v.Value}}
+  {{- end}}
+};
+
+export const {{$enumName}}Names: { [value: number]: {{$enumName}} } = {
+  {{- range $i, $v := .Names}}
+  {{- if $i}},{{end}}
+  {{$v.Value}}: {{$enumName}}.{{$v.Name}}
+  {{- end}}
+};
+{{- end}}
 `
 
+// Names returns the values to look up by number, the first name is used for
+// aliased numbers.
+func (ev *enumValues) Names() []*enumKeyVal {
+	var names []*enumKeyVal
+	seen := make(map[int32]bool)
+	for _, v := range ev.Values {
+		if !seen[v.Value] {
+			seen[v.Value] = true
+			names = append(names, v)
+		}
+	}
+	return names
+}
+
 func (ev *enumValues) Compile() (string, error) {
 	return compileAndExecute(enumTemplate, ev)
 }
 
+// enumMapsTemplate is the .enums.ts file with the Values and Names maps of
+// the const enums of a file with enum_style=const. The names are cast
+// instead of read from the enums, which are only imported as types.
+var enumMapsTemplate = `
+{{with lintHeader}}{{.}}
+
+{{end -}}
+{{with banner .Source}}{{.}}
+
+{{end -}}
+import { {{range $i, $e := .AllEnums}}{{if $i}}, {{end}}{{$e.Name}}{{end}} } from "./{{.BaseName}}{{jsExtension}}";
+{{- range .AllEnums}}
+{{$enumName := .Name}}
+export const {{$enumName}}Values: { [name: string]: number } = {
+  {{- range $i, $v := .Values}}
+  {{- if $i}},{{end}}
+  {{$v.Name}}: {{$v.Value}}
+  {{- end}}
+};
+
+export const {{$enumName}}Names: { [value: number]: {{$enumName}} } = {
+  {{- range $i, $v := .Names}}
+  {{- if $i}},{{end}}
+  {{$v.Value}}: <{{$enumName}}>"{{$v.Name}}"
+  {{- end}}
+};
+{{- end}}
+`
+
+// CompileEnumMaps compiles the .enums.ts file of pf, see enumMapsTemplate.
+func (pf *protoFile) CompileEnumMaps() (string, error) {
+	return compileAndExecute(enumMapsTemplate, pf)
+}
+
 type messageValues struct {
-	Name          string
+	Name string
+	// FullName is the fully qualified name of the message, its brand with
+	// the nominal parameter.
+	FullName      string
 	Interface     string
 	JSONInterface string
+	// ReadonlyInterface is the interface with readonly members generated
+	// with the readonly parameter.
+	ReadonlyInterface string
+	Deprecated        bool
+	// Doc is the comments of the message in the .proto file, see docComment,
+	// Source its position with the source_locations parameter.
+	Doc        string
+	Source     string
+	Extendable bool
+	// Options are the custom options of the message.
+	Options []*optionValue
 
 	Fields      []*fieldValues
 	NestedTypes []*messageValues
@@ -62,11 +165,29 @@ type messageValues struct {
 }
 
 var messageTemplate = `
+{{- with .Source}}
+// source: {{.}}
+{{- end}}
+{{- with docComment .Doc .Deprecated}}
+{{.}}
+{{- end}}
 export interface {{.Interface}} {
   {{- if .Fields }}
   {{- range .Fields}}
-  {{.Field }}?: {{. | fieldType}};
+  {{- if .Deprecated}}
+  /** @deprecated */
   {{- end}}
+  {{.Field }}{{if not .Required}}?{{end}}: {{interfaceMemberType . false}};
+  {{- if .Alias}}
+  {{- if .Deprecated}}
+  /** @deprecated */
+  {{- end}}
+  {{.Alias}}{{if not .Required}}?{{end}}: {{interfaceMemberType . false}};
+  {{- end}}
+  {{- end}}
+  {{- end}}
+  {{- if nominal}}
+  readonly $type?: "{{.FullName}}";
   {{- end}}
 
   toJSON?(): object;
@@ -80,117 +201,1180 @@ export interface {{.Interface}} {
 
 {{ end -}}
 
+{{if .Deprecated}}/** @deprecated */
+{{end -}}
 export interface {{.JSONInterface}} {
   {{- range $i, $v := .Fields}}
-  {{$v.Name}}?: {{ $v | fieldType }};
+  {{- if $v.Deprecated}}
+  /** @deprecated */
+  {{- end}}
+  {{$v.Name}}?: {{interfaceMemberType $v true}};
+  {{- end}}
+  {{- if nominal}}
+  readonly $type?: "{{.FullName}}";
   {{- end}}
   toJSON?(): object;
 }
+{{- if readonly}}
 
+{{if .Deprecated}}/** @deprecated */
+{{end -}}
+export interface {{.ReadonlyInterface}} {
+  {{- range .Fields}}
+  {{- if .Deprecated}}
+  /** @deprecated */
+  {{- end}}
+  readonly {{.Field}}{{if not .Required}}?{{end}}: {{readonlyMemberType .}};
+  {{- if .Alias}}
+  {{- if .Deprecated}}
+  /** @deprecated */
+  {{- end}}
+  readonly {{.Alias}}{{if not .Required}}?{{end}}: {{readonlyMemberType .}};
+  {{- end}}
+  {{- end}}
+  {{- if nominal}}
+  readonly $type?: "{{.FullName}}";
+  {{- end}}
+}
+{{- end}}
+{{- if generateClasses}}
+
+{{with .Source -}}
+// source: {{.}}
+{{end -}}
+{{with docComment .Doc .Deprecated -}}
+{{.}}
+{{end -}}
 export class {{.Name}} implements {{.Interface}} {
+  {{- if properties}}
+  {{- range .Fields}}
+  {{- if .Deprecated}}
+  /** @deprecated */
+  {{- end}}
+  public {{if immutable}}readonly {{end}}{{.Field}}: {{. | propertyType}};
+  {{- end}}
+  {{- else}}
   private _json: {{.JSONInterface}};
+  {{- end}}
+  {{- if nominal}}
+  declare readonly $type?: "{{.FullName}}";
+  {{- end}}
+  {{- with .Options}}
+
+  static options: { [name: string]: any } = {{optionsObject . "  "}};
+  {{- end}}
+  {{- if protobuf}}
 
-  constructor(m?: {{.Interface}}) {
-    this._json = {};
+  static protobuf: MessageCodec = messageCodec([
+    {{- range $i, $f := .Fields}}
+    {{- if $i}},{{end}}
+    {{fieldSchema $f}}
+    {{- end}}
+  {{- if .Fields}}
+  {{end}}]);
+  {{- end}}
+  {{- if httpGet}}
+
+  static queryFields: QueryFields = {
+    {{- range $i, $f := queryFields .Fields}}
+    {{- if $i}},{{end}}
+    {{$f}}
+    {{- end}}
+  {{- if queryFields .Fields}}
+  {{end}}};
+  {{- end}}
+
+  {{- if properties}}
+
+  constructor(m: {{if .HasRequired}}Partial<{{.Interface}}>{{else}}{{.Interface}}{{end}} = {}{{if immutable}}, unknown?: object{{end}}) {
+    {{- range .Fields}}
+    this.{{.Field}} = {{propertyValue .}};
+    {{- end}}
+    {{- if immutable}}
+    deepFreeze({{assign}}(unknownFields(this), unknown));
+    deepFreeze(this);
+    {{- end}}
+  }
+  {{- else}}
+
+  constructor(m?: {{if .HasRequired}}Partial<{{.Interface}}>{{else}}{{.Interface}}{{end}}{{if immutable}}, unknown?: object{{end}}) {
+    this._json = {{if immutable}}{{assign}}({}, unknown){{else}}{}{{end}};
     if (m) {
       {{- range .Fields}}
-      this._json["{{.Name}}"] = m.{{.Field}};
+      this._json["{{.Name}}"] = {{if .Alias}}m.{{.Field}} !== undefined ? m.{{.Field}} : m.{{.Alias}}{{else}}m.{{.Field}}{{end}};
       {{- end}}
     }
+    {{- range .Fields}}
+    {{- if .Required}}
+    if (this._json["{{.Name}}"] === undefined) {
+      this._json["{{.Name}}"] = {{zeroValue .}};
+    }
+    {{- end}}
+    {{- end}}
+    {{- if immutable}}
+    deepFreeze(this);
+    {{- end}}
   }
+  {{- end}}
   {{- range .Fields}}
+  {{- if not properties}}
 
   // {{.Field}} ({{.Name}})
-  public get {{.Field}}(): {{. | fieldType}} {
-    {{if .IsRepeated -}}
+  {{- if .Deprecated}}
+  /** @deprecated */
+  {{- end}}
+  public get {{.Field}}(): {{. | accessorType}} {
+    {{if .IsOptional -}}
+      return this._json.{{.Name}}
+    {{- else if .IsRepeated -}}
       return this._json.{{.Name}} || []
+    {{- else if .IsMap -}}
+      return this._json.{{.Name}} || {}
+    {{- else if hasZeroValue . -}}
+      return this._json.{{.Name}} != null ? this._json.{{.Name}} : {{zeroValue .}}
     {{- else -}}
-      return this._json.{{.Name}}!
+      return this._json.{{.Name}}
     {{- end}};
   }
-  public set {{.Field}}(value: {{. | fieldType}}) {
+  {{- if not immutable}}
+  {{- if .Deprecated}}
+  /** @deprecated */
+  {{- end}}
+  public set {{.Field}}(value: {{. | accessorType}}) {
     this._json.{{.Name}} = value;
   }
   {{- end}}
+  {{- else if or .Alias .HasPresence}}
+  {{end}}
+  {{- if .Alias}}
+  {{- if .Deprecated}}
+  /** @deprecated */
+  {{- end}}
+  public get {{.Alias}}(): {{. | accessorType}} {
+    return this.{{.Field}};
+  }
+  {{- if not immutable}}
+  {{- if .Deprecated}}
+  /** @deprecated */
+  {{- end}}
+  public set {{.Alias}}(value: {{. | accessorType}}) {
+    this.{{.Field}} = value;
+  }
+  {{- end}}
+  {{- end}}
+  {{- if .HasPresence}}
+  public has{{.Name | camelCase | upperCaseFirst}}(): boolean {
+    return {{if properties}}this.{{.Field}}{{else}}this._json.{{.Name}}{{end}} != null;
+  }
+  {{- if not immutable}}
+  public clear{{.Name | camelCase | upperCaseFirst}}() {
+    {{if properties}}this.{{.Field}} = undefined{{else}}delete this._json.{{.Name}}{{end}};
+  }
+  {{- end}}
+  {{- end}}
+  {{- end}}
+  {{- if immutable}}
 
-  static fromJSON(m: {{.JSONInterface}} = {}): {{.Name}} {
+  // with returns a copy of the message with the members set in m, it's
+  // frozen like the message.
+  public with(m: Partial<{{.Interface}}>): {{.Name}} {
     return new {{.Name}}({
+      {{- range $i, $f := .Fields}}{{if $i}},{{end}}
+      {{$f.Field}}: {{withMember $f}}
+      {{- end}}
+    {{- if .Fields}}
+    {{end}}}, {{if properties}}unknownFields(this){{else}}this._json{{end}});
+  }
+  {{- end}}
+
+  {{- if .Extendable}}
+
+  public getExtension<T>(ext: Extension<{{.Interface}}, T>): T | undefined {
+    return ext.fromJSON({{if properties}}unknownFields(this){{else}}this._json{{end}});
+  }
+  {{- if immutable}}
+  public withExtension<T>(ext: Extension<{{.Interface}}, T>, value: T): {{.Name}} {
+    const unknown: any = {{assign}}({}, {{if properties}}unknownFields(this){{else}}this._json{{end}});
+    unknown[ext.name] = value;
+    return new {{.Name}}(this, unknown);
+  }
+  {{- else}}
+  public setExtension<T>(ext: Extension<{{.Interface}}, T>, value: T) {
+    {{if properties}}unknownFields(this){{else}}(<any>this._json){{end}}[ext.name] = value;
+  }
+  {{- end}}
+  {{- end}}
+
+  static fromJSON(m: {{.JSONInterface}} = {}): {{.Name}} {
+    {{- if immutable}}
+    // Preserve unknown fields (and extensions) so they survive a round-trip,
+    // the message is frozen once constructed.
+    const known: string[] = [{{range $i, $v := .Fields}}{{if $i}}, {{end}}"{{$v.Name}}"{{end}}];
+    const unknown: any = {};
+    Object.keys(m).forEach(k => {
+      if (known.indexOf(k) < 0) {
+        unknown[k] = (<any>m)[k];
+      }
+    });
+    {{- end}}
+    const v = new {{.Name}}({
     {{range $i, $v := .Fields -}}
       {{- if $i}},
       {{else}}  {{end}}{{$v.Field}}: {{ $v | objectToField -}}
     {{- end}}
+    }{{if immutable}}, unknown{{end}});
+    {{- if not immutable}}
+    // Preserve unknown fields (and extensions) so they survive a round-trip.
+    const known: string[] = [{{range $i, $v := .Fields}}{{if $i}}, {{end}}"{{$v.Name}}"{{end}}];
+    Object.keys(m).forEach(k => {
+      if (known.indexOf(k) < 0) {
+        {{if properties}}unknownFields(v){{else}}(<any>v._json){{end}}[k] = (<any>m)[k];
+      }
+    });
+    {{- end}}
+    return v;
+  }
+
+  // create returns a {{.Name}} with the members set in m and the zero values
+  // of the other fields, nested messages and oneof members are left
+  // undefined.
+  static create(m: Partial<{{.Interface}}> = {}): {{.Name}} {
+    return new {{.Name}}({
+    {{range $i, $v := .Fields -}}
+      {{- if $i}},
+      {{else}}  {{end}}{{$v.Field}}: {{ $v | createField -}}
+    {{- end}}
+    });
+  }
+
+  // fromPartial builds a {{.Name}} from the members that are set in m,
+  // filling zero values for the others, except oneof members, and building
+  // nested messages.
+  static fromPartial(m: DeepPartial<{{.Interface}}> = {}): {{.Name}} {
+    return new {{.Name}}({
+    {{range $i, $v := .Fields -}}
+      {{- if $i}},
+      {{else}}  {{end}}{{$v.Field}}: {{ $v | partialToField -}}
+    {{- end}}
     });
   }
 
   public toJSON(): object {
+    {{- if or emitDefaults .HasJSONConversions properties}}
+    const json: any = {{if properties}}{{assign}}({}, unknownFields(this), {{template "properties" .}}){{else}}{{assign}}({}, this._json){{end}};
+    {{- if emitDefaults}}
+    {{- range $f := .Fields}}
+    {{- with emittedDefault $f}}
+    if (json["{{$f.Name}}"] === undefined) {
+      json["{{$f.Name}}"] = {{.}};
+    }
+    {{- end}}
+    {{- end}}
+    {{- end}}
+    {{- range $f := .Fields}}
+    {{- with fieldToJSON $f}}
+    if (json["{{$f.Name}}"] != null) {
+      {{.}};
+    }
+    {{- end}}
+    {{- end}}
+    return json;
+    {{- else}}
     return this._json;
+    {{- end}}
+  }
+
+  // clone returns a deep copy of the message sharing no state with it, the
+  // nested messages are cloned by their classes.
+  public clone(): {{.Name}} {
+    {{- if immutable}}
+    // Frozen messages can be shared.
+    return this;
+    {{- else}}
+    const json: any = {{if properties}}{{template "properties" .}}{{else}}{{assign}}({}, this._json){{end}};
+    {{- range $f := .Fields}}
+    {{- with fieldClone $f}}
+    if (json["{{$f.Name}}"] != null) {
+      {{.}};
+    }
+    {{- end}}
+    {{- end}}
+    {{- if properties}}
+    const m = new {{.Name}}({
+      {{- range $i, $f := .Fields}}{{if $i}},{{end}}
+      {{$f.Field}}: json["{{$f.Name}}"]
+      {{- end}}
+    {{- if .Fields}}
+    {{end}}});
+    {{assign}}(unknownFields(m), unknownFields(this));
+    {{- else}}
+    const m = new {{.Name}}();
+    m._json = json;
+    {{- end}}
+    return m;
+    {{- end}}
+  }
+
+  // equals reports whether other has the same field values, unset fields
+  // being equal to their zero values.
+  public equals(other: {{.Interface}}): boolean {
+    {{- if .Fields}}
+    const o = other instanceof {{.Name}} ? other : new {{.Name}}(other);
+    {{- if eq (len .Fields) 1}}
+    return {{fieldEquals (index .Fields 0)}};
+    {{- else}}
+    return (
+      {{- range $i, $f := .Fields}}{{if $i}} &&{{end}}
+      {{fieldEquals $f}}
+      {{- end}}
+    );
+    {{- end}}
+    {{- else}}
+    return true;
+    {{- end}}
   }
 }
+{{- if builders}}
+
+// {{.Name}}Builder builds a {{.Name}} field by field with chainable setters,
+// build checks that the required fields are set.
+export class {{.Name}}Builder {
+  private m: Partial<{{.Interface}}> = {};
+  {{- $name := .Name}}
+  {{- range .Fields}}
+
+  {{if .Deprecated}}/** @deprecated */
+  {{end}}public set{{.Field | upperCaseFirst}}(value: {{interfaceMemberType . false}}): {{$name}}Builder {
+    this.m.{{.Field}} = value;
+    return this;
+  }
+  {{- end}}
+
+  // build returns the {{.Name}}, it throws if a required field isn't set.
+  public build(): {{.Name}} {
+    {{- range .Fields}}
+    {{- if .Required}}
+    if (this.m.{{.Field}} === undefined) {
+      throw new Error("{{$name}}Builder: required field {{.Name}} isn't set");
+    }
+    {{- end}}
+    {{- end}}
+    return {{.Name}}.create(this.m);
+  }
+}
+{{- end}}
+{{- if ioTs}}
+
+// {{.Name}}JSONCodec validates the proto3 JSON of {{.Name}}.
+export const {{.Name}}JSONCodec = t.partial({
+  {{- range $i, $f := .Fields}}{{if $i}},{{end}}
+  {{$f.Name}}: {{fieldCodec $f}}
+  {{- end}}
+});
+
+// {{.Name}}Codec decodes {{.Name}} messages from their proto3 JSON.
+export const {{.Name}}Codec = {{.Name}}JSONCodec.pipe(
+  new t.Type<{{.Name}}, any, any>(
+    "{{.Name}}",
+    (u): u is {{.Name}} => u instanceof {{.Name}},
+    m => t.success({{.Name}}.fromJSON(m)),
+    m => m.toJSON()
+  ),
+  "{{.Name}}"
+);
+{{- end}}
+{{- end}}
+{{- if functional}}
+
+// create{{.Name}} returns an {{.Interface}} with the members set in m and
+// the zero values of the other fields, nested messages and oneof members are
+// left undefined.
+export const create{{.Name}} = (m: Partial<{{.Interface}}> = {}): {{.Interface}} => {
+  return {
+    {{- range $i, $f := .Fields}}{{if $i}},{{end}}
+    {{$f.Field}}: {{createField $f}}
+    {{- end}}
+  };
+};
+
+// decode{{.Name}} converts proto3 JSON to an {{.Interface}}, absent fields
+// get their zero values, except oneof members.
+export const decode{{.Name}} = (m: {{.JSONInterface}} = {}): {{.Interface}} => {
+  return create{{.Name}}({
+    {{- range $i, $f := .Fields}}{{if $i}},{{end}}
+    {{$f.Field}}: {{objectToField $f}}
+    {{- end}}
+  });
+};
+
+// encode{{.Name}} converts an {{.Interface}} to its canonical proto3 JSON.
+export const encode{{.Name}} = (m: {{.Interface}}): {{.JSONInterface}} => {
+  {{- if or emitDefaults .HasJSONConversions}}
+  const json: any = {{template "jsonMembers" .}};
+  {{- if emitDefaults}}
+  {{- range $f := .Fields}}
+  {{- with emittedDefault $f}}
+  if (json["{{$f.Name}}"] === undefined) {
+    json["{{$f.Name}}"] = {{.}};
+  }
+  {{- end}}
+  {{- end}}
+  {{- end}}
+  {{- range $f := .Fields}}
+  {{- with fieldToJSON $f}}
+  if (json["{{$f.Name}}"] != null) {
+    {{.}};
+  }
+  {{- end}}
+  {{- end}}
+  return json;
+  {{- else}}
+  return {{template "jsonMembers" .}};
+  {{- end}}
+};
+{{- end}}
+{{- define "properties"}}{
+      {{- range $i, $f := .Fields}}{{if $i}},{{end}}
+      {{$f.Name}}: {{propertyToJSON $f}}
+      {{- end}}
+    {{- if .Fields}}
+    {{end}}}
+{{- end}}
+{{- define "jsonMembers"}}{
+    {{- range $i, $f := .Fields}}{{if $i}},{{end}}
+    {{$f.Name}}: m.{{$f.Field}}
+    {{- end}}
+  }
+{{- end}}
 `
 
+// HasRequired reports whether any field is required, the constructor of the
+// class takes a Partial of its interface then.
+func (mv *messageValues) HasRequired() bool {
+	for _, fv := range mv.Fields {
+		if fv.Required {
+			return true
+		}
+	}
+	return false
+}
+
+// HasJSONConversions reports whether toJSON converts any field values.
+func (mv *messageValues) HasJSONConversions() bool {
+	for _, fv := range mv.Fields {
+		if fieldToJSON(*fv) != "" {
+			return true
+		}
+	}
+	return false
+}
+
 func (mv *messageValues) Compile() (string, error) {
 	return compileAndExecute(messageTemplate, mv)
 }
 
 type fieldValues struct {
-	Name       string
-	Field      string
+	Name  string
+	Field string
+	// Alias is the original name of the field with field_naming=both, it
+	// has accessors too. It's empty when it doesn't differ from Field.
+	Alias      string
 	Type       string
+	ProtoType  descriptor.FieldDescriptorProto_Type
 	IsEnum     bool
 	IsRepeated bool
+	IsOptional bool
+	// IsOneof is set for the members of oneofs, except the synthetic ones
+	// of proto3 optional fields, only the member that is set is sent.
+	IsOneof    bool
+	Deprecated bool
+	// HasPresence is set for fields that distinguish unset from their zero
+	// value: message fields, oneof members, proto3 optional fields and
+	// proto2 fields.
+	HasPresence bool
+	// Required fields have non-optional interface members, the message
+	// classes set their zero value when they're absent, see isRequired.
+	Required bool
+
+	// IsPlainJSON is set for well-known types that are plain JSON values.
+	IsPlainJSON bool
+	IsTimestamp bool
+	// EnumDefault is the name of the zero value of enum fields.
+	EnumDefault string
+
+	IsMap    bool
+	MapKey   *fieldValues
+	MapValue *fieldValues
+
+	// Number and TypeName, the full name of message and enum types, are
+	// used by the protobuf codecs, see fieldSchema, TypeName by toJSON for
+	// wrappers too. IsPacked is set for
+	// repeated fields encoded packed.
+	Number   int32
+	TypeName string
+	IsPacked bool
+}
+
+type extensionValues struct {
+	Name     string
+	Number   int32
+	Extendee string
+	Field    *fieldValues
+}
+
+var extensionTemplate = `
+{{- if .Field.Deprecated}}
+/** @deprecated */
+{{- end}}
+export const {{.Name}}: Extension<{{.Extendee}}, {{.Field | fieldType}}> = {
+  name: "{{.Field.Name}}",
+  fieldNumber: {{.Number}},
+  fromJSON: (m: any) => {{.Field | objectToField}}
+};
+`
+
+func (ev *extensionValues) Compile() (string, error) {
+	return compileAndExecute(extensionTemplate, ev)
 }
 
 type serviceValues struct {
-	Package   string
-	Name      string
+	Package string
+	Name    string
+	// FullName is the fully qualified name of the service, Path its route
+	// with the default /twirp prefix.
+	FullName  string
+	Path      string
 	Interface string
 	Methods   []*serviceMethodValues
+	Options   []*optionValue
+	// ErrorMeta is the type of the meta of the service's errors, see
+	// dependencyResolver.ErrorMeta.
+	ErrorMeta string
+	// Headers are the headers required by all methods, see requiredHeaders.
+	Headers []string
+	// Source is the position of the service in the .proto file with the
+	// source_locations parameter.
+	Source string
+}
+
+// HasBatches reports whether calls of some methods can be batched.
+func (sv *serviceValues) HasBatches() bool {
+	for _, m := range sv.Methods {
+		if m.Batch != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// GetMethods lists the methods called with GET, see the http_get parameter.
+func (sv *serviceValues) GetMethods() []*serviceMethodValues {
+	var methods []*serviceMethodValues
+	for _, m := range sv.Methods {
+		if m.Get {
+			methods = append(methods, m)
+		}
+	}
+	return methods
+}
+
+// MethodOptions lists the methods with custom options.
+func (sv *serviceValues) MethodOptions() []*serviceMethodValues {
+	var methods []*serviceMethodValues
+	for _, m := range sv.Methods {
+		if len(m.Options) > 0 {
+			methods = append(methods, m)
+		}
+	}
+	return methods
+}
+
+// resolveHeaders names the types of the headers of the methods requiring
+// some: <Service>Headers for the headers of the service, <Service><Method>Headers
+// for methods requiring more. Calls of methods requiring headers aren't
+// batched, batches are sent without headers.
+func (sv *serviceValues) resolveHeaders() {
+	types := make(map[string]string)
+	for _, m := range sv.Methods {
+		switch {
+		case len(m.Headers) > len(sv.Headers):
+			m.HeadersType = sv.Name + m.Name + "Headers"
+		case len(m.Headers) > 0:
+			m.HeadersType = sv.Name + "Headers"
+		}
+		types[m.Name] = m.HeadersType
+	}
+	for _, m := range sv.Methods {
+		if m.Batch != nil && (m.HeadersType != "" || types[m.Batch.Method] != "") {
+			m.Batch = nil
+		}
+	}
+}
+
+// mergeHeaders returns the headers of a service followed by the ones a method
+// adds, header names are case-insensitive.
+func mergeHeaders(service, method []string) []string {
+	var headers []string
+	for _, h := range append(append([]string(nil), service...), method...) {
+		found := false
+		for _, e := range headers {
+			found = found || strings.EqualFold(e, h)
+		}
+		if !found {
+			headers = append(headers, h)
+		}
+	}
+	return headers
+}
+
+// UnaryMethods lists the methods that aren't streaming.
+func (sv *serviceValues) UnaryMethods() []*serviceMethodValues {
+	var methods []*serviceMethodValues
+	for _, m := range sv.Methods {
+		if !m.ServerStreaming {
+			methods = append(methods, m)
+		}
+	}
+	return methods
 }
 
 var serviceTemplate = `
+{{with .ErrorMeta -}}
+// {{$.Name}}Error is the error of {{$.Name}} methods.
+export type {{$.Name}}Error = TwirpError<{{.}}>;
+
+{{end -}}
+{{range $m := .Methods}}{{with $m.ErrorMeta -}}
+// {{$.Name}}{{$m.Name}}Error is the error of {{$.Name}}.{{$m.Name}}.
+export type {{$.Name}}{{$m.Name}}Error = TwirpError<{{.}}>;
+
+{{end}}{{end -}}
+{{with .Headers -}}
+// {{$.Name}}Headers are the headers required by {{$.Name}} methods.
+export interface {{$.Name}}Headers {
+  {{- range .}}
+  {{printf "%q" .}}: string;
+  {{- end}}
+  [name: string]: string;
+}
+
+{{end -}}
+{{range $m := .Methods}}{{if gt (len $m.Headers) (len $.Headers) -}}
+// {{$m.HeadersType}} are the headers required by {{$.Name}}.{{$m.Name}}.
+export interface {{$m.HeadersType}}{{if $.Headers}} extends {{$.Name}}Headers{{end}} {
+  {{- range slice $m.Headers (len $.Headers)}}
+  {{printf "%q" .}}: string;
+  {{- end}}
+  {{- if not $.Headers}}
+  [name: string]: string;
+  {{- end}}
+}
+
+{{end}}{{end -}}
+{{range .Methods -}}
+// {{$.Name}}{{.Name}}Request and {{$.Name}}{{.Name}}Response are the input
+// and result types of {{$.Name}}.{{.Name}}.
+export type {{$.Name}}{{.Name}}Request = {{if .InputIsEmpty}}void{{else}}{{.InputType}}{{end}};
+export type {{$.Name}}{{.Name}}Response = {{if .OutputIsEmpty}}void{{else}}{{.ResultType}}{{end}};
+
+{{end -}}
+// {{.Name}}Methods maps the {{.Name}} methods to their input and output
+// types, for generic wrappers over the service. The output of streaming
+// methods is the type of their messages.
+export type {{.Name}}Methods = {
+  {{- range .Methods}}
+  {{.Name | methodName}}: { input: {{$.Name}}{{.Name}}Request; output: {{$.Name}}{{.Name}}Response };
+  {{- end}}
+};
+
+{{with .Source -}}
+// source: {{.}}
+{{end -}}
 export interface {{.Interface}} {
   {{- range .Methods}}
+  {{- with docComment .Doc .Deprecated}}
+  {{.}}
+  {{- end}}
   {{.Name | methodName}}: (
+    {{- if not .InputIsEmpty}}
+    data: {{.InputType}},
+    {{- end}}
+    headers{{if .HeadersType}}: {{.HeadersType}}{{else}}?: object{{end}},
+    options?: CallOptions
+  ) => {{if .ServerStreaming}}AsyncIterable{{else}}Promise{{end}}<{{if .OutputIsEmpty}}void{{else}}{{.ResultType}}{{end}}>;
+  {{- if .Subscribe}}
+  subscribe{{.Name}}: (
+    {{- if not .InputIsEmpty}}
     data: {{.InputType}},
-    headers?: object
-  ) => Promise<{{.OutputType}}>;
+    {{- end}}
+    headers{{if .HeadersType}}: {{.HeadersType}}{{else}}?: object{{end}},
+    options?: CallOptions
+  ) => AsyncIterable<{{if .OutputIsEmpty}}void{{else}}{{.ResultType}}{{end}}>;
+  {{- end}}
   {{- end}}
 }
+{{- if functional}}
+{{- range .Methods}}
+
+// {{$.Name | methodName}}{{.Name}} calls {{$.Name}}.{{.Name}} with the options
+// of a client.
+{{with .Source -}}
+// source: {{.}}
+{{end -}}
+{{with docComment .Doc .Deprecated -}}
+{{.}}
+{{end -}}
+export const {{$.Name | methodName}}{{.Name}} = (
+  client: ClientOptions,
+  {{- if not .InputIsEmpty}}
+  params: {{.InputType}},
+  {{- end}}
+  headers{{if .HeadersType}}: {{.HeadersType}}{{else}}: object = {}{{end}},
+  options: CallOptions = {}
+{{- if .ServerStreaming}}
+): AsyncIterable<{{if .OutputIsEmpty}}void{{else}}{{.ResultType}}{{end}}> => {
+  const open = () => {
+    return callTwirpMethod(client, "{{$.FullName}}", "{{.Name}}", {{template "functionalParams" .}}, headers, options, res => Promise.resolve(res));
+  };
+  return readTwirpStream(open, {{if .OutputIsEmpty}}() => undefined{{else}}m => decode{{.OutputName}}(m){{end}});
+};
+{{- else}}
+): Promise<{{if .OutputIsEmpty}}void{{else}}{{.ResultType}}{{end}}> => {
+  {{- if .OutputIsEmpty}}
+  return callTwirpMethod(client, "{{$.FullName}}", "{{.Name}}", {{template "functionalParams" .}}, headers, options, () => Promise.resolve());
+  {{- else}}
+  return callTwirpMethod(client, "{{$.FullName}}", "{{.Name}}", {{template "functionalParams" .}}, headers, options, readTwirpResponse).then(m => {
+    return decode{{.OutputName}}(m);
+  });
+  {{- end}}
+};
+{{- end}}
+{{- end}}
+{{- else}}
 
+{{with .Source -}}
+// source: {{.}}
+{{end -}}
 export class {{.Name}} implements {{.Interface}} {
   private hostname: string;
   private fetch: Fetch;
-  private path = "/twirp/{{.Package}}.{{.Name}}/";
+  private options: ClientOptions;
+  private path: string;
+  {{- if .HasBatches}}
+  private batchers: { [method: string]: Batcher<any, any> } = {};
+  {{- end}}
+  {{- with .Options}}
+
+  static options: { [name: string]: any } = {{optionsObject . "  "}};
+  {{- end}}
+  {{- with .MethodOptions}}
 
-  constructor(hostname: string, fetch: Fetch) {
+  static methodOptions: { [method: string]: { [name: string]: any } } = {
+    {{- range $i, $m := .}}
+    {{- if $i}},{{end}}
+    {{$m.Name}}: {{optionsObject $m.Options "    "}}
+    {{- end}}
+  };
+  {{- end}}
+
+  constructor(options: ClientOptions);
+  constructor(hostname: string, fetch: Fetch, options?: ClientOptions);
+  constructor(hostname: string | ClientOptions, fetch?: Fetch, options: ClientOptions = {}) {
+    if (typeof hostname !== "string") {
+      options = hostname;
+      hostname = options.baseURL == null ? resolveBaseURL("{{.FullName}}") : options.baseURL;
+    }
     this.hostname = hostname;
-    this.fetch = fetch;
+    this.fetch = clientFetch(options, fetch || options.fetch || defaultFetch);
+    this.options = options;
+    this.path = (options.pathPrefix == null ? "/twirp" : options.pathPrefix) + "/{{.FullName}}/";
+  }
+
+  // warmup opens a connection to the server ahead of the first call, see
+  // preconnect.
+  public warmup(): void {
+    preconnect(this.hostname, this.options);
   }
 
   private url(name: string): string {
     return this.hostname + this.path + name;
   }
 
+  private fetchFor(options: CallOptions): Fetch {
+    return options.fetch ? clientFetch(this.options, options.fetch) : this.fetch;
+  }
+  {{- if .HasBatches}}
+
+  private batcher<Q, R>(method: string, send: (requests: Q[]) => Promise<ReadonlyArray<R>>): Batcher<Q, R> {
+    if (!this.batchers[method]) {
+      this.batchers[method] = new Batcher(this.options.batch || {}, send);
+    }
+    return this.batchers[method];
+  }
+  {{- end}}
+  {{- if websocket}}
+
+  // withWebSocket creates a client making its calls over transport.
+  static withWebSocket(transport: WebSocketTransport, options: ClientOptions = {}): {{.Name}} {
+    return new {{.Name}}({{objectLiteral "...options" "fetch: transport.fetch"}});
+  }
+  {{- end}}
+
   {{- range .Methods}}
 
+  {{with .Source -}}
+  // source: {{.}}
+  {{end -}}
+  {{with docComment .Doc .Deprecated -}}
+  {{.}}
+  {{end -}}
   public {{.Name | methodName}}(
+    {{- if not .InputIsEmpty}}
     params: {{.InputType}},
-    headers: object = {}
-  ): Promise<{{.OutputType}}> {
-    return this.fetch(
-      this.url("{{.Name}}"),
-      createTwirpRequest(params, headers)
-    ).then(res => {
-      if (!res.ok) {
-        return throwTwirpError(res);
-      }
-      return res.json().then(m => {
-        return {{.OutputType}}.fromJSON(m);
-      });
-    });
+    {{- end}}
+    headers{{if .HeadersType}}: {{.HeadersType}}{{else}}: object = {}{{end}},
+    options: CallOptions = {}
+  {{- if .ServerStreaming}}
+  ): AsyncIterable<{{if .OutputIsEmpty}}void{{else}}{{.ResultType}}{{end}}> {
+    const open = () => {
+      return twirpCall(mergeOptions(this.options, "{{.Name}}", options), callOptions =>
+        this.fetchFor(callOptions)(
+          this.url("{{.Name}}"),
+          createTwirpRequest({{if .InputIsEmpty}}{}{{else}}params{{end}}, headers, callOptions)
+        ).then(res => (res.ok ? res : throwTwirpError(res)))
+      );
+    };
+    {{- if .OutputIsEmpty}}
+    return readTwirpStream(open, () => undefined);
+    {{- else if not generateClasses}}
+    return readTwirpStream(open, m => m);
+    {{- else}}
+    return readTwirpStream(open, m => {{.OutputType}}.fromJSON(m));
+    {{- end}}
+  }
+  {{- else if and .ReadOnly (not .OutputIsEmpty)}}
+  ): Promise<{{.ResultType}}> {
+    {{- if .Batch}}
+    if (this.options.batch && Object.keys(headers).length === 0 && Object.keys(options).length === 0) {
+      return this.batcher("{{.Name}}", (requests: {{.InputType}}[]) =>
+        this.{{.Batch.Method | methodName}}(
+          {{- if generateClasses}}new {{.Batch.InputType}}({ {{.Batch.Requests}}: requests }){{else}}{ {{.Batch.Requests}}: requests }{{end -}}
+        ).then(res => res.{{.Batch.Responses}} || [])
+      ).call(params);
+    }
+    {{- end}}
+    const merged = mergeOptions(this.options, "{{.Name}}", options);
+    return cachedCall(merged, "{{$.FullName}}/{{.Name}}", {{if .InputIsEmpty}}{}{{else}}params{{end}}, () =>
+      twirpCall(merged, callOptions =>
+        this.fetchFor(callOptions)(
+          {{- if .Get}}
+          queryURL(this.url("{{.Name}}"){{if not .InputIsEmpty}}, params{{if generateClasses}}, {{.InputType}}.queryFields{{end}}{{end}}),
+          createTwirpGetRequest(headers, callOptions)
+          {{- else}}
+          this.url("{{.Name}}"),
+          createTwirpRequest(
+            {{- if .InputIsEmpty}}{}{{else}}params{{end}}, headers, callOptions
+            {{- if protobuf}}, {{if .InputIsEmpty}}wellKnownCodecs["google.protobuf.Empty"]{{else}}{{.InputType}}.protobuf{{end}}{{end -}}
+          )
+          {{- end}}
+        ).then(res => {
+          if (!res.ok) {
+            return throwTwirpError(res);
+          }
+          return readTwirpResponse(res, callOptions{{if protobuf}}, {{.OutputType}}.protobuf{{end}});
+        })
+      )
+    )
+    {{- if generateClasses}}.then(m => {
+      return {{.OutputType}}.fromJSON(m);
+    })
+    {{- end}};
+  }
+  {{- else}}
+  ): Promise<{{if .OutputIsEmpty}}void{{else}}{{.ResultType}}{{end}}> {
+    {{- if .Batch}}
+    if (this.options.batch && Object.keys(headers).length === 0 && Object.keys(options).length === 0) {
+      return this.batcher("{{.Name}}", (requests: {{.InputType}}[]) =>
+        this.{{.Batch.Method | methodName}}(
+          {{- if generateClasses}}new {{.Batch.InputType}}({ {{.Batch.Requests}}: requests }){{else}}{ {{.Batch.Requests}}: requests }{{end -}}
+        ).then(res => res.{{.Batch.Responses}} || [])
+      ).call(params);
+    }
+    {{- end}}
+    return twirpCall(mergeOptions(this.options, "{{.Name}}", options), callOptions =>
+      this.fetchFor(callOptions)(
+        {{- if .Get}}
+        queryURL(this.url("{{.Name}}"){{if not .InputIsEmpty}}, params{{if generateClasses}}, {{.InputType}}.queryFields{{end}}{{end}}),
+        createTwirpGetRequest(headers, callOptions)
+        {{- else}}
+        this.url("{{.Name}}"),
+        createTwirpRequest(
+          {{- if .InputIsEmpty}}{}{{else}}params{{end}}, headers, callOptions
+          {{- if protobuf}}, {{if .InputIsEmpty}}wellKnownCodecs["google.protobuf.Empty"]{{else}}{{.InputType}}.protobuf{{end}}{{end -}}
+        )
+        {{- end}}
+      ).then(res => {
+        if (!res.ok) {
+          return throwTwirpError(res);
+        }
+        {{- if .OutputIsEmpty}}
+        return;
+        {{- else if not generateClasses}}
+        return readTwirpResponse(res, callOptions);
+        {{- else}}
+        return readTwirpResponse(res, callOptions{{if protobuf}}, {{.OutputType}}.protobuf{{end}}).then(m => {
+          return {{.OutputType}}.fromJSON(m);
+        });
+        {{- end}}
+      })
+    );
+  }
+  {{- end}}
+  {{- if .Subscribe}}
+
+  {{if .Deprecated -}}
+  /** @deprecated */
+  {{end -}}
+  public subscribe{{.Name}}(
+    {{- if not .InputIsEmpty}}
+    params: {{.InputType}},
+    {{- end}}
+    headers{{if .HeadersType}}: {{.HeadersType}}{{else}}: object = {}{{end}},
+    options: CallOptions = {}
+  ): AsyncIterable<{{if .OutputIsEmpty}}void{{else}}{{.ResultType}}{{end}}> {
+    const open = () => {
+      return twirpCall(mergeOptions(this.options, "{{.Name}}", options), callOptions =>
+        this.fetchFor(callOptions)(
+          this.url("{{.Subscribe}}"),
+          createTwirpRequest({{if .InputIsEmpty}}{}{{else}}params{{end}}, { Accept: "text/event-stream", ...headers }, callOptions)
+        ).then(res => (res.ok ? res : throwTwirpError(res)))
+      );
+    };
+    {{- if .OutputIsEmpty}}
+    return readServerSentEvents(open, () => undefined);
+    {{- else if not generateClasses}}
+    return readServerSentEvents(open, m => m);
+    {{- else}}
+    return readServerSentEvents(open, m => {{.OutputType}}.fromJSON(m));
+    {{- end}}
+  }
+  {{- end}}
+  {{- end}}
+}
+{{- end}}
+{{- if mocks}}
+
+// {{.Name}}Mock implements {{.Interface}} with canned responses, for tests.
+export class {{.Name}}Mock implements {{.Interface}} {
+  // calls are the calls made to the mock, in order.
+  public calls: MockCall[] = [];
+
+  constructor(
+    public responses: {
+      {{- range .Methods}}
+      {{.Name | methodName}}?: MockResponse<{{template "mockTypes" .}}{{if .ServerStreaming}}[]{{end}}>;
+      {{- if .Subscribe}}
+      subscribe{{.Name}}?: MockResponse<{{template "mockTypes" .}}[]>;
+      {{- end}}
+      {{- end}}
+    } = {},
+    public options: MockOptions = {}
+  ) {}
+  {{- range .Methods}}
+
+  public {{.Name | methodName}}(
+    {{- if not .InputIsEmpty}}
+    params: {{.InputType}},
+    {{- end}}
+    headers: object = {},
+    options: CallOptions = {}
+  ): {{if .ServerStreaming}}AsyncIterable{{else}}Promise{{end}}<{{if .OutputIsEmpty}}void{{else}}{{.OutputType}}{{end}}> {
+    {{- if .ServerStreaming}}
+    return mockStream(resolveMock(this.calls, this.options, "{{.Name}}", this.responses.{{.Name | methodName}}, {{template "mockArgs" .}}));
+    {{- else if .OutputIsEmpty}}
+    return resolveMock(this.calls, this.options, "{{.Name}}", this.responses.{{.Name | methodName}} || (() => undefined), {{template "mockArgs" .}});
+    {{- else}}
+    return resolveMock(this.calls, this.options, "{{.Name}}", this.responses.{{.Name | methodName}}, {{template "mockArgs" .}});
+    {{- end}}
   }
+  {{- if .Subscribe}}
+
+  public subscribe{{.Name}}(
+    {{- if not .InputIsEmpty}}
+    params: {{.InputType}},
+    {{- end}}
+    headers: object = {},
+    options: CallOptions = {}
+  ): AsyncIterable<{{if .OutputIsEmpty}}void{{else}}{{.OutputType}}{{end}}> {
+    return mockStream(resolveMock(this.calls, this.options, "{{.Name}}", this.responses.subscribe{{.Name}}, {{template "mockArgs" .}}));
+  }
+  {{- end}}
+  {{- end}}
+}
+{{- end}}
+{{- if and .GetMethods (or fakes msw (and server (not fastify)))}}
+
+// {{.Name | methodName}}QueryFields returns the QueryFields of the requests of the methods
+// called with GET, served from their query string.
+const {{.Name | methodName}}QueryFields = (name: string): QueryFields | undefined => {
+  switch (name) {
+    {{- range .GetMethods}}
+    case "{{.Name}}":
+      return {{template "queryFields" .}};
+    {{- end}}
+  }
+  return undefined;
+};
+{{- end}}
+{{- if or fakes msw}}
+
+// {{.Name}}Handlers implement the methods of {{.Name}} in tests, see
+{{- if fakes}} {{.Name}}Fake{{end}}{{if and fakes msw}} and{{end}}{{if msw}} create{{.Name}}MswHandlers{{end}}.
+export interface {{.Name}}Handlers {
+  {{- range .Methods}}
+  {{- if not .ServerStreaming}}
+  {{.Name | methodName}}?: (
+    request: {{if .InputIsEmpty}}void{{else}}{{.InputType}}{{end}},
+    headers: { [name: string]: string }
+  ) => {{if .OutputIsEmpty}}void{{else}}{{.OutputType}}{{end}} | Promise<{{if .OutputIsEmpty}}void{{else}}{{.OutputType}}{{end}}>;
+  {{- end}}
+  {{- end}}
+}
+
+// {{.Name | methodName}}HandlerMethod returns the handler of the method name in
+// handlers, taking the JSON of its requests.
+const {{.Name | methodName}}HandlerMethod = (handlers: {{.Name}}Handlers, name: string): FakeMethod | undefined => {
+  switch (name) {
+    {{- range .Methods}}
+    {{- if not .ServerStreaming}}
+    case "{{.Name}}": {
+      const handler = handlers.{{.Name | methodName}};
+      {{- if .InputIsEmpty}}
+      return handler && ((_, headers) => handler(undefined, headers));
+      {{- else}}
+      return handler && ((m, headers) => handler({{if functional}}decode{{.InputName}}(m){{else if generateClasses}}{{.InputType}}.fromJSON(m){{else}}m{{end}}, headers));
+      {{- end}}
+    }
+    {{- end}}
+    {{- end}}
+  }
+  return undefined;
+};
+{{- end}}
+{{- if fakes}}
+
+// {{.Name}}Fake serves {{.Name}} in memory with handlers. Its fetch can be
+// passed to a client to test the client end to end, streaming methods aren't
+// served.
+export class {{.Name}}Fake {
+  constructor(public handlers: {{.Name}}Handlers = {}) {}
+
+  public fetch: Fetch = fakeFetch("{{.FullName}}", name => {{.Name | methodName}}HandlerMethod(this.handlers, name)
+  {{- if .GetMethods}}, {{.Name | methodName}}QueryFields{{end}});
+}
+{{- end}}
+{{- if msw}}
+
+// create{{.Name}}MswHandlers creates Mock Service Worker handlers stubbing
+// the methods of {{.Name}} with handlers, on the routes under pathPrefix of
+// any origin. Methods without a handler aren't stubbed.
+export const create{{.Name}}MswHandlers = (handlers: {{.Name}}Handlers, pathPrefix = "*/twirp") => {
+  return mswHandlers(pathPrefix + "/{{.FullName}}/", [
+  {{- range $i, $m := .UnaryMethods}}{{if $i}}, {{end}}"{{.Name}}"{{end -}}
+  ], name => {{.Name | methodName}}HandlerMethod(handlers, name)
+  {{- if .GetMethods}}, {{.Name | methodName}}QueryFields{{end}});
+};
+{{- end}}
+{{- if vue}}
+
+// use{{.Name}} returns a Vue composable for each method of client, with
+// reactive data, error and loading refs
+{{- with .UnaryMethods}}, e.g.
+//
+//   const { {{(index . 0).Name | methodName}} } = use{{$.Name}}(client);
+//
+//{{else}}.{{end}} Streaming methods are left out.
+export const use{{.Name}} = (client: {{.Interface}}) => {
+  return {
+    {{- range $i, $m := .UnaryMethods}}
+    {{- if $i}},{{end}}
+    {{.Name | methodName}}: useTwirpCall(client.{{.Name | methodName}}.bind(client))
+    {{- end}}
+  };
+};
+{{- end}}
+{{- if server}}
+
+// {{.Name}}Server is the implementation of {{.Name}} served by
+// create{{.Name}}Handler. Streaming methods aren't served.
+export interface {{.Name}}Server {
+  {{- range .Methods}}
+  {{- if not .ServerStreaming}}
+  {{.Name | methodName}}(
+    {{- if not .InputIsEmpty}}
+    request: {{.InputType}},
+    {{- end}}
+    ctx: ServerContext
+  ): {{if .OutputIsEmpty}}void{{else}}{{.OutputType}}{{end}} | Promise<{{if .OutputIsEmpty}}void{{else}}{{.OutputType}}{{end}}>;
+  {{- end}}
   {{- end}}
 }
+{{- if fastify}}
+
+// create{{.Name}}Plugin creates a Fastify plugin serving service, e.g.
+// fastify.register(create{{.Name}}Plugin(service), { pathPrefix: "/rpc" }).
+export const create{{.Name}}Plugin = (service: {{.Name}}Server): FastifyPlugin => {
+  return (fastify, options, done) => {
+    const route = fastifyRoutes(fastify, "{{.FullName}}", options);
+    {{- range .Methods}}
+    {{- if not .ServerStreaming}}
+    {{- if .InputIsEmpty}}
+    route<{}>("{{.Name}}", {{.BodySchema}}, (_, ctx) => service.{{.Name | methodName}}(ctx){{if .Get}}, {}{{end}});
+    {{- else}}
+    route<{{.InputJSONType}}>("{{.Name}}", {{.BodySchema}}, (m, ctx) => service.{{.Name | methodName}}({{if functional}}decode{{.InputName}}(m){{else if generateClasses}}{{.InputType}}.fromJSON(m){{else}}m{{end}}, ctx)
+    {{- if .Get}}, {{template "queryFields" .}}{{end}});
+    {{- end}}
+    {{- end}}
+    {{- end}}
+    done();
+  };
+};
+{{- else}}
+
+// create{{.Name}}Handler creates an HTTP handler serving service, for Node's
+// http module and Express.
+export const create{{.Name}}Handler = (service: {{.Name}}Server, options: ServerOptions = {}) => {
+  return twirpHandler("{{.FullName}}", name => {
+    switch (name) {
+      {{- range .Methods}}
+      {{- if not .ServerStreaming}}
+      case "{{.Name}}": {
+        {{- if .InputIsEmpty}}
+        return (_, ctx) => Promise.resolve(service.{{.Name | methodName}}(ctx));
+        {{- else}}
+        return (m, ctx) => Promise.resolve(service.{{.Name | methodName}}({{if functional}}decode{{.InputName}}(m){{else if generateClasses}}{{.InputType}}.fromJSON(m){{else}}m{{end}}, ctx));
+        {{- end}}
+      }
+      {{- end}}
+      {{- end}}
+    }
+    return undefined;
+  }, options{{if .GetMethods}}, {{.Name | methodName}}QueryFields{{end}});
+};
+{{- end}}
+{{- end}}
+{{define "functionalParams"}}
+{{- if .InputIsEmpty}}{}{{else}}encode{{.InputName}}(params){{end}}
+{{- end}}
+{{- define "queryFields"}}
+{{- if or .InputIsEmpty (not generateClasses)}}{}{{else}}{{.InputType}}.queryFields{{end}}
+{{- end}}
+{{- define "mockTypes"}}
+{{- if .InputIsEmpty}}void{{else}}{{.InputType}}{{end}}, {{if .OutputIsEmpty}}void{{else}}{{.OutputType}}{{end}}
+{{- end}}
+{{- define "mockArgs"}}
+{{- if .InputIsEmpty}}undefined{{else}}params{{end}}, headers, options
+{{- end -}}
 `
 
 func (sv *serviceValues) Compile() (string, error) {
@@ -203,51 +1387,389 @@ type serviceMethodValues struct {
 	Path       string
 	InputType  string
 	OutputType string
+	// ResultType is the type of the results of the method, the readonly
+	// interface of its output with readonly=responses.
+	ResultType string
+	// InputName and OutputName are the generated names of the messages,
+	// whose functions convert them in functional mode.
+	InputName  string
+	OutputName string
+	Deprecated bool
+	// Doc is the comments of the method in the .proto file, see docComment,
+	// Source its position with the source_locations parameter.
+	Doc     string
+	Source  string
+	Options []*optionValue
+	// ErrorMeta overrides the error meta type of the service.
+	ErrorMeta string
+	// InputJSONType is the type of the JSON of the input, BodySchema its
+	// JSON Schema, for the Fastify plugins of services.
+	InputJSONType string
+	BodySchema    string
+
+	// google.protobuf.Empty is omitted from the generated client methods.
+	InputIsEmpty  bool
+	OutputIsEmpty bool
+
+	// ServerStreaming methods return an AsyncIterable of the messages of
+	// their newline-delimited JSON response.
+	ServerStreaming bool
+	// ReadOnly methods have no side effects, see noSideEffects, their results
+	// are cached with the cache option of clients. With the http_get
+	// parameter Get is set for the ones that aren't streaming, they're
+	// called with GET.
+	ReadOnly bool
+	Get      bool
+	// Batch is the batch method calls are collected into with the batch
+	// option of clients.
+	Batch *batchValues
+	// Subscribe is the path of the server-sent events endpoint of the
+	// method relative to the route of its service, generating a subscribe
+	// variant of the method, see subscribePath.
+	Subscribe string
+	// Headers are the headers required by the method, the ones of its
+	// service first, HeadersType the type of its headers argument or "" for
+	// object, see resolveHeaders.
+	Headers     []string
+	HeadersType string
 }
 
 type protoFile struct {
+	// Source is the name of the .proto file.
+	Source             string
 	Output             string
 	RelativeImportBase string
 	Messages           []*messageValues
 	Services           []*serviceValues
 	Enums              []*enumValues
+	Extensions         []*extensionValues
 	Imports            map[string]*importValues
+	// Package is the proto package of the file, Namespaces the namespaces
+	// merged with its messages and PackageMembers the members of the
+	// namespace of the package, see namespaceValues.
+	Package        string
+	Namespaces     []*namespaceValues
+	PackageMembers []*namespaceMember
+}
+
+// runtimeNames are the names that can be imported from twirp.ts.
+var runtimeNames = []string{
+	"assign", "authFetch", "Batcher", "BatchOptions", "cachedCall", "CacheStore",
+	"CallOptions", "callTwirpMethod", "clientFetch", "ClientOptions", "compressFetch",
+	"CompressionOptions", "createTwirpGetRequest", "createTwirpRequest", "deepFreeze",
+	"DeepPartial", "defaultFetch", "Extension", "fakeFetch", "FakeMethod",
+	"FastifyPlugin", "fastifyRoutes", "Fetch", "formatBytes", "formatTimestamp",
+	"Int32", "jsonSerializer", "mapEquals", "mapValues", "MemoryCacheStore",
+	"mergeOptions", "MessageCodec", "messageCodec", "MockCall", "MockOptions",
+	"MockResponse", "mockStream", "mswHandlers", "OpenTelemetry", "parseTimestamp",
+	"preconnect", "QueryFields", "queryURL", "readServerSentEvents",
+	"readTwirpResponse", "readTwirpStream", "repeatedEquals", "resolveBaseURL",
+	"resolveMock", "Serializer", "ServerContext", "ServerMethod", "ServerOptions",
+	"ServerRequest", "ServerResponse", "setBaseURL", "throwTwirpError", "Timestamp",
+	"TokenProvider", "traceFetch", "twirpCall", "TwirpCallRefs", "TwirpError",
+	"twirpHandler", "UInt32", "unknownFields", "useTwirpCall", "WebSocketTransport",
+	"wellKnownCodecs",
+}
+
+// RuntimeImports lists the names imported from twirp.ts.
+func (pf *protoFile) RuntimeImports() []string {
+	if declarationsOnly() {
+		return nil
+	}
+
+	var names []string
+	switch {
+	case len(pf.Services) > 0 && functional():
+		names = append(names, "CallOptions", "callTwirpMethod", "ClientOptions")
+	case len(pf.Services) > 0:
+		names = append(names, "CallOptions", "clientFetch", "ClientOptions", "defaultFetch", "Fetch", "mergeOptions", "preconnect", "resolveBaseURL", "throwTwirpError", "twirpCall")
+	}
+	post, get := false, false
+	for _, sv := range pf.Services {
+		for _, m := range sv.Methods {
+			post = post || !m.Get || m.Subscribe != ""
+			get = get || m.Get
+		}
+	}
+	if len(pf.Services) > 0 && (post || !get) && !functional() {
+		names = append(names, "createTwirpRequest")
+	}
+	if get {
+		names = append(names, "createTwirpGetRequest", "queryURL")
+	}
+	for _, sv := range pf.Services {
+		meta := sv.ErrorMeta != ""
+		for _, m := range sv.Methods {
+			meta = meta || m.ErrorMeta != ""
+		}
+		if meta {
+			names = append(names, "TwirpError")
+			break
+		}
+	}
+
+	streaming, subscribe := false, false
+	for _, sv := range pf.Services {
+		for _, m := range sv.Methods {
+			streaming = streaming || m.ServerStreaming
+			subscribe = subscribe || m.Subscribe != ""
+		}
+	}
+	if streaming {
+		names = append(names, "readTwirpStream")
+	}
+	if subscribe {
+		names = append(names, "readServerSentEvents")
+	}
+	for _, sv := range pf.Services {
+		cached := false
+		for _, m := range sv.Methods {
+			cached = cached || m.ReadOnly && !m.OutputIsEmpty && !m.ServerStreaming
+		}
+		if cached && !functional() {
+			names = append(names, "cachedCall")
+			break
+		}
+	}
+	for _, sv := range pf.Services {
+		if sv.HasBatches() {
+			names = append(names, "Batcher")
+			break
+		}
+	}
+	if params.WebSocket && len(pf.Services) > 0 {
+		names = append(names, "WebSocketTransport")
+	}
+	if (params.Fakes || params.Msw) && len(pf.Services) > 0 {
+		names = append(names, "FakeMethod")
+	}
+	if params.Fakes && len(pf.Services) > 0 {
+		names = append(names, "fakeFetch")
+		if functional() {
+			names = append(names, "Fetch")
+		}
+	}
+	if params.Msw && len(pf.Services) > 0 {
+		names = append(names, "mswHandlers")
+	}
+	if params.Server && len(pf.Services) > 0 {
+		names = append(names, "ServerContext")
+		if fastify() {
+			names = append(names, "FastifyPlugin", "fastifyRoutes")
+		} else {
+			names = append(names, "ServerOptions", "twirpHandler")
+		}
+	}
+	if params.Vue && len(pf.Services) > 0 {
+		names = append(names, "useTwirpCall")
+	}
+	if params.Mocks && len(pf.Services) > 0 {
+		names = append(names, "MockCall", "MockOptions", "MockResponse", "resolveMock")
+		if streaming || subscribe {
+			names = append(names, "mockStream")
+		}
+	}
+
+	for _, sv := range pf.Services {
+		read := false
+		for _, m := range sv.Methods {
+			read = read || !m.OutputIsEmpty && !m.ServerStreaming
+		}
+		if read {
+			names = append(names, "readTwirpResponse")
+			break
+		}
+	}
+
+	codecs := false
+	if params.Protobuf && len(pf.Services) > 0 {
+		for _, sv := range pf.Services {
+			for _, m := range sv.Methods {
+				codecs = codecs || m.InputIsEmpty
+			}
+		}
+	}
+
+	extendable := len(pf.Extensions) > 0
+	timestamps, bytes := false, false
+	repeated, maps, mapValues := false, false, false
+	branded := make(map[string]bool)
+	addField := func(fv *fieldValues) {
+		if fv.IsMap {
+			fv = fv.MapValue
+		}
+		timestamps = timestamps || fv.IsTimestamp
+		if isBranded(fv.ProtoType) {
+			branded[fv.Type] = true
+		}
+	}
+	for _, m := range pf.Messages {
+		extendable = extendable || m.Extendable && generateClasses()
+		for _, fv := range m.Fields {
+			addField(fv)
+			repeated = repeated || fv.IsRepeated && !fv.IsMap
+			maps = maps || fv.IsMap
+			mapValues = mapValues || fv.IsMap && convertsMapValues(*fv)
+			if fv.IsMap {
+				fv = fv.MapValue
+			}
+			codecs = codecs || fv.IsTimestamp || fv.IsPlainJSON
+			bytes = bytes || fv.ProtoType == descriptor.FieldDescriptorProto_TYPE_BYTES || fv.TypeName == ".google.protobuf.BytesValue"
+		}
+	}
+	for _, ev := range pf.Extensions {
+		addField(ev.Field)
+	}
+	if extendable {
+		names = append(names, "Extension")
+	}
+	if params.Protobuf && len(pf.Messages) > 0 {
+		names = append(names, "messageCodec", "MessageCodec")
+	}
+	if mapValues {
+		names = append(names, "mapValues")
+	}
+	if params.Target == "es5" && (generateClasses() && len(pf.Messages) > 0 || params.WebSocket && len(pf.Services) > 0) {
+		names = append(names, "assign")
+	}
+	if properties() && len(pf.Messages) > 0 {
+		names = append(names, "unknownFields")
+	}
+	if params.Immutable && len(pf.Messages) > 0 {
+		names = append(names, "deepFreeze")
+	}
+	if generateClasses() && len(pf.Messages) > 0 {
+		names = append(names, "DeepPartial")
+		if repeated {
+			names = append(names, "repeatedEquals")
+		}
+		if maps {
+			names = append(names, "mapEquals")
+		}
+	}
+	if params.HTTPGet && (generateClasses() && len(pf.Messages) > 0 || get && (params.Fakes || params.Msw || params.Server && !fastify())) {
+		names = append(names, "QueryFields")
+	}
+	if params.Protobuf && codecs {
+		names = append(names, "wellKnownCodecs")
+	}
+	if bytes && (generateClasses() || functional()) {
+		names = append(names, "formatBytes")
+	}
+	if timestamps && params.Timestamp == "object" {
+		names = append(names, "formatTimestamp", "parseTimestamp", "Timestamp")
+	}
+	for t := range branded {
+		names = append(names, t)
+	}
+
+	sort.Slice(names, func(i, j int) bool {
+		return strings.ToLower(names[i]) < strings.ToLower(names[j])
+	})
+	return names
+}
+
+// AllEnums lists the enums of the file, the nested ones included.
+func (pf *protoFile) AllEnums() []*enumValues {
+	enums := append([]*enumValues(nil), pf.Enums...)
+	for _, mv := range pf.Messages {
+		enums = append(enums, mv.NestedEnums...)
+	}
+	return enums
+}
+
+// EnumMaps lists the Values and Names maps of the enums of the file when
+// they're generated in its .enums.ts file, it imports and re-exports them.
+func (pf *protoFile) EnumMaps() []string {
+	if declarationsOnly() || !constEnums() {
+		return nil
+	}
+	var names []string
+	for _, ev := range pf.AllEnums() {
+		names = append(names, ev.Name+"Values", ev.Name+"Names")
+	}
+	return names
+}
+
+// BaseName is the name of the generated file without its .ts extension.
+func (pf *protoFile) BaseName() string {
+	return strings.TrimSuffix(filepath.Base(pf.Output), ".ts")
+}
+
+// RuntimeModule is the module specifier of the twirp.ts runtime.
+func (pf *protoFile) RuntimeModule() string {
+	if params.RuntimePackage != "" {
+		return params.RuntimePackage
+	}
+	return pf.RelativeImportBase + "twirp" + jsExtension()
 }
 
-func (pf *protoFile) AddImport(imprt *descriptor.FileDescriptorProto, name string) {
+// AddImport imports name as local from the package index of imprt, or
+// directly from the generated file when direct is set to break circular
+// imports. Local differs from name for aliased types, see UseFile.
+func (pf *protoFile) AddImport(imprt *descriptor.FileDescriptorProto, name, local string, direct bool) {
 	if importName(imprt) == "timestamp" {
 		return
 	}
 
-	iv, ok := pf.Imports[imprt.GetPackage()]
+	// Imports are grouped per output directory, package-less files don't
+	// have a unique package name.
+	path := tsImportPath(imprt)
+	key, base := path, pf.RelativeImportBase
+	if direct {
+		key, path = imprt.GetName(), strings.TrimSuffix(tsFileName(imprt), tsExtension())+jsExtension()
+	} else if jsExtension() != "" {
+		// ES modules can't import directories.
+		path = strings.TrimPrefix(path+"/index", "/") + jsExtension()
+	}
+	// Files mapped with M parameters are imported from their module.
+	if module, ok := params.ImportMap[imprt.GetName()]; ok {
+		key, path, base = module, module, ""
+	}
+
+	iv, ok := pf.Imports[key]
 	if !ok {
 		iv = &importValues{
-			RelativeImportBase: pf.RelativeImportBase,
-			Path:               tsImportPath(imprt),
+			RelativeImportBase: base,
+			Path:               path,
 			TypeMap:            make(map[string]struct{}),
+			Names:              make(map[string]string),
+			Files:              make(map[string]string),
 		}
-		pf.Imports[imprt.GetPackage()] = iv
+		pf.Imports[key] = iv
+	}
+	if _, ok := iv.TypeMap[local]; !ok {
+		iv.TypeMap[local] = struct{}{}
+		iv.Types = append(iv.Types, local)
+		iv.Names[local] = name
 	}
-	if _, ok := iv.TypeMap[name]; !ok {
-		iv.TypeMap[name] = struct{}{}
-		iv.Types = append(iv.Types, name)
+	if key == tsImportPath(imprt) {
+		iv.Files[local] = tsFileName(imprt)
 	}
 }
 
 var protoTemplate = `
-/* eslint-disable */
+{{with lintHeader}}{{.}}
 
-// This file has been generated by https://github.com/horizon-games/protoc-gen-twirp_ts.
-// Do not edit.
+{{end -}}
+{{with banner .Source}}{{.}}
 
+{{end -}}
+{{if and ioTs .Messages -}}
+import * as t from "io-ts";
+{{end -}}
 {{if .Imports -}}
 {{- range .Imports -}}
 {{- . | compile}}
 {{end -}}
 {{- end -}}
 
-{{- if .Services -}}
-import { createTwirpRequest, Fetch, throwTwirpError } from "{{.RelativeImportBase}}twirp";
+{{- with .RuntimeImports -}}
+import { {{join . ", "}} } from "{{$.RuntimeModule}}";
+{{end -}}
+{{- with .EnumMaps -}}
+import { {{join . ", "}} } from "./{{$.BaseName}}.enums{{jsExtension}}";
+export { {{join . ", "}} };
 {{end -}}
 
 {{- if .Enums}}
@@ -263,6 +1785,19 @@ import { createTwirpRequest, Fetch, throwTwirpError } from "{{.RelativeImportBas
 {{end -}}
 {{end}}
 
+{{- range .Namespaces -}}
+{{. | compile}}
+
+{{end}}
+
+{{- if .Extensions -}}
+// Extensions
+{{range .Extensions -}}
+{{. | compile}}
+
+{{end -}}
+{{end}}
+
 {{- if .Services -}}
 // Services
 {{range .Services}}
@@ -275,15 +1810,119 @@ func (pf *protoFile) Compile() (string, error) {
 	return compileAndExecute(protoTemplate, pf)
 }
 
-func compileAndExecute(tpl string, data interface{}) (string, error) {
-	funcMap := template.FuncMap{
-		"compile":       compile,
-		"fieldType":     fieldType,
-		"methodName":    methodName,
-		"objectToField": objectToField,
+func templateFuncs() template.FuncMap {
+	return template.FuncMap{
+		"accessorType":        accessorType,
+		"assign":              assign,
+		"banner":              banner,
+		"builders":            func() bool { return params.Builders },
+		"camelCase":           camelCase,
+		"compile":             compile,
+		"createField":         createField,
+		"constEnums":          constEnums,
+		"declarationsOnly":    declarationsOnly,
+		"defaultValue":        defaultValue,
+		"docComment":          docComment,
+		"emitDefaults":        func() bool { return params.EmitDefaults },
+		"emittedDefault":      emittedDefault,
+		"fakes":               func() bool { return params.Fakes },
+		"fastify":             fastify,
+		"fieldClone":          fieldClone,
+		"fieldCodec":          fieldCodec,
+		"fieldEquals":         fieldEquals,
+		"fieldSchema":         fieldSchema,
+		"fieldToJSON":         fieldToJSON,
+		"fieldType":           fieldType,
+		"functional":          functional,
+		"generateClasses":     generateClasses,
+		"hasZeroValue":        hasZeroValue,
+		"httpGet":             func() bool { return params.HTTPGet },
+		"immutable":           func() bool { return params.Immutable },
+		"interfaceMemberType": interfaceMemberType,
+		"ioTs":                func() bool { return params.IoTs },
+		"join":                strings.Join,
+		"jsExtension":         jsExtension,
+		"lintHeader":          lintHeader,
+		"memberType":          memberType,
+		"methodName":          methodName,
+		"mocks":               func() bool { return params.Mocks },
+		"msw":                 func() bool { return params.Msw },
+		"nominal":             func() bool { return params.Nominal },
+		"objectLiteral":       objectLiteral,
+		"objectToField":       objectToField,
+		"optionsObject":       optionsObject,
+		"partialToField":      partialToField,
+		"properties":          properties,
+		"propertyToJSON":      propertyToJSON,
+		"propertyType":        propertyType,
+		"propertyValue":       propertyValue,
+		"protobuf":            func() bool { return params.Protobuf },
+		"queryFields":         queryFields,
+		"readonly":            readonly,
+		"readonlyMemberType":  readonlyMemberType,
+		"server":              func() bool { return params.Server },
+		"upperCaseFirst":      upperCaseFirst,
+		"withMember":          withMember,
+		"vue":                 func() bool { return params.Vue },
+		"websocket":           func() bool { return params.WebSocket },
+		"zeroValue":           zeroValue,
+	}
+}
+
+// templateFiles are the templates that can be overridden with the templates
+// parameter, by files named after them, e.g. message.tmpl.
+var templateFiles = map[string]*string{
+	"enum":      &enumTemplate,
+	"export":    &exportTemplate,
+	"extension": &extensionTemplate,
+	"import":    &importTemplate,
+	"message":   &messageTemplate,
+	"namespace": &namespaceTemplate,
+	"proto":     &protoTemplate,
+	"protobuf":  &protobufTemplate,
+	"service":   &serviceTemplate,
+	"twirp":     &twirpTemplate,
+}
+
+// loadTemplates replaces the built-in templates with the ones found in dir,
+// templates missing from dir are left as-is.
+func loadTemplates(dir string) error {
+	for name, tpl := range templateFiles {
+		filename := filepath.Join(dir, name+".tmpl")
+		b, err := ioutil.ReadFile(filename)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		if _, err := template.New(name).Funcs(templateFuncs()).Parse(string(b)); err != nil {
+			return fmt.Errorf("%s: %v", filename, err)
+		}
+		*tpl = string(b)
+	}
+	return nil
+}
+
+// lintHeader returns the comments disabling linters and type checking in
+// generated files, see the lint and ts_nocheck parameters.
+func lintHeader() string {
+	var lines []string
+	if params.Lint == "tslint" || params.Lint == "both" {
+		lines = append(lines, "/* tslint:disable */")
+	}
+	if params.Lint == "eslint" || params.Lint == "both" {
+		lines = append(lines, "/* eslint-disable */")
 	}
+	if params.TSNoCheck {
+		lines = append(lines, "// @ts-nocheck")
+	}
+	return strings.Join(lines, "\n")
+}
 
-	t, err := template.New("").Funcs(funcMap).Parse(tpl)
+func compileAndExecute(tpl string, data interface{}) (string, error) {
+	t, err := template.New("").Funcs(templateFuncs()).Parse(tpl)
 	if err != nil {
 		return "", err
 	}
@@ -297,78 +1936,561 @@ func compileAndExecute(tpl string, data interface{}) (string, error) {
 }
 
 func objectToField(fv fieldValues) string {
-	t := fv.Type
+	if fv.IsMap {
+		return mapToField(fv)
+	}
+
+	if fv.IsOptional {
+		// Keep explicit presence: an absent key stays undefined instead of
+		// being converted into an empty value.
+		required := fv
+		required.IsOptional = false
+		s := objectToField(required)
+		if strings.HasPrefix(s, presenceGuard(fv)) {
+			return s
+		}
+		return presenceGuard(fv) + s
+	}
 
-	if t == "Date" {
-		t = "string"
+	if fv.IsPlainJSON {
+		return fmt.Sprintf(`m["%s"]`, fv.Name)
 	}
 
-	if fv.IsRepeated {
-		switch t {
-		case "string", "number", "boolean":
-			return fmt.Sprintf(strings.TrimSpace(`
-(m["%s"]! || []).map(v => {
-        return %s(v);
-      })
-`),
-				fv.Name, upperCaseFirst(t),
-			)
+	if fv.IsTimestamp && params.Timestamp != "string" {
+		if fv.IsRepeated {
+			return fmt.Sprintf(`(m["%s"] || []).map(v => %s)`, fv.Name, timestampFromJSON("<any>v"))
 		}
+		return presenceGuard(fv) + timestampFromJSON(fmt.Sprintf(`<any>m["%s"]`, fv.Name))
+	}
 
-		if fv.IsEnum {
-			return fmt.Sprintf(strings.TrimSpace(`
-(m["%s"]! || []).map(v => {
-        return (<any>%s)[v];
-      })
-`),
-				fv.Name, fv.Type,
-			)
+	t := fv.Type
+
+	if fv.IsRepeated {
+		var conv string
+		switch {
+		case isBranded(fv.ProtoType):
+			conv = fmt.Sprintf(`v => <%s>Number(v)`, t)
+		case t == "string", t == "number", t == "boolean":
+			// The conversion functions are passed as-is, they ignore the
+			// other arguments of the callback.
+			conv = upperCaseFirst(t)
+		case fv.IsEnum:
+			conv = "v => " + enumFromJSON(fv.Type, "v")
+		default:
+			conv = "v => " + messageFromJSON(t, "v")
 		}
+		return fmt.Sprintf(`(m["%s"] || []).map(%s)`, fv.Name, conv)
+	}
 
-		return fmt.Sprintf(strings.TrimSpace(`
-(m["%s"]! || []).map(v => {
-        return %s.fromJSON(v);
-      })
-`),
-			fv.Name, t)
+	if isFloat(fv.ProtoType) {
+		// Number parses the "NaN", "Infinity" and "-Infinity" strings.
+		return presenceGuard(fv) + fmt.Sprintf(`Number(m["%s"])`, fv.Name)
 	}
 
-	switch t {
-	case "string", "number", "boolean":
-		return fmt.Sprintf(`m["%s"]!`, fv.Name)
+	switch {
+	case t == "string", t == "number", t == "boolean", isBranded(fv.ProtoType):
+		return fmt.Sprintf(`m["%s"]`, fv.Name)
 	}
 
 	if fv.IsEnum {
-		return fmt.Sprintf(`(<any>%s)[m["%s"]!]!`, fv.Type, fv.Name)
+		return enumFromJSON(fv.Type, fmt.Sprintf(`<any>m["%s"]`, fv.Name))
+	}
+
+	// Nested messages are only converted when present, self-referential
+	// messages would otherwise recurse forever through fromJSON's default
+	// argument.
+	return presenceGuard(fv) + messageFromJSON(t, fmt.Sprintf(`m["%s"]`, fv.Name))
+}
+
+// messageFromJSON converts the JSON v of a message of type t with the
+// fromJSON of its class, or its decode function in functional mode.
+func messageFromJSON(t, v string) string {
+	if functional() {
+		return fmt.Sprintf(`decode%s(%s)`, t, v)
+	}
+	return fmt.Sprintf(`%s.fromJSON(%s)`, t, v)
+}
+
+// emittedDefault returns the zero value emitted for an absent field with
+// emit_defaults, or an empty string for the members of oneofs, only the one
+// that is set is sent.
+func emittedDefault(fv fieldValues) string {
+	if fv.IsOneof {
+		return ""
+	}
+	return defaultValue(fv)
+}
+
+// defaultValue returns the proto3 zero value of a field as emitted by jsonpb
+// with EmitDefaults, or an empty string for fields without one.
+func defaultValue(fv fieldValues) string {
+	switch {
+	case fv.IsOptional, fv.IsPlainJSON:
+		return ""
+	case fv.IsMap:
+		return "{}"
+	case fv.IsRepeated:
+		return "[]"
+	case fv.IsEnum:
+		if fv.EnumDefault == "" {
+			return ""
+		}
+		return fmt.Sprintf("%q", fv.EnumDefault)
+	}
+
+	if isLong(fv.ProtoType) && fv.Type == "string" {
+		return `"0"`
+	}
+	if isBranded(fv.ProtoType) {
+		return "0"
+	}
+
+	switch fv.Type {
+	case "string":
+		return `""`
+	case "number":
+		return "0"
+	case "boolean":
+		return "false"
+	}
+	return ""
+}
+
+// hasZeroValue reports whether the getter of a field returns its zero value
+// when it's absent. Messages, plain JSON values and enums without a zero
+// member have none.
+func hasZeroValue(fv fieldValues) bool {
+	if fv.ProtoType == descriptor.FieldDescriptorProto_TYPE_MESSAGE || fv.ProtoType == descriptor.FieldDescriptorProto_TYPE_GROUP {
+		return false
+	}
+	return defaultValue(fv) != ""
+}
+
+// zeroValue returns the zero value of a required field in the class of its
+// message, typed like its member: enums are their zero member and branded
+// integers are cast.
+func zeroValue(fv fieldValues) string {
+	switch {
+	case fv.IsRepeated, fv.IsMap:
+		return defaultValue(fv)
+	case fv.IsEnum:
+		return fv.Type + "." + fv.EnumDefault
+	case isBranded(fv.ProtoType):
+		return "<" + fv.Type + ">0"
+	}
+	return defaultValue(fv)
+}
+
+// enumFromJSON converts the JSON value v of an enum, either its name or its
+// number, to the enum type.
+func enumFromJSON(enumType, v string) string {
+	if constEnums() {
+		// Const enums can't be indexed.
+		return fmt.Sprintf(`%sNames[typeof %s === "number" ? %s : %sValues[%s]]`, enumType, v, v, enumType, v)
+	}
+	return fmt.Sprintf(`(<any>%s)[%s] || %sNames[%s]`, enumType, v, enumType, v)
+}
+
+// fieldToJSON returns a statement converting the value of a field in toJSON
+// to its canonical proto3 JSON, or an empty string if it's used as-is.
+// Repeated fields and maps convert each of their values.
+func fieldToJSON(fv fieldValues) string {
+	v := fmt.Sprintf(`json["%s"]`, fv.Name)
+	switch {
+	case fv.IsMap:
+		conv := valueToJSON(*fv.MapValue, "v")
+		if conv == "" {
+			return ""
+		}
+		return fmt.Sprintf(`%s = mapValues(%s, (v: any) => %s)`, v, v, conv)
+	case fv.IsRepeated:
+		conv := valueToJSON(fv, "v")
+		if conv == "" {
+			return ""
+		}
+		return fmt.Sprintf(`%s = %s.map((v: any) => %s)`, v, v, conv)
+	}
+	if conv := valueToJSON(fv, v); conv != "" {
+		return v + " = " + conv
+	}
+	return ""
+}
+
+// timestampFromJSON converts the RFC 3339 string v to the representation
+// selected by the timestamp parameter.
+func timestampFromJSON(v string) string {
+	switch params.Timestamp {
+	case "date":
+		return fmt.Sprintf(`new Date(%s)`, v)
+	case "object":
+		return fmt.Sprintf(`parseTimestamp(%s)`, v)
+	}
+	return v
+}
+
+// valueToJSON returns an expression converting a single value v of a field
+// to its canonical proto3 JSON, whatever the shape it was set in: 64-bit
+// integers are strings, timestamps RFC 3339 strings, bytes standard base64,
+// enums their names (numbers with enums_as_ints) and non-finite floats
+// "NaN", "Infinity" and "-Infinity", JSON.stringify would turn them into
+// null. Messages may be plain interface objects, which use camelCase names,
+// they're converted by their classes then, or by their encode functions in
+// functional mode.
+func valueToJSON(fv fieldValues, v string) string {
+	switch t := fv.ProtoType; {
+	case isFloat(t):
+		return fmt.Sprintf(`isFinite(%s) ? %s : String(%s)`, v, v, v)
+	case isLong(t):
+		return fmt.Sprintf(`String(%s)`, v)
+	case t == descriptor.FieldDescriptorProto_TYPE_BYTES:
+		return fmt.Sprintf(`formatBytes(%s)`, v)
+	case fv.IsEnum && params.EnumsAsInts:
+		return fmt.Sprintf(`typeof %s === "number" ? %s : %sValues[%s]`, v, v, fv.Type, v)
+	case fv.IsEnum:
+		return fmt.Sprintf(`typeof %s === "number" ? %sNames[%s] : %s`, v, fv.Type, v, v)
+	case fv.IsTimestamp:
+		switch params.Timestamp {
+		case "date":
+			return fmt.Sprintf(`new Date(%s).toISOString()`, v)
+		case "object":
+			return fmt.Sprintf(`formatTimestamp(%s)`, v)
+		}
+		return ""
+	case fv.IsPlainJSON:
+		return wrapperToJSON(fv, v)
+	case t == descriptor.FieldDescriptorProto_TYPE_MESSAGE, t == descriptor.FieldDescriptorProto_TYPE_GROUP:
+		if functional() {
+			return fmt.Sprintf(`encode%s(%s)`, fv.Type, v)
+		}
+		return fmt.Sprintf(`(%s instanceof %s ? %s : new %s(%s)).toJSON()`, v, fv.Type, v, fv.Type, v)
+	}
+	return ""
+}
+
+// propertyValue returns the value of the property of a field set by the
+// constructor of message classes with class_style=properties, from the
+// member of m. Fields without presence get their zero value when absent.
+func propertyValue(fv fieldValues) string {
+	v := "m." + fv.Field
+	if fv.Alias != "" {
+		v = fmt.Sprintf("(m.%s !== undefined ? m.%s : m.%s)", fv.Field, fv.Field, fv.Alias)
+	}
+	switch {
+	case fv.Required:
+		return fmt.Sprintf("%s !== undefined ? %s : %s", v, v, zeroValue(fv))
+	case fv.IsRepeated, fv.IsMap:
+		return v + " || " + zeroValue(fv)
+	case fv.HasPresence || !hasZeroValue(fv):
+		return v
+	}
+	return fmt.Sprintf("%s != null ? %s : %s", v, v, zeroValue(fv))
+}
+
+// propertyToJSON returns the JSON member of the property of a field in toJSON
+// with class_style=properties. Zero values are left out like absent fields,
+// unless with emit_defaults.
+func propertyToJSON(fv fieldValues) string {
+	v := "this." + fv.Field
+	if params.EmitDefaults || fv.Required || fv.HasPresence || fv.IsRepeated || fv.IsMap || !hasZeroValue(fv) {
+		return v
+	}
+	return fmt.Sprintf("%s !== %s ? %s : undefined", v, zeroValue(fv), v)
+}
+
+// withMember returns the value of a field in the copy made by the with method
+// of immutable messages: its member in m when it's there, even undefined to
+// clear it, or its current value, absent without a zero value.
+func withMember(fv fieldValues) string {
+	current := "this." + fv.Field
+	if !properties() {
+		current = "this._json." + fv.Name
+	}
+	if fv.Alias != "" {
+		return fmt.Sprintf(`"%s" in m ? m.%s : "%s" in m ? m.%s : %s`, fv.Field, fv.Field, fv.Alias, fv.Alias, current)
+	}
+	return fmt.Sprintf(`"%s" in m ? m.%s : %s`, fv.Field, fv.Field, current)
+}
+
+// fieldClone returns a statement deeply copying the value of a field in
+// clone, or an empty string if it's immutable.
+func fieldClone(fv fieldValues) string {
+	v := fmt.Sprintf(`json["%s"]`, fv.Name)
+	switch {
+	case fv.IsMap:
+		conv := valueClone(*fv.MapValue, "v")
+		if conv == "" {
+			return fmt.Sprintf(`%s = %s({}, %s)`, v, assign(), v)
+		}
+		return fmt.Sprintf(`%s = mapValues(%s, (v: any) => %s)`, v, v, conv)
+	case fv.IsRepeated:
+		conv := valueClone(fv, "v")
+		if conv == "" {
+			return fmt.Sprintf(`%s = %s.slice()`, v, v)
+		}
+		return fmt.Sprintf(`%s = %s.map((v: any) => %s)`, v, v, conv)
+	}
+	if conv := valueClone(fv, v); conv != "" {
+		return v + " = " + conv
+	}
+	return ""
+}
+
+// valueClone returns an expression deeply copying a single value v of a
+// field, or an empty string for strings, numbers, booleans and enums.
+func valueClone(fv fieldValues, v string) string {
+	switch {
+	case fv.IsTimestamp:
+		switch params.Timestamp {
+		case "date":
+			return fmt.Sprintf(`new Date(%s)`, v)
+		case "object":
+			return fmt.Sprintf(`({ seconds: %s.seconds, nanos: %s.nanos })`, v, v)
+		}
+		return ""
+	case fv.IsPlainJSON:
+		if t, _ := plainJSONType(fv.TypeName); t == "string" || strings.HasSuffix(t, " | null") {
+			return ""
+		}
+		return fmt.Sprintf(`JSON.parse(JSON.stringify(%s))`, v)
+	case isMessageValue(fv):
+		return fmt.Sprintf(`(%s instanceof %s ? %s : new %s(%s)).clone()`, v, fv.Type, v, fv.Type, v)
+	}
+	return ""
+}
+
+// fieldEquals returns the condition comparing a field of the message in
+// equals with the one of o, through their getters.
+func fieldEquals(fv fieldValues) string {
+	a, b := "this."+fv.Field, "o."+fv.Field
+	if properties() && fv.HasPresence && !fv.IsOptional && hasZeroValue(fv) {
+		// Unset properties are undefined instead of their zero value.
+		a = fmt.Sprintf("(%s != null ? %s : %s)", a, a, zeroValue(fv))
+		b = fmt.Sprintf("(%s != null ? %s : %s)", b, b, zeroValue(fv))
+	}
+	switch {
+	case fv.IsMap:
+		return fmt.Sprintf(`mapEquals(%s, %s, (x, y) => %s)`, a, b, valueEquals(*fv.MapValue, "x", "y"))
+	case fv.IsRepeated:
+		return fmt.Sprintf(`repeatedEquals(%s, %s, (x, y) => %s)`, a, b, valueEquals(fv, "x", "y"))
+	}
+	eq := valueEquals(fv, a, b)
+	if (fv.IsOptional || !hasZeroValue(fv)) && eq != a+" === "+b {
+		// Unset messages and timestamps are undefined.
+		return fmt.Sprintf(`(%s == null || %s == null ? %s == %s : %s)`, a, b, a, b, eq)
+	}
+	return eq
+}
+
+// valueEquals returns the condition comparing single values x and y of a
+// field: 64-bit integers may be numbers or strings, floating point NaNs are
+// equal, Dates are compared by time and messages by their equals methods.
+func valueEquals(fv fieldValues, x, y string) string {
+	switch {
+	case fv.ProtoType == descriptor.FieldDescriptorProto_TYPE_DOUBLE, fv.ProtoType == descriptor.FieldDescriptorProto_TYPE_FLOAT,
+		fv.TypeName == ".google.protobuf.DoubleValue", fv.TypeName == ".google.protobuf.FloatValue":
+		return fmt.Sprintf(`(%s === %s || %s !== %s && %s !== %s)`, x, y, x, x, y, y)
+	case isLong(fv.ProtoType):
+		return fmt.Sprintf(`String(%s) === String(%s)`, x, y)
+	case fv.IsTimestamp && params.Timestamp == "date":
+		return fmt.Sprintf(`new Date(%s).getTime() === new Date(%s).getTime()`, x, y)
+	case fv.IsTimestamp && params.Timestamp == "object":
+		return fmt.Sprintf(`%s.seconds === %s.seconds && %s.nanos === %s.nanos`, x, y, x, y)
+	case fv.IsPlainJSON:
+		if t, _ := plainJSONType(fv.TypeName); t == "string" || strings.HasSuffix(t, " | null") {
+			break
+		}
+		return fmt.Sprintf(`JSON.stringify(%s) === JSON.stringify(%s)`, x, y)
+	case isMessageValue(fv):
+		return fmt.Sprintf(`(%s instanceof %s ? %s : new %s(%s)).equals(%s)`, x, fv.Type, x, fv.Type, x, y)
+	}
+	return fmt.Sprintf(`%s === %s`, x, y)
+}
+
+// wrapperToJSON converts the values of wrappers like their wrapped fields,
+// keeping null.
+func wrapperToJSON(fv fieldValues, v string) string {
+	var conv string
+	switch fv.TypeName {
+	case ".google.protobuf.DoubleValue", ".google.protobuf.FloatValue":
+		conv = fmt.Sprintf(`isFinite(%s) ? %s : String(%s)`, v, v, v)
+	case ".google.protobuf.Int64Value", ".google.protobuf.UInt64Value":
+		conv = fmt.Sprintf(`String(%s)`, v)
+	case ".google.protobuf.BytesValue":
+		conv = fmt.Sprintf(`formatBytes(%s)`, v)
+	default:
+		return ""
+	}
+	return fmt.Sprintf(`%s == null ? null : %s`, v, conv)
+}
+
+// partialToField returns the value of a field in fromPartial, read from the
+// member of the deep partial m: missing members get their zero value and
+// nested messages are built from their partials.
+func partialToField(fv fieldValues) string {
+	v := "m." + fv.Field
+	switch {
+	case fv.IsMap && isMessageValue(*fv.MapValue):
+		return fmt.Sprintf(`mapValues(%s, v => %s.fromPartial(v))`, v, fv.MapValue.Type)
+	case fv.IsMap:
+		return fmt.Sprintf(`<%s>(%s || {})`, fieldType(&fv), v)
+	case fv.IsRepeated && isMessageValue(fv):
+		return fmt.Sprintf(`(%s || []).map(v => %s.fromPartial(v))`, v, fv.Type)
+	case fv.IsRepeated:
+		return fmt.Sprintf(`%s || []`, v)
+	case isMessageValue(fv):
+		return fmt.Sprintf(`%s == null ? undefined : %s.fromPartial(%s)`, v, fv.Type, v)
+	}
+	return createField(fv)
+}
+
+// createField returns the value of a field in create, the member of m or
+// the zero value of the field when it's missing. The members of oneofs stay
+// unset, setting them all would send several members of the oneof.
+func createField(fv fieldValues) string {
+	v := "m." + fv.Field
+	if fv.IsOptional || fv.IsOneof || !fv.IsRepeated && !fv.IsMap && !hasZeroValue(fv) {
+		return v
+	}
+	return fmt.Sprintf(`%s != null ? %s : %s`, v, v, zeroValue(fv))
+}
+
+// isMessageValue reports whether values of fv are instances of message
+// classes, unlike timestamps and plain JSON well-known types.
+func isMessageValue(fv fieldValues) bool {
+	return (fv.ProtoType == descriptor.FieldDescriptorProto_TYPE_MESSAGE || fv.ProtoType == descriptor.FieldDescriptorProto_TYPE_GROUP) && !fv.IsPlainJSON && !fv.IsTimestamp
+}
+
+func presenceGuard(fv fieldValues) string {
+	return fmt.Sprintf(`m["%s"] == null ? undefined : `, fv.Name)
+}
+
+// convertsMapValues reports whether the code generated for a map field
+// converts its values with mapValues.
+func convertsMapValues(fv fieldValues) bool {
+	var conv string
+	switch {
+	case generateClasses():
+		conv = mapToField(fv) + fieldToJSON(fv) + fieldClone(fv) + partialToField(fv)
+	case functional():
+		conv = mapToField(fv) + fieldToJSON(fv)
 	}
+	return strings.Contains(conv, "mapValues(")
+}
 
-	return fmt.Sprintf(`%s.fromJSON(m["%s"]!)`, t, fv.Name)
+// mapToField converts the values of a map field in fromJSON with the shared
+// mapValues of twirp.ts.
+func mapToField(fv fieldValues) string {
+	value := fv.MapValue
+
+	var conv string
+	switch t := value.Type; {
+	case isFloat(value.ProtoType):
+		conv = "Number"
+	case value.IsTimestamp && params.Timestamp != "string":
+		conv = "v => " + timestampFromJSON("v")
+	case t == "string", t == "number", t == "boolean", isBranded(value.ProtoType), value.IsTimestamp, value.IsPlainJSON:
+		return fmt.Sprintf(`m["%s"]`, fv.Name)
+	case value.IsEnum:
+		conv = "v => " + enumFromJSON(t, "v")
+	default:
+		conv = "v => " + messageFromJSON(t, "v")
+	}
+	return fmt.Sprintf(`mapValues(m["%s"], %s)`, fv.Name, conv)
 }
 
 func typeToInterface(typeName string) string {
 	return "I" + typeName
 }
 
+func typeToReadonlyInterface(typeName string) string {
+	return "ReadonlyI" + typeName
+}
+
 func typeToJSONInterface(typeName string) string {
 	return "I" + typeName + "JSON"
 }
 
 func methodName(method string) string {
-	return strings.ToLower(method[0:1]) + method[1:]
+	return safeIdentifier(strings.ToLower(method[0:1]) + method[1:])
+}
+
+// namespaceValues is a namespace aliasing the generated types by their proto
+// names, see the namespaces parameter. Messages with nested types are merged
+// with a namespace of them, and package indexes export a namespace of the
+// package, e.g. foo.bar.Item.Inner for the Item_Inner class.
+type namespaceValues struct {
+	Name    string
+	Members []*namespaceMember
+}
+
+// namespaceMember aliases Type as Name, import aliases keep the values and
+// namespaces of types along with their types.
+type namespaceMember struct {
+	Name string
+	Type string
+}
+
+var namespaceTemplate = `
+export namespace {{.Name}} {
+  {{- range .Members}}
+  export import {{.Name}} = {{.Type}};
+  {{- end}}
+}
+`
+
+func (nv *namespaceValues) Compile() (string, error) {
+	return compileAndExecute(namespaceTemplate, nv)
+}
+
+// namespaceMemberOf returns the namespace member aliasing the generated type name of a
+// proto type by the last part of its proto name.
+func namespaceMemberOf(protoName, name string) *namespaceMember {
+	return &namespaceMember{
+		Name: safeIdentifier(protoName[strings.LastIndex(protoName, ".")+1:]),
+		Type: name,
+	}
 }
 
 type exportValues struct {
-	Exports []string
+	Files []*exportFile
+	// Imports and Namespaces are the imports of the generated types and the
+	// namespaces of the packages of the index.
+	Imports    []*importValues
+	Namespaces []*namespaceValues
 }
 
-const exportTemplate = `
-/* eslint-disable */
+var exportTemplate = `
+{{with lintHeader}}{{.}}
 
-// This file has been generated by https://github.com/horizon-games/protoc-gen-twirp_ts.
-// Do not edit.
+{{end -}}
+{{with banner ""}}{{.}}
 
-{{range $i, $e := .Exports -}}
-export * from "./{{$e}}";
+{{end -}}
+{{range .Files -}}
+{{if .Names -}}
+export {
+  {{- range $i, $n := .Names}}
+  {{- if $i}},{{end}}
+  {{$n}}
+  {{- end}}
+} from "./{{.Path}}{{jsExtension}}";
+{{end -}}
+{{if .Types -}}
+export type {
+  {{- range $i, $n := .Types}}
+  {{- if $i}},{{end}}
+  {{$n}}
+  {{- end}}
+} from "./{{.Path}}{{jsExtension}}";
+{{end -}}
+{{end -}}
+{{with .Imports}}
+{{range . -}}
+{{. | compile}}
+{{end -}}
+{{end -}}
+{{range .Namespaces}}
+{{. | compile}}
 {{end -}}
 `
 
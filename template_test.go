@@ -0,0 +1,32 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+)
+
+func TestPartialToField(t *testing.T) {
+	params = defaultParameters()
+	str := descriptor.FieldDescriptorProto_TYPE_STRING
+	msg := descriptor.FieldDescriptorProto_TYPE_MESSAGE
+	tests := []struct {
+		name string
+		fv   fieldValues
+		want string
+	}{
+		{"scalar", fieldValues{Field: "name", Type: "string", ProtoType: str}, `m.name != null ? m.name : ""`},
+		{"optional", fieldValues{Field: "nickname", Type: "string", ProtoType: str, IsOptional: true, HasPresence: true}, `m.nickname`},
+		{"oneof scalar", fieldValues{Field: "phone", Type: "string", ProtoType: str, IsOneof: true, HasPresence: true}, `m.phone`},
+		{"oneof message", fieldValues{Field: "address", Type: "Address", ProtoType: msg, IsOneof: true, HasPresence: true}, `m.address == null ? undefined : Address.fromPartial(m.address)`},
+		{"repeated", fieldValues{Field: "emails", Type: "string", ProtoType: str, IsRepeated: true}, `m.emails || []`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := partialToField(tt.fv); got != tt.want {
+				t.Errorf("partialToField(%s) = %s, want %s", tt.fv.Field, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,7 @@
+#!/bin/sh
+# Generates the descriptor sets of the .proto files of testdata read by the
+# tests, run by go generate.
+cd "$(dirname "$0")" || exit 1
+for f in $(find . -name '*.proto' | sed 's|^\./||'); do
+	protoc --include_imports --include_source_info -o "${f%.proto}.pb" "$f" || exit 1
+done
@@ -0,0 +1,502 @@
+/* eslint-disable */
+
+// This file has been generated by https://github.com/horizon-games/protoc-gen-twirp_ts.
+// Do not edit.
+//
+// versions:
+//   protoc-gen-twirp_ts (unknown)
+//   protoc (unknown)
+// source: batch.proto
+
+import { Batcher, CallOptions, clientFetch, ClientOptions, createTwirpRequest, DeepPartial, defaultFetch, Extension, Fetch, mergeOptions, preconnect, readTwirpResponse, repeatedEquals, resolveBaseURL, throwTwirpError, twirpCall } from "../../twirp";
+
+export interface IGetItemRequest {
+  id?: string;
+
+  toJSON?(): object;
+}
+
+export interface IGetItemRequestJSON {
+  id?: string;
+  toJSON?(): object;
+}
+
+export class GetItemRequest implements IGetItemRequest {
+  private _json: IGetItemRequestJSON;
+
+  constructor(m?: IGetItemRequest) {
+    this._json = {};
+    if (m) {
+      this._json["id"] = m.id;
+    }
+  }
+
+  // id (id)
+  public get id(): string {
+    return this._json.id != null ? this._json.id : "";
+  }
+  public set id(value: string) {
+    this._json.id = value;
+  }
+
+  static fromJSON(m: IGetItemRequestJSON = {}): GetItemRequest {
+    const v = new GetItemRequest({
+      id: m["id"]
+    });
+    // Preserve unknown fields (and extensions) so they survive a round-trip.
+    const known: string[] = ["id"];
+    Object.keys(m).forEach(k => {
+      if (known.indexOf(k) < 0) {
+        (<any>v._json)[k] = (<any>m)[k];
+      }
+    });
+    return v;
+  }
+
+  // create returns a GetItemRequest with the members set in m and the zero values
+  // of the other fields, nested messages and oneof members are left
+  // undefined.
+  static create(m: Partial<IGetItemRequest> = {}): GetItemRequest {
+    return new GetItemRequest({
+      id: m.id != null ? m.id : ""
+    });
+  }
+
+  // fromPartial builds a GetItemRequest from the members that are set in m,
+  // filling zero values for the others, except oneof members, and building
+  // nested messages.
+  static fromPartial(m: DeepPartial<IGetItemRequest> = {}): GetItemRequest {
+    return new GetItemRequest({
+      id: m.id != null ? m.id : ""
+    });
+  }
+
+  public toJSON(): object {
+    return this._json;
+  }
+
+  // clone returns a deep copy of the message sharing no state with it, the
+  // nested messages are cloned by their classes.
+  public clone(): GetItemRequest {
+    const json: any = Object.assign({}, this._json);
+    const m = new GetItemRequest();
+    m._json = json;
+    return m;
+  }
+
+  // equals reports whether other has the same field values, unset fields
+  // being equal to their zero values.
+  public equals(other: IGetItemRequest): boolean {
+    const o = other instanceof GetItemRequest ? other : new GetItemRequest(other);
+    return this.id === o.id;
+  }
+}
+
+export interface IItem {
+  id?: string;
+  name?: string;
+
+  toJSON?(): object;
+}
+
+export interface IItemJSON {
+  id?: string;
+  name?: string;
+  toJSON?(): object;
+}
+
+export class Item implements IItem {
+  private _json: IItemJSON;
+
+  constructor(m?: IItem) {
+    this._json = {};
+    if (m) {
+      this._json["id"] = m.id;
+      this._json["name"] = m.name;
+    }
+  }
+
+  // id (id)
+  public get id(): string {
+    return this._json.id != null ? this._json.id : "";
+  }
+  public set id(value: string) {
+    this._json.id = value;
+  }
+
+  // name (name)
+  public get name(): string {
+    return this._json.name != null ? this._json.name : "";
+  }
+  public set name(value: string) {
+    this._json.name = value;
+  }
+
+  static fromJSON(m: IItemJSON = {}): Item {
+    const v = new Item({
+      id: m["id"],
+      name: m["name"]
+    });
+    // Preserve unknown fields (and extensions) so they survive a round-trip.
+    const known: string[] = ["id", "name"];
+    Object.keys(m).forEach(k => {
+      if (known.indexOf(k) < 0) {
+        (<any>v._json)[k] = (<any>m)[k];
+      }
+    });
+    return v;
+  }
+
+  // create returns a Item with the members set in m and the zero values
+  // of the other fields, nested messages and oneof members are left
+  // undefined.
+  static create(m: Partial<IItem> = {}): Item {
+    return new Item({
+      id: m.id != null ? m.id : "",
+      name: m.name != null ? m.name : ""
+    });
+  }
+
+  // fromPartial builds a Item from the members that are set in m,
+  // filling zero values for the others, except oneof members, and building
+  // nested messages.
+  static fromPartial(m: DeepPartial<IItem> = {}): Item {
+    return new Item({
+      id: m.id != null ? m.id : "",
+      name: m.name != null ? m.name : ""
+    });
+  }
+
+  public toJSON(): object {
+    return this._json;
+  }
+
+  // clone returns a deep copy of the message sharing no state with it, the
+  // nested messages are cloned by their classes.
+  public clone(): Item {
+    const json: any = Object.assign({}, this._json);
+    const m = new Item();
+    m._json = json;
+    return m;
+  }
+
+  // equals reports whether other has the same field values, unset fields
+  // being equal to their zero values.
+  public equals(other: IItem): boolean {
+    const o = other instanceof Item ? other : new Item(other);
+    return (
+      this.id === o.id &&
+      this.name === o.name
+    );
+  }
+}
+
+export interface IBatchGetItemsRequest {
+  requests?: GetItemRequest[];
+
+  toJSON?(): object;
+}
+
+export interface IBatchGetItemsRequestJSON {
+  requests?: GetItemRequest[];
+  toJSON?(): object;
+}
+
+export class BatchGetItemsRequest implements IBatchGetItemsRequest {
+  private _json: IBatchGetItemsRequestJSON;
+
+  constructor(m?: IBatchGetItemsRequest) {
+    this._json = {};
+    if (m) {
+      this._json["requests"] = m.requests;
+    }
+  }
+
+  // requests (requests)
+  public get requests(): GetItemRequest[] {
+    return this._json.requests || [];
+  }
+  public set requests(value: GetItemRequest[]) {
+    this._json.requests = value;
+  }
+
+  static fromJSON(m: IBatchGetItemsRequestJSON = {}): BatchGetItemsRequest {
+    const v = new BatchGetItemsRequest({
+      requests: (m["requests"] || []).map(v => GetItemRequest.fromJSON(v))
+    });
+    // Preserve unknown fields (and extensions) so they survive a round-trip.
+    const known: string[] = ["requests"];
+    Object.keys(m).forEach(k => {
+      if (known.indexOf(k) < 0) {
+        (<any>v._json)[k] = (<any>m)[k];
+      }
+    });
+    return v;
+  }
+
+  // create returns a BatchGetItemsRequest with the members set in m and the zero values
+  // of the other fields, nested messages and oneof members are left
+  // undefined.
+  static create(m: Partial<IBatchGetItemsRequest> = {}): BatchGetItemsRequest {
+    return new BatchGetItemsRequest({
+      requests: m.requests != null ? m.requests : []
+    });
+  }
+
+  // fromPartial builds a BatchGetItemsRequest from the members that are set in m,
+  // filling zero values for the others, except oneof members, and building
+  // nested messages.
+  static fromPartial(m: DeepPartial<IBatchGetItemsRequest> = {}): BatchGetItemsRequest {
+    return new BatchGetItemsRequest({
+      requests: (m.requests || []).map(v => GetItemRequest.fromPartial(v))
+    });
+  }
+
+  public toJSON(): object {
+    const json: any = Object.assign({}, this._json);
+    if (json["requests"] != null) {
+      json["requests"] = json["requests"].map((v: any) => (v instanceof GetItemRequest ? v : new GetItemRequest(v)).toJSON());
+    }
+    return json;
+  }
+
+  // clone returns a deep copy of the message sharing no state with it, the
+  // nested messages are cloned by their classes.
+  public clone(): BatchGetItemsRequest {
+    const json: any = Object.assign({}, this._json);
+    if (json["requests"] != null) {
+      json["requests"] = json["requests"].map((v: any) => (v instanceof GetItemRequest ? v : new GetItemRequest(v)).clone());
+    }
+    const m = new BatchGetItemsRequest();
+    m._json = json;
+    return m;
+  }
+
+  // equals reports whether other has the same field values, unset fields
+  // being equal to their zero values.
+  public equals(other: IBatchGetItemsRequest): boolean {
+    const o = other instanceof BatchGetItemsRequest ? other : new BatchGetItemsRequest(other);
+    return repeatedEquals(this.requests, o.requests, (x, y) => (x instanceof GetItemRequest ? x : new GetItemRequest(x)).equals(y));
+  }
+}
+
+export interface IBatchGetItemsResponse {
+  items?: Item[];
+
+  toJSON?(): object;
+}
+
+export interface IBatchGetItemsResponseJSON {
+  items?: Item[];
+  toJSON?(): object;
+}
+
+export class BatchGetItemsResponse implements IBatchGetItemsResponse {
+  private _json: IBatchGetItemsResponseJSON;
+
+  constructor(m?: IBatchGetItemsResponse) {
+    this._json = {};
+    if (m) {
+      this._json["items"] = m.items;
+    }
+  }
+
+  // items (items)
+  public get items(): Item[] {
+    return this._json.items || [];
+  }
+  public set items(value: Item[]) {
+    this._json.items = value;
+  }
+
+  static fromJSON(m: IBatchGetItemsResponseJSON = {}): BatchGetItemsResponse {
+    const v = new BatchGetItemsResponse({
+      items: (m["items"] || []).map(v => Item.fromJSON(v))
+    });
+    // Preserve unknown fields (and extensions) so they survive a round-trip.
+    const known: string[] = ["items"];
+    Object.keys(m).forEach(k => {
+      if (known.indexOf(k) < 0) {
+        (<any>v._json)[k] = (<any>m)[k];
+      }
+    });
+    return v;
+  }
+
+  // create returns a BatchGetItemsResponse with the members set in m and the zero values
+  // of the other fields, nested messages and oneof members are left
+  // undefined.
+  static create(m: Partial<IBatchGetItemsResponse> = {}): BatchGetItemsResponse {
+    return new BatchGetItemsResponse({
+      items: m.items != null ? m.items : []
+    });
+  }
+
+  // fromPartial builds a BatchGetItemsResponse from the members that are set in m,
+  // filling zero values for the others, except oneof members, and building
+  // nested messages.
+  static fromPartial(m: DeepPartial<IBatchGetItemsResponse> = {}): BatchGetItemsResponse {
+    return new BatchGetItemsResponse({
+      items: (m.items || []).map(v => Item.fromPartial(v))
+    });
+  }
+
+  public toJSON(): object {
+    const json: any = Object.assign({}, this._json);
+    if (json["items"] != null) {
+      json["items"] = json["items"].map((v: any) => (v instanceof Item ? v : new Item(v)).toJSON());
+    }
+    return json;
+  }
+
+  // clone returns a deep copy of the message sharing no state with it, the
+  // nested messages are cloned by their classes.
+  public clone(): BatchGetItemsResponse {
+    const json: any = Object.assign({}, this._json);
+    if (json["items"] != null) {
+      json["items"] = json["items"].map((v: any) => (v instanceof Item ? v : new Item(v)).clone());
+    }
+    const m = new BatchGetItemsResponse();
+    m._json = json;
+    return m;
+  }
+
+  // equals reports whether other has the same field values, unset fields
+  // being equal to their zero values.
+  public equals(other: IBatchGetItemsResponse): boolean {
+    const o = other instanceof BatchGetItemsResponse ? other : new BatchGetItemsResponse(other);
+    return repeatedEquals(this.items, o.items, (x, y) => (x instanceof Item ? x : new Item(x)).equals(y));
+  }
+}
+
+// Extensions
+export const batch: Extension<object, string> = {
+  name: "[acme.batch.batch]",
+  fieldNumber: 50200,
+  fromJSON: (m: any) => m["[acme.batch.batch]"]
+};
+
+// Services
+// ItemsGetItemRequest and ItemsGetItemResponse are the input
+// and result types of Items.GetItem.
+export type ItemsGetItemRequest = GetItemRequest;
+export type ItemsGetItemResponse = Item;
+
+// ItemsBatchGetItemsRequest and ItemsBatchGetItemsResponse are the input
+// and result types of Items.BatchGetItems.
+export type ItemsBatchGetItemsRequest = BatchGetItemsRequest;
+export type ItemsBatchGetItemsResponse = BatchGetItemsResponse;
+
+// ItemsMethods maps the Items methods to their input and output
+// types, for generic wrappers over the service. The output of streaming
+// methods is the type of their messages.
+export type ItemsMethods = {
+  getItem: { input: ItemsGetItemRequest; output: ItemsGetItemResponse };
+  batchGetItems: { input: ItemsBatchGetItemsRequest; output: ItemsBatchGetItemsResponse };
+};
+
+export interface IItems {
+  getItem: (
+    data: GetItemRequest,
+    headers?: object,
+    options?: CallOptions
+  ) => Promise<Item>;
+  batchGetItems: (
+    data: BatchGetItemsRequest,
+    headers?: object,
+    options?: CallOptions
+  ) => Promise<BatchGetItemsResponse>;
+}
+
+export class Items implements IItems {
+  private hostname: string;
+  private fetch: Fetch;
+  private options: ClientOptions;
+  private path: string;
+  private batchers: { [method: string]: Batcher<any, any> } = {};
+
+  static methodOptions: { [method: string]: { [name: string]: any } } = {
+    GetItem: {
+      "acme.batch.batch": "BatchGetItems"
+    }
+  };
+
+  constructor(options: ClientOptions);
+  constructor(hostname: string, fetch: Fetch, options?: ClientOptions);
+  constructor(hostname: string | ClientOptions, fetch?: Fetch, options: ClientOptions = {}) {
+    if (typeof hostname !== "string") {
+      options = hostname;
+      hostname = options.baseURL == null ? resolveBaseURL("acme.batch.Items") : options.baseURL;
+    }
+    this.hostname = hostname;
+    this.fetch = clientFetch(options, fetch || options.fetch || defaultFetch);
+    this.options = options;
+    this.path = (options.pathPrefix == null ? "/twirp" : options.pathPrefix) + "/acme.batch.Items/";
+  }
+
+  // warmup opens a connection to the server ahead of the first call, see
+  // preconnect.
+  public warmup(): void {
+    preconnect(this.hostname, this.options);
+  }
+
+  private url(name: string): string {
+    return this.hostname + this.path + name;
+  }
+
+  private fetchFor(options: CallOptions): Fetch {
+    return options.fetch ? clientFetch(this.options, options.fetch) : this.fetch;
+  }
+
+  private batcher<Q, R>(method: string, send: (requests: Q[]) => Promise<ReadonlyArray<R>>): Batcher<Q, R> {
+    if (!this.batchers[method]) {
+      this.batchers[method] = new Batcher(this.options.batch || {}, send);
+    }
+    return this.batchers[method];
+  }
+
+  public getItem(
+    params: GetItemRequest,
+    headers: object = {},
+    options: CallOptions = {}
+  ): Promise<Item> {
+    if (this.options.batch && Object.keys(headers).length === 0 && Object.keys(options).length === 0) {
+      return this.batcher("GetItem", (requests: GetItemRequest[]) =>
+        this.batchGetItems(new BatchGetItemsRequest({ requests: requests })).then(res => res.items || [])
+      ).call(params);
+    }
+    return twirpCall(mergeOptions(this.options, "GetItem", options), callOptions =>
+      this.fetchFor(callOptions)(
+        this.url("GetItem"),
+        createTwirpRequest(params, headers, callOptions)
+      ).then(res => {
+        if (!res.ok) {
+          return throwTwirpError(res);
+        }
+        return readTwirpResponse(res, callOptions).then(m => {
+          return Item.fromJSON(m);
+        });
+      })
+    );
+  }
+
+  public batchGetItems(
+    params: BatchGetItemsRequest,
+    headers: object = {},
+    options: CallOptions = {}
+  ): Promise<BatchGetItemsResponse> {
+    return twirpCall(mergeOptions(this.options, "BatchGetItems", options), callOptions =>
+      this.fetchFor(callOptions)(
+        this.url("BatchGetItems"),
+        createTwirpRequest(params, headers, callOptions)
+      ).then(res => {
+        if (!res.ok) {
+          return throwTwirpError(res);
+        }
+        return readTwirpResponse(res, callOptions).then(m => {
+          return BatchGetItemsResponse.fromJSON(m);
+        });
+      })
+    );
+  }
+}
@@ -0,0 +1,33 @@
+/* eslint-disable */
+
+// This file has been generated by https://github.com/horizon-games/protoc-gen-twirp_ts.
+// Do not edit.
+//
+// versions:
+//   protoc-gen-twirp_ts (unknown)
+//   protoc (unknown)
+
+export {
+  GetItemRequest,
+  Item,
+  BatchGetItemsRequest,
+  BatchGetItemsResponse,
+  batch,
+  Items
+} from "./batch";
+export type {
+  IGetItemRequest,
+  IGetItemRequestJSON,
+  IItem,
+  IItemJSON,
+  IBatchGetItemsRequest,
+  IBatchGetItemsRequestJSON,
+  IBatchGetItemsResponse,
+  IBatchGetItemsResponseJSON,
+  IItems,
+  ItemsMethods,
+  ItemsGetItemRequest,
+  ItemsGetItemResponse,
+  ItemsBatchGetItemsRequest,
+  ItemsBatchGetItemsResponse
+} from "./batch";
@@ -0,0 +1,21 @@
+/* eslint-disable */
+
+// This file has been generated by https://github.com/horizon-games/protoc-gen-twirp_ts.
+// Do not edit.
+//
+// versions:
+//   protoc-gen-twirp_ts (unknown)
+//   protoc (unknown)
+// source: users.proto
+
+import { User_Role } from "./users";
+
+export const User_RoleValues: { [name: string]: number } = {
+  MEMBER: 0,
+  ADMIN: 1
+};
+
+export const User_RoleNames: { [value: number]: User_Role } = {
+  0: <User_Role>"MEMBER",
+  1: <User_Role>"ADMIN"
+};
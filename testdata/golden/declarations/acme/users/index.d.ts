@@ -0,0 +1,22 @@
+/* eslint-disable */
+
+// This file has been generated by https://github.com/horizon-games/protoc-gen-twirp_ts.
+// Do not edit.
+//
+// versions:
+//   protoc-gen-twirp_ts (unknown)
+//   protoc (unknown)
+
+export {
+  User_Role
+} from "./users";
+export type {
+  IUser,
+  IUserJSON,
+  IGetUserRequest,
+  IGetUserRequestJSON,
+  IListUsersRequest,
+  IListUsersRequestJSON,
+  IListUsersResponse,
+  IListUsersResponseJSON
+} from "./users";
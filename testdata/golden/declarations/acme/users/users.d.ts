@@ -0,0 +1,81 @@
+/* eslint-disable */
+
+// This file has been generated by https://github.com/horizon-games/protoc-gen-twirp_ts.
+// Do not edit.
+//
+// versions:
+//   protoc-gen-twirp_ts (unknown)
+//   protoc (unknown)
+// source: users.proto
+
+/**
+ * User is a registered user.
+ */
+export interface IUser {
+  id?: string;
+  name?: string;
+  balance?: number;
+  score?: number;
+  created?: string;
+  labels?: { [key: string]: string };
+  emails?: string[];
+  nickname?: string | undefined;
+  role?: User_Role;
+  phone?: string;
+  fax?: string;
+
+  toJSON?(): object;
+}
+
+export const enum User_Role {
+  MEMBER = "MEMBER",
+  ADMIN = "ADMIN"
+}
+
+export interface IUserJSON {
+  id?: string;
+  name?: string;
+  balance?: number;
+  score?: number;
+  created?: string;
+  labels?: { [key: string]: string };
+  emails?: string[];
+  nickname?: string | undefined;
+  role?: User_Role;
+  phone?: string;
+  fax?: string;
+  toJSON?(): object;
+}
+
+export interface IGetUserRequest {
+  id?: string;
+
+  toJSON?(): object;
+}
+
+export interface IGetUserRequestJSON {
+  id?: string;
+  toJSON?(): object;
+}
+
+export interface IListUsersRequest {
+  pageSize?: number;
+
+  toJSON?(): object;
+}
+
+export interface IListUsersRequestJSON {
+  page_size?: number;
+  toJSON?(): object;
+}
+
+export interface IListUsersResponse {
+  users?: IUser[];
+
+  toJSON?(): object;
+}
+
+export interface IListUsersResponseJSON {
+  users?: IUserJSON[];
+  toJSON?(): object;
+}
@@ -0,0 +1,674 @@
+/* eslint-disable */
+
+// This file has been generated by https://github.com/horizon-games/protoc-gen-twirp_ts.
+// Do not edit.
+//
+// versions:
+//   protoc-gen-twirp_ts (unknown)
+//   protoc (unknown)
+// source: users.proto
+
+import { cachedCall, CallOptions, clientFetch, ClientOptions, createTwirpRequest, DeepPartial, defaultFetch, Fetch, mapEquals, mergeOptions, preconnect, readTwirpResponse, repeatedEquals, resolveBaseURL, throwTwirpError, twirpCall } from "../../twirp";
+
+/**
+ * User is a registered user.
+ */
+export interface IUser {
+  id?: string;
+  name?: string;
+  balance?: number;
+  score?: number;
+  created?: string;
+  labels?: { [key: string]: string };
+  emails?: string[];
+  nickname?: string | undefined;
+  role?: User_Role;
+  phone?: string;
+  fax?: string;
+
+  toJSON?(): object;
+}
+
+export enum User_Role {
+  MEMBER = "MEMBER",
+  ADMIN = "ADMIN"
+}
+
+export const User_RoleValues: { [name: string]: number } = {
+  MEMBER: 0,
+  ADMIN: 1
+};
+
+export const User_RoleNames: { [value: number]: User_Role } = {
+  0: User_Role.MEMBER,
+  1: User_Role.ADMIN
+};
+
+export interface IUserJSON {
+  id?: string;
+  name?: string;
+  balance?: number;
+  score?: number;
+  created?: string;
+  labels?: { [key: string]: string };
+  emails?: string[];
+  nickname?: string | undefined;
+  role?: User_Role;
+  phone?: string;
+  fax?: string;
+  toJSON?(): object;
+}
+
+/**
+ * User is a registered user.
+ */
+export class User implements IUser {
+  private _json: IUserJSON;
+
+  constructor(m?: IUser) {
+    this._json = {};
+    if (m) {
+      this._json["id"] = m.id;
+      this._json["name"] = m.name;
+      this._json["balance"] = m.balance;
+      this._json["score"] = m.score;
+      this._json["created"] = m.created;
+      this._json["labels"] = m.labels;
+      this._json["emails"] = m.emails;
+      this._json["nickname"] = m.nickname;
+      this._json["role"] = m.role;
+      this._json["phone"] = m.phone;
+      this._json["fax"] = m.fax;
+    }
+  }
+
+  // id (id)
+  public get id(): string {
+    return this._json.id != null ? this._json.id : "";
+  }
+  public set id(value: string) {
+    this._json.id = value;
+  }
+
+  // name (name)
+  public get name(): string {
+    return this._json.name != null ? this._json.name : "";
+  }
+  public set name(value: string) {
+    this._json.name = value;
+  }
+
+  // balance (balance)
+  public get balance(): number {
+    return this._json.balance != null ? this._json.balance : 0;
+  }
+  public set balance(value: number) {
+    this._json.balance = value;
+  }
+
+  // score (score)
+  public get score(): number {
+    return this._json.score != null ? this._json.score : 0;
+  }
+  public set score(value: number) {
+    this._json.score = value;
+  }
+
+  // created (created)
+  public get created(): string | undefined {
+    return this._json.created;
+  }
+  public set created(value: string | undefined) {
+    this._json.created = value;
+  }
+  public hasCreated(): boolean {
+    return this._json.created != null;
+  }
+  public clearCreated() {
+    delete this._json.created;
+  }
+
+  // labels (labels)
+  public get labels(): { [key: string]: string } {
+    return this._json.labels || {};
+  }
+  public set labels(value: { [key: string]: string }) {
+    this._json.labels = value;
+  }
+
+  // emails (emails)
+  public get emails(): string[] {
+    return this._json.emails || [];
+  }
+  public set emails(value: string[]) {
+    this._json.emails = value;
+  }
+
+  // nickname (nickname)
+  public get nickname(): string | undefined {
+    return this._json.nickname;
+  }
+  public set nickname(value: string | undefined) {
+    this._json.nickname = value;
+  }
+  public hasNickname(): boolean {
+    return this._json.nickname != null;
+  }
+  public clearNickname() {
+    delete this._json.nickname;
+  }
+
+  // role (role)
+  public get role(): User_Role {
+    return this._json.role != null ? this._json.role : User_Role.MEMBER;
+  }
+  public set role(value: User_Role) {
+    this._json.role = value;
+  }
+
+  // phone (phone)
+  public get phone(): string {
+    return this._json.phone != null ? this._json.phone : "";
+  }
+  public set phone(value: string) {
+    this._json.phone = value;
+  }
+  public hasPhone(): boolean {
+    return this._json.phone != null;
+  }
+  public clearPhone() {
+    delete this._json.phone;
+  }
+
+  // fax (fax)
+  public get fax(): string {
+    return this._json.fax != null ? this._json.fax : "";
+  }
+  public set fax(value: string) {
+    this._json.fax = value;
+  }
+  public hasFax(): boolean {
+    return this._json.fax != null;
+  }
+  public clearFax() {
+    delete this._json.fax;
+  }
+
+  static fromJSON(m: IUserJSON = {}): User {
+    const v = new User({
+      id: m["id"],
+      name: m["name"],
+      balance: m["balance"],
+      score: m["score"] == null ? undefined : Number(m["score"]),
+      created: m["created"],
+      labels: m["labels"],
+      emails: (m["emails"] || []).map(String),
+      nickname: m["nickname"] == null ? undefined : m["nickname"],
+      role: (<any>User_Role)[<any>m["role"]] || User_RoleNames[<any>m["role"]],
+      phone: m["phone"],
+      fax: m["fax"]
+    });
+    // Preserve unknown fields (and extensions) so they survive a round-trip.
+    const known: string[] = ["id", "name", "balance", "score", "created", "labels", "emails", "nickname", "role", "phone", "fax"];
+    Object.keys(m).forEach(k => {
+      if (known.indexOf(k) < 0) {
+        (<any>v._json)[k] = (<any>m)[k];
+      }
+    });
+    return v;
+  }
+
+  // create returns a User with the members set in m and the zero values
+  // of the other fields, nested messages and oneof members are left
+  // undefined.
+  static create(m: Partial<IUser> = {}): User {
+    return new User({
+      id: m.id != null ? m.id : "",
+      name: m.name != null ? m.name : "",
+      balance: m.balance != null ? m.balance : 0,
+      score: m.score != null ? m.score : 0,
+      created: m.created,
+      labels: m.labels != null ? m.labels : {},
+      emails: m.emails != null ? m.emails : [],
+      nickname: m.nickname,
+      role: m.role != null ? m.role : User_Role.MEMBER,
+      phone: m.phone,
+      fax: m.fax
+    });
+  }
+
+  // fromPartial builds a User from the members that are set in m,
+  // filling zero values for the others, except oneof members, and building
+  // nested messages.
+  static fromPartial(m: DeepPartial<IUser> = {}): User {
+    return new User({
+      id: m.id != null ? m.id : "",
+      name: m.name != null ? m.name : "",
+      balance: m.balance != null ? m.balance : 0,
+      score: m.score != null ? m.score : 0,
+      created: m.created,
+      labels: <{ [key: string]: string }>(m.labels || {}),
+      emails: m.emails || [],
+      nickname: m.nickname,
+      role: m.role != null ? m.role : User_Role.MEMBER,
+      phone: m.phone,
+      fax: m.fax
+    });
+  }
+
+  public toJSON(): object {
+    const json: any = Object.assign({}, this._json);
+    if (json["balance"] != null) {
+      json["balance"] = String(json["balance"]);
+    }
+    if (json["score"] != null) {
+      json["score"] = isFinite(json["score"]) ? json["score"] : String(json["score"]);
+    }
+    if (json["role"] != null) {
+      json["role"] = typeof json["role"] === "number" ? User_RoleNames[json["role"]] : json["role"];
+    }
+    return json;
+  }
+
+  // clone returns a deep copy of the message sharing no state with it, the
+  // nested messages are cloned by their classes.
+  public clone(): User {
+    const json: any = Object.assign({}, this._json);
+    if (json["labels"] != null) {
+      json["labels"] = Object.assign({}, json["labels"]);
+    }
+    if (json["emails"] != null) {
+      json["emails"] = json["emails"].slice();
+    }
+    const m = new User();
+    m._json = json;
+    return m;
+  }
+
+  // equals reports whether other has the same field values, unset fields
+  // being equal to their zero values.
+  public equals(other: IUser): boolean {
+    const o = other instanceof User ? other : new User(other);
+    return (
+      this.id === o.id &&
+      this.name === o.name &&
+      String(this.balance) === String(o.balance) &&
+      (this.score === o.score || this.score !== this.score && o.score !== o.score) &&
+      this.created === o.created &&
+      mapEquals(this.labels, o.labels, (x, y) => x === y) &&
+      repeatedEquals(this.emails, o.emails, (x, y) => x === y) &&
+      this.nickname === o.nickname &&
+      this.role === o.role &&
+      this.phone === o.phone &&
+      this.fax === o.fax
+    );
+  }
+}
+
+export interface IGetUserRequest {
+  id?: string;
+
+  toJSON?(): object;
+}
+
+export interface IGetUserRequestJSON {
+  id?: string;
+  toJSON?(): object;
+}
+
+export class GetUserRequest implements IGetUserRequest {
+  private _json: IGetUserRequestJSON;
+
+  constructor(m?: IGetUserRequest) {
+    this._json = {};
+    if (m) {
+      this._json["id"] = m.id;
+    }
+  }
+
+  // id (id)
+  public get id(): string {
+    return this._json.id != null ? this._json.id : "";
+  }
+  public set id(value: string) {
+    this._json.id = value;
+  }
+
+  static fromJSON(m: IGetUserRequestJSON = {}): GetUserRequest {
+    const v = new GetUserRequest({
+      id: m["id"]
+    });
+    // Preserve unknown fields (and extensions) so they survive a round-trip.
+    const known: string[] = ["id"];
+    Object.keys(m).forEach(k => {
+      if (known.indexOf(k) < 0) {
+        (<any>v._json)[k] = (<any>m)[k];
+      }
+    });
+    return v;
+  }
+
+  // create returns a GetUserRequest with the members set in m and the zero values
+  // of the other fields, nested messages and oneof members are left
+  // undefined.
+  static create(m: Partial<IGetUserRequest> = {}): GetUserRequest {
+    return new GetUserRequest({
+      id: m.id != null ? m.id : ""
+    });
+  }
+
+  // fromPartial builds a GetUserRequest from the members that are set in m,
+  // filling zero values for the others, except oneof members, and building
+  // nested messages.
+  static fromPartial(m: DeepPartial<IGetUserRequest> = {}): GetUserRequest {
+    return new GetUserRequest({
+      id: m.id != null ? m.id : ""
+    });
+  }
+
+  public toJSON(): object {
+    return this._json;
+  }
+
+  // clone returns a deep copy of the message sharing no state with it, the
+  // nested messages are cloned by their classes.
+  public clone(): GetUserRequest {
+    const json: any = Object.assign({}, this._json);
+    const m = new GetUserRequest();
+    m._json = json;
+    return m;
+  }
+
+  // equals reports whether other has the same field values, unset fields
+  // being equal to their zero values.
+  public equals(other: IGetUserRequest): boolean {
+    const o = other instanceof GetUserRequest ? other : new GetUserRequest(other);
+    return this.id === o.id;
+  }
+}
+
+export interface IListUsersRequest {
+  pageSize?: number;
+
+  toJSON?(): object;
+}
+
+export interface IListUsersRequestJSON {
+  page_size?: number;
+  toJSON?(): object;
+}
+
+export class ListUsersRequest implements IListUsersRequest {
+  private _json: IListUsersRequestJSON;
+
+  constructor(m?: IListUsersRequest) {
+    this._json = {};
+    if (m) {
+      this._json["page_size"] = m.pageSize;
+    }
+  }
+
+  // pageSize (page_size)
+  public get pageSize(): number {
+    return this._json.page_size != null ? this._json.page_size : 0;
+  }
+  public set pageSize(value: number) {
+    this._json.page_size = value;
+  }
+
+  static fromJSON(m: IListUsersRequestJSON = {}): ListUsersRequest {
+    const v = new ListUsersRequest({
+      pageSize: m["page_size"]
+    });
+    // Preserve unknown fields (and extensions) so they survive a round-trip.
+    const known: string[] = ["page_size"];
+    Object.keys(m).forEach(k => {
+      if (known.indexOf(k) < 0) {
+        (<any>v._json)[k] = (<any>m)[k];
+      }
+    });
+    return v;
+  }
+
+  // create returns a ListUsersRequest with the members set in m and the zero values
+  // of the other fields, nested messages and oneof members are left
+  // undefined.
+  static create(m: Partial<IListUsersRequest> = {}): ListUsersRequest {
+    return new ListUsersRequest({
+      pageSize: m.pageSize != null ? m.pageSize : 0
+    });
+  }
+
+  // fromPartial builds a ListUsersRequest from the members that are set in m,
+  // filling zero values for the others, except oneof members, and building
+  // nested messages.
+  static fromPartial(m: DeepPartial<IListUsersRequest> = {}): ListUsersRequest {
+    return new ListUsersRequest({
+      pageSize: m.pageSize != null ? m.pageSize : 0
+    });
+  }
+
+  public toJSON(): object {
+    return this._json;
+  }
+
+  // clone returns a deep copy of the message sharing no state with it, the
+  // nested messages are cloned by their classes.
+  public clone(): ListUsersRequest {
+    const json: any = Object.assign({}, this._json);
+    const m = new ListUsersRequest();
+    m._json = json;
+    return m;
+  }
+
+  // equals reports whether other has the same field values, unset fields
+  // being equal to their zero values.
+  public equals(other: IListUsersRequest): boolean {
+    const o = other instanceof ListUsersRequest ? other : new ListUsersRequest(other);
+    return this.pageSize === o.pageSize;
+  }
+}
+
+export interface IListUsersResponse {
+  users?: User[];
+
+  toJSON?(): object;
+}
+
+export interface IListUsersResponseJSON {
+  users?: User[];
+  toJSON?(): object;
+}
+
+export class ListUsersResponse implements IListUsersResponse {
+  private _json: IListUsersResponseJSON;
+
+  constructor(m?: IListUsersResponse) {
+    this._json = {};
+    if (m) {
+      this._json["users"] = m.users;
+    }
+  }
+
+  // users (users)
+  public get users(): User[] {
+    return this._json.users || [];
+  }
+  public set users(value: User[]) {
+    this._json.users = value;
+  }
+
+  static fromJSON(m: IListUsersResponseJSON = {}): ListUsersResponse {
+    const v = new ListUsersResponse({
+      users: (m["users"] || []).map(v => User.fromJSON(v))
+    });
+    // Preserve unknown fields (and extensions) so they survive a round-trip.
+    const known: string[] = ["users"];
+    Object.keys(m).forEach(k => {
+      if (known.indexOf(k) < 0) {
+        (<any>v._json)[k] = (<any>m)[k];
+      }
+    });
+    return v;
+  }
+
+  // create returns a ListUsersResponse with the members set in m and the zero values
+  // of the other fields, nested messages and oneof members are left
+  // undefined.
+  static create(m: Partial<IListUsersResponse> = {}): ListUsersResponse {
+    return new ListUsersResponse({
+      users: m.users != null ? m.users : []
+    });
+  }
+
+  // fromPartial builds a ListUsersResponse from the members that are set in m,
+  // filling zero values for the others, except oneof members, and building
+  // nested messages.
+  static fromPartial(m: DeepPartial<IListUsersResponse> = {}): ListUsersResponse {
+    return new ListUsersResponse({
+      users: (m.users || []).map(v => User.fromPartial(v))
+    });
+  }
+
+  public toJSON(): object {
+    const json: any = Object.assign({}, this._json);
+    if (json["users"] != null) {
+      json["users"] = json["users"].map((v: any) => (v instanceof User ? v : new User(v)).toJSON());
+    }
+    return json;
+  }
+
+  // clone returns a deep copy of the message sharing no state with it, the
+  // nested messages are cloned by their classes.
+  public clone(): ListUsersResponse {
+    const json: any = Object.assign({}, this._json);
+    if (json["users"] != null) {
+      json["users"] = json["users"].map((v: any) => (v instanceof User ? v : new User(v)).clone());
+    }
+    const m = new ListUsersResponse();
+    m._json = json;
+    return m;
+  }
+
+  // equals reports whether other has the same field values, unset fields
+  // being equal to their zero values.
+  public equals(other: IListUsersResponse): boolean {
+    const o = other instanceof ListUsersResponse ? other : new ListUsersResponse(other);
+    return repeatedEquals(this.users, o.users, (x, y) => (x instanceof User ? x : new User(x)).equals(y));
+  }
+}
+
+// Services
+// UsersGetUserRequest and UsersGetUserResponse are the input
+// and result types of Users.GetUser.
+export type UsersGetUserRequest = GetUserRequest;
+export type UsersGetUserResponse = User;
+
+// UsersListUsersRequest and UsersListUsersResponse are the input
+// and result types of Users.ListUsers.
+export type UsersListUsersRequest = ListUsersRequest;
+export type UsersListUsersResponse = ListUsersResponse;
+
+// UsersMethods maps the Users methods to their input and output
+// types, for generic wrappers over the service. The output of streaming
+// methods is the type of their messages.
+export type UsersMethods = {
+  getUser: { input: UsersGetUserRequest; output: UsersGetUserResponse };
+  listUsers: { input: UsersListUsersRequest; output: UsersListUsersResponse };
+};
+
+export interface IUsers {
+  /**
+   * GetUser returns a user by ID.
+   */
+  getUser: (
+    data: GetUserRequest,
+    headers?: object,
+    options?: CallOptions
+  ) => Promise<User>;
+  listUsers: (
+    data: ListUsersRequest,
+    headers?: object,
+    options?: CallOptions
+  ) => Promise<ListUsersResponse>;
+}
+
+export class Users implements IUsers {
+  private hostname: string;
+  private fetch: Fetch;
+  private options: ClientOptions;
+  private path: string;
+
+  constructor(options: ClientOptions);
+  constructor(hostname: string, fetch: Fetch, options?: ClientOptions);
+  constructor(hostname: string | ClientOptions, fetch?: Fetch, options: ClientOptions = {}) {
+    if (typeof hostname !== "string") {
+      options = hostname;
+      hostname = options.baseURL == null ? resolveBaseURL("acme.users.Users") : options.baseURL;
+    }
+    this.hostname = hostname;
+    this.fetch = clientFetch(options, fetch || options.fetch || defaultFetch);
+    this.options = options;
+    this.path = (options.pathPrefix == null ? "/twirp" : options.pathPrefix) + "/acme.users.Users/";
+  }
+
+  // warmup opens a connection to the server ahead of the first call, see
+  // preconnect.
+  public warmup(): void {
+    preconnect(this.hostname, this.options);
+  }
+
+  private url(name: string): string {
+    return this.hostname + this.path + name;
+  }
+
+  private fetchFor(options: CallOptions): Fetch {
+    return options.fetch ? clientFetch(this.options, options.fetch) : this.fetch;
+  }
+
+  /**
+   * GetUser returns a user by ID.
+   */
+  public getUser(
+    params: GetUserRequest,
+    headers: object = {},
+    options: CallOptions = {}
+  ): Promise<User> {
+    return twirpCall(mergeOptions(this.options, "GetUser", options), callOptions =>
+      this.fetchFor(callOptions)(
+        this.url("GetUser"),
+        createTwirpRequest(params, headers, callOptions)
+      ).then(res => {
+        if (!res.ok) {
+          return throwTwirpError(res);
+        }
+        return readTwirpResponse(res, callOptions).then(m => {
+          return User.fromJSON(m);
+        });
+      })
+    );
+  }
+
+  public listUsers(
+    params: ListUsersRequest,
+    headers: object = {},
+    options: CallOptions = {}
+  ): Promise<ListUsersResponse> {
+    const merged = mergeOptions(this.options, "ListUsers", options);
+    return cachedCall(merged, "acme.users.Users/ListUsers", params, () =>
+      twirpCall(merged, callOptions =>
+        this.fetchFor(callOptions)(
+          this.url("ListUsers"),
+          createTwirpRequest(params, headers, callOptions)
+        ).then(res => {
+          if (!res.ok) {
+            return throwTwirpError(res);
+          }
+          return readTwirpResponse(res, callOptions);
+        })
+      )
+    ).then(m => {
+      return ListUsersResponse.fromJSON(m);
+    });
+  }
+}
@@ -0,0 +1,148 @@
+/* eslint-disable */
+
+// This file has been generated by https://github.com/horizon-games/protoc-gen-twirp_ts.
+// Do not edit.
+//
+// versions:
+//   protoc-gen-twirp_ts (unknown)
+//   protoc (unknown)
+// source: extensions.proto
+
+import { DeepPartial, Extension } from "../../twirp";
+
+export interface IResource {
+  id?: string;
+  token?: string;
+
+  toJSON?(): object;
+}
+
+export interface IResourceJSON {
+  id?: string;
+  token?: string;
+  toJSON?(): object;
+}
+
+export class Resource implements IResource {
+  private _json: IResourceJSON;
+
+  constructor(m?: IResource) {
+    this._json = {};
+    if (m) {
+      this._json["id"] = m.id;
+      this._json["token"] = m.token;
+    }
+  }
+
+  // id (id)
+  public get id(): string {
+    return this._json.id != null ? this._json.id : "";
+  }
+  public set id(value: string) {
+    this._json.id = value;
+  }
+  public hasId(): boolean {
+    return this._json.id != null;
+  }
+  public clearId() {
+    delete this._json.id;
+  }
+
+  // token (token)
+  public get token(): string {
+    return this._json.token != null ? this._json.token : "";
+  }
+  public set token(value: string) {
+    this._json.token = value;
+  }
+  public hasToken(): boolean {
+    return this._json.token != null;
+  }
+  public clearToken() {
+    delete this._json.token;
+  }
+
+  public getExtension<T>(ext: Extension<IResource, T>): T | undefined {
+    return ext.fromJSON(this._json);
+  }
+  public setExtension<T>(ext: Extension<IResource, T>, value: T) {
+    (<any>this._json)[ext.name] = value;
+  }
+
+  static fromJSON(m: IResourceJSON = {}): Resource {
+    const v = new Resource({
+      id: m["id"],
+      token: m["token"]
+    });
+    // Preserve unknown fields (and extensions) so they survive a round-trip.
+    const known: string[] = ["id", "token"];
+    Object.keys(m).forEach(k => {
+      if (known.indexOf(k) < 0) {
+        (<any>v._json)[k] = (<any>m)[k];
+      }
+    });
+    return v;
+  }
+
+  // create returns a Resource with the members set in m and the zero values
+  // of the other fields, nested messages and oneof members are left
+  // undefined.
+  static create(m: Partial<IResource> = {}): Resource {
+    return new Resource({
+      id: m.id != null ? m.id : "",
+      token: m.token != null ? m.token : ""
+    });
+  }
+
+  // fromPartial builds a Resource from the members that are set in m,
+  // filling zero values for the others, except oneof members, and building
+  // nested messages.
+  static fromPartial(m: DeepPartial<IResource> = {}): Resource {
+    return new Resource({
+      id: m.id != null ? m.id : "",
+      token: m.token != null ? m.token : ""
+    });
+  }
+
+  public toJSON(): object {
+    return this._json;
+  }
+
+  // clone returns a deep copy of the message sharing no state with it, the
+  // nested messages are cloned by their classes.
+  public clone(): Resource {
+    const json: any = Object.assign({}, this._json);
+    const m = new Resource();
+    m._json = json;
+    return m;
+  }
+
+  // equals reports whether other has the same field values, unset fields
+  // being equal to their zero values.
+  public equals(other: IResource): boolean {
+    const o = other instanceof Resource ? other : new Resource(other);
+    return (
+      this.id === o.id &&
+      this.token === o.token
+    );
+  }
+}
+
+// Extensions
+export const secret: Extension<object, boolean> = {
+  name: "[acme.extensions.secret]",
+  fieldNumber: 50000,
+  fromJSON: (m: any) => m["[acme.extensions.secret]"]
+};
+
+export const note: Extension<IResource, string> = {
+  name: "[acme.extensions.note]",
+  fieldNumber: 100,
+  fromJSON: (m: any) => m["[acme.extensions.note]"]
+};
+
+export const tags: Extension<IResource, number[]> = {
+  name: "[acme.extensions.tags]",
+  fieldNumber: 101,
+  fromJSON: (m: any) => (m["[acme.extensions.tags]"] || []).map(Number)
+};
@@ -0,0 +1,19 @@
+/* eslint-disable */
+
+// This file has been generated by https://github.com/horizon-games/protoc-gen-twirp_ts.
+// Do not edit.
+//
+// versions:
+//   protoc-gen-twirp_ts (unknown)
+//   protoc (unknown)
+
+export {
+  Resource,
+  secret,
+  note,
+  tags
+} from "./extensions";
+export type {
+  IResource,
+  IResourceJSON
+} from "./extensions";
@@ -0,0 +1,984 @@
+/* eslint-disable */
+
+// This file has been generated by https://github.com/horizon-games/protoc-gen-twirp_ts.
+// Do not edit.
+//
+// versions:
+//   protoc-gen-twirp_ts (unknown)
+//   protoc (unknown)
+
+// TwirpErrorCode are the error codes of the Twirp protocol.
+export enum TwirpErrorCode {
+  Canceled = "canceled",
+  Unknown = "unknown",
+  InvalidArgument = "invalid_argument",
+  Malformed = "malformed",
+  DeadlineExceeded = "deadline_exceeded",
+  NotFound = "not_found",
+  BadRoute = "bad_route",
+  AlreadyExists = "already_exists",
+  PermissionDenied = "permission_denied",
+  Unauthenticated = "unauthenticated",
+  ResourceExhausted = "resource_exhausted",
+  FailedPrecondition = "failed_precondition",
+  Aborted = "aborted",
+  OutOfRange = "out_of_range",
+  Unimplemented = "unimplemented",
+  Internal = "internal",
+  Unavailable = "unavailable",
+  DataLoss = "data_loss"
+}
+
+export interface TwirpErrorJSON {
+  code: string;
+  msg: string;
+  meta?: {
+    [index: string]: string;
+  };
+}
+
+// TwirpError is the error generated client methods reject with, M is the
+// type of its meta, see the error_meta_option parameter.
+export class TwirpError<M extends object = { [index: string]: string }> extends Error {
+  code: TwirpErrorCode;
+  msg: string;
+  meta: M;
+
+  constructor(te: TwirpErrorJSON) {
+    super(te.msg);
+    // Keep instanceof working when compiled to ES5.
+    Object.setPrototypeOf(this, new.target.prototype);
+
+    this.name = "TwirpError";
+    this.code = <TwirpErrorCode>te.code;
+    this.msg = te.msg;
+    this.meta = <M>(<any>te.meta || {});
+  }
+}
+
+// httpErrorCode returns the error code of an HTTP status, for errors that
+// aren't Twirp errors, e.g. from a proxy. Twirp v5 servers send
+// resource_exhausted errors with the status 403, see the twirp_version
+// parameter.
+const httpErrorCode = (status: number): TwirpErrorCode => {
+  switch (status) {
+    case 401:
+      return TwirpErrorCode.Unauthenticated;
+    case 403:
+      return TwirpErrorCode.PermissionDenied;
+    case 404:
+      return TwirpErrorCode.BadRoute;
+    case 429:
+    case 502:
+    case 503:
+    case 504:
+      return TwirpErrorCode.Unavailable;
+  }
+  return status >= 300 && status <= 400 ? TwirpErrorCode.Internal : TwirpErrorCode.Unknown;
+};
+
+// isTwirpErrorCode reports whether code is an error code of the Twirp
+// protocol.
+const isTwirpErrorCode = (code: string): boolean =>
+  Object.keys(TwirpErrorCode).some(name => (<any>TwirpErrorCode)[name] === code);
+
+export const throwTwirpError = (resp: Response): Promise<never> => {
+  return resp.text().then(text => {
+    let err: TwirpErrorJSON | undefined;
+    try {
+      err = JSON.parse(text);
+    } catch (e) {
+      // Not a Twirp error, see below.
+    }
+    if (!err || typeof err.code !== "string") {
+      const meta: { [index: string]: string } = {
+        http_error_from_intermediary: "true",
+        status_code: String(resp.status),
+        body: text
+      };
+      err = {
+        code: httpErrorCode(resp.status),
+        msg: "HTTP status " + resp.status + " without a Twirp error",
+        meta
+      };
+      // Redirects aren't followed, see createTwirpRequest. Browsers hide
+      // them in opaque responses with the status 0.
+      if (resp.status === 0 || (resp.status >= 300 && resp.status < 400)) {
+        err.code = TwirpErrorCode.Internal;
+        err.msg = "unexpected redirect" + (resp.status ? " with HTTP status " + resp.status : "");
+        meta.location = resp.headers.get("Location") || "";
+      }
+    }
+    // Like the Twirp v7 clients, codes of other versions of the protocol are
+    // internal errors, Twirp v5 clients keep them.
+    if (!isTwirpErrorCode(err.code)) {
+      err = {
+        code: TwirpErrorCode.Internal,
+        msg: "invalid Twirp error code " + err.code + " with HTTP status " + resp.status,
+        meta: {
+          status_code: String(resp.status),
+          body: text
+        }
+      };
+    }
+    throw new TwirpError(err);
+  });
+};
+
+// toTwirpError converts the errors of calls to TwirpErrors: aborted calls are
+// canceled, other failed requests unavailable and other errors internal.
+export const toTwirpError = (err: any): TwirpError => {
+  if (err instanceof TwirpError) {
+    return err;
+  }
+  const msg = String((err && err.message) || err);
+  if (err && err.name === "AbortError") {
+    return new TwirpError({ code: TwirpErrorCode.Canceled, msg });
+  }
+  if (err instanceof TypeError) {
+    return new TwirpError({ code: TwirpErrorCode.Unavailable, msg });
+  }
+  return new TwirpError({ code: TwirpErrorCode.Internal, msg });
+};
+
+// DeadlineExceededError is the error of calls that took longer than their
+// timeout.
+export class DeadlineExceededError extends TwirpError {
+  constructor(timeout: number) {
+    super({
+      code: TwirpErrorCode.DeadlineExceeded,
+      msg: "call exceeded its " + timeout + "ms timeout"
+    });
+    this.name = "DeadlineExceededError";
+  }
+}
+
+// RetryPolicy retries failed calls with exponential backoff.
+export interface RetryPolicy {
+  // maxAttempts is the maximum number of attempts, including the first.
+  maxAttempts: number;
+  // initialBackoff is the delay before the first retry in milliseconds,
+  // 100 by default. It's multiplied by multiplier, 2 by default, for each
+  // further retry, up to maxBackoff, 10000 by default.
+  initialBackoff?: number;
+  multiplier?: number;
+  maxBackoff?: number;
+  // jitter randomizes the delays by up to this fraction, 0.2 by default.
+  jitter?: number;
+  // retryableCodes are the Twirp error codes retried, unavailable and
+  // deadline_exceeded by default.
+  retryableCodes?: string[];
+}
+
+// CacheStore stores the cached results of calls as JSON, e.g. in memory, see
+// MemoryCacheStore, or in localStorage. It may return promises.
+export interface CacheStore {
+  get(key: string): string | undefined | Promise<string | undefined>;
+  set(key: string, value: string, ttl: number): void | Promise<void>;
+}
+
+// CacheOptions cache the results of the methods without side effects, marked
+// with option idempotency_level = NO_SIDE_EFFECTS, for ttl milliseconds. The
+// results are keyed by method and request, not by headers. A ttl of 0
+// disables the cache.
+export interface CacheOptions {
+  ttl: number;
+  // store is a MemoryCacheStore shared by all clients by default.
+  store?: CacheStore;
+}
+
+// MemoryCacheStore is a CacheStore in memory.
+export class MemoryCacheStore implements CacheStore {
+  private entries: { [key: string]: { value: string; expires: number } } = {};
+
+  public get(key: string): string | undefined {
+    const entry = this.entries[key];
+    if (entry && entry.expires < Date.now()) {
+      delete this.entries[key];
+      return undefined;
+    }
+    return entry && entry.value;
+  }
+
+  public set(key: string, value: string, ttl: number) {
+    this.entries[key] = { value, expires: Date.now() + ttl };
+  }
+}
+
+const defaultCacheStore = new MemoryCacheStore();
+
+// cachedCall returns the cached JSON result of a call to method with request,
+// or makes the call and caches its result with the cache of options.
+export const cachedCall = (
+  options: CallOptions,
+  method: string,
+  request: object,
+  call: () => Promise<any>
+): Promise<any> => {
+  const cache = options.cache;
+  if (!cache || !cache.ttl) {
+    return call();
+  }
+  const store = cache.store || defaultCacheStore;
+  const ttl = cache.ttl;
+  const key = method + ":" + JSON.stringify(request || {});
+  return Promise.resolve(store.get(key)).then(cached => {
+    if (cached !== undefined) {
+      return JSON.parse(cached);
+    }
+    return call().then(json => {
+      return Promise.resolve(store.set(key, JSON.stringify(json), ttl)).then(() => json);
+    });
+  });
+};
+
+// CallOptions are the per-call options of service client methods.
+export interface CallOptions {
+  // headers are sent with the request, merged over the headers of the
+  // client, the headers argument of methods overrides them.
+  headers?: object;
+  // credentials, mode and requestCache are the credentials, mode and cache
+  // options of the fetch of the request, e.g. "include" to send cookies
+  // cross-origin. requestCache is the HTTP cache mode, cache caches responses.
+  credentials?: "omit" | "same-origin" | "include";
+  mode?: "cors" | "no-cors" | "same-origin";
+  requestCache?: "default" | "no-store" | "reload" | "no-cache" | "force-cache" | "only-if-cached";
+  // keepalive lets the request outlive the page, e.g. for analytics sent
+  // during unload, with a body of up to 64KB. priority and referrerPolicy
+  // are the priority hint and referrer policy of the request.
+  keepalive?: boolean;
+  priority?: "high" | "low" | "auto";
+  referrerPolicy?: "" | "no-referrer" | "no-referrer-when-downgrade" | "origin" | "origin-when-cross-origin" | "same-origin" | "strict-origin" | "strict-origin-when-cross-origin" | "unsafe-url";
+  // fetch makes the request instead of the fetch of the client, e.g. with
+  // a service binding from the environment of a Cloudflare Worker.
+  fetch?: Fetch;
+  // timeout rejects calls with a DeadlineExceededError after as many
+  // milliseconds, aborting their request. It applies to each attempt.
+  timeout?: number;
+  retry?: RetryPolicy;
+  cache?: CacheOptions;
+  // signal aborts the request, e.g. from an AbortController.
+  signal?: AbortSignal;
+  // serializer encodes requests and decodes responses, see Serializer.
+  serializer?: Serializer;
+}
+
+// ClientOptions are the options of service clients, the defaults of the
+// per-call options, which methods overrides by method name, e.g. "GetUser".
+export interface ClientOptions {
+  // baseURL is the URL of the server, e.g. "https://api.example.com".
+  baseURL?: string;
+  // fetch is the fetch implementation, the global fetch by default.
+  fetch?: Fetch;
+  // pathPrefix is the prefix of the routes of services, "/twirp" by default.
+  pathPrefix?: string;
+  headers?: object;
+  credentials?: "omit" | "same-origin" | "include";
+  mode?: "cors" | "no-cors" | "same-origin";
+  requestCache?: "default" | "no-store" | "reload" | "no-cache" | "force-cache" | "only-if-cached";
+  keepalive?: boolean;
+  priority?: "high" | "low" | "auto";
+  referrerPolicy?: "" | "no-referrer" | "no-referrer-when-downgrade" | "origin" | "origin-when-cross-origin" | "same-origin" | "strict-origin" | "strict-origin-when-cross-origin" | "unsafe-url";
+  timeout?: number;
+  retry?: RetryPolicy;
+  cache?: CacheOptions;
+  serializer?: Serializer;
+  methods?: { [method: string]: CallOptions };
+  // batch collects the calls of batchable methods made without headers or
+  // options into calls of their batch method, see Batcher.
+  batch?: BatchOptions;
+  // otel traces calls with OpenTelemetry, it's the @opentelemetry/api
+  // package, see traceFetch.
+  otel?: OpenTelemetry;
+  // compression gzips large request bodies, see compressFetch.
+  compression?: CompressionOptions;
+  // tokenProvider provides the Authorization header of requests, see
+  // authFetch.
+  tokenProvider?: TokenProvider;
+}
+
+// baseURLs are the base URLs set with setBaseURL, by service or package.
+const baseURLs: { [name: string]: string } = {};
+
+// setBaseURL sets the base URL of the clients created without a baseURL of a
+// service, e.g. "acme.users.Users", or of the services of a package and its
+// subpackages, e.g. "acme". The empty name sets the base URL of all services.
+export const setBaseURL = (name: string, baseURL: string) => {
+  baseURLs[name] = baseURL;
+};
+
+// resolveBaseURL returns the base URL of the clients of service created
+// without a baseURL, the one set for the service or else for its innermost
+// package. It's "" when none is set, relative to the origin of the page.
+export const resolveBaseURL = (service: string): string => {
+  for (let name = service; ; name = name.slice(0, Math.max(name.lastIndexOf("."), 0))) {
+    if (baseURLs[name] != null) {
+      return baseURLs[name];
+    }
+    if (!name) {
+      return "";
+    }
+  }
+};
+
+// preconnected are the origins preconnect hinted.
+const preconnected: { [origin: string]: boolean } = {};
+
+// preconnect hints browsers to open a connection to the origin of baseURL
+// ahead of the first request, with a <link rel="preconnect"> in the head of
+// the document. It does nothing elsewhere and for relative base URLs.
+export const preconnect = (baseURL: string, options: ClientOptions = {}) => {
+  if (typeof document === "undefined" || !/^https?:\/\//i.test(baseURL)) {
+    return;
+  }
+  const origin = new URL(baseURL).origin;
+  if (preconnected[origin]) {
+    return;
+  }
+  preconnected[origin] = true;
+  const link = document.createElement("link");
+  link.rel = "preconnect";
+  link.href = origin;
+  // Requests with and without credentials use separate connections.
+  link.crossOrigin = options.credentials === "include" ? "use-credentials" : "anonymous";
+  document.head.appendChild(link);
+};
+
+// BatchOptions configure the batching of calls: calls made within delay
+// milliseconds of the first one, 10 by default, are sent together, up to
+// maxSize calls.
+export interface BatchOptions {
+  delay?: number;
+  maxSize?: number;
+}
+
+// CompressionOptions configure the compression of request bodies: bodies of
+// at least threshold bytes, 1024 by default, are sent gzipped.
+export interface CompressionOptions {
+  threshold?: number;
+}
+
+// CompressionStream is missing from older DOM typings.
+declare const CompressionStream: any;
+
+// compressFetch wraps fetch to gzip the bodies of requests with the
+// Content-Encoding header, where CompressionStream is available.
+export const compressFetch = (options: CompressionOptions | undefined, fetch: Fetch): Fetch => {
+  if (!options || typeof CompressionStream === "undefined") {
+    return fetch;
+  }
+  const threshold = options.threshold == null ? 1024 : options.threshold;
+  return <any>((input: string, init: any = {}) => {
+    const body = init.body;
+    const bytes = typeof body === "string" ? new TextEncoder().encode(body) : body;
+    if (!bytes || bytes.length < threshold) {
+      return fetch(input, init);
+    }
+    const stream = new Blob([bytes]).stream().pipeThrough(new CompressionStream("gzip"));
+    return new Response(stream).arrayBuffer().then(compressed => {
+      const headers = { ...init.headers, "Content-Encoding": "gzip" };
+      return fetch(input, { ...init, headers, body: new Uint8Array(compressed) });
+    });
+  });
+};
+
+// TokenProvider returns the Authorization header of requests, e.g. "Bearer "
+// and an access token. refresh is set after a request was rejected as
+// unauthenticated with the previous one.
+export type TokenProvider = (refresh: boolean) => string | Promise<string>;
+
+// authFetch wraps fetch to set the Authorization header of requests from
+// tokenProvider. A request rejected with a 401 is sent once more with a
+// refreshed header.
+export const authFetch = (tokenProvider: TokenProvider | undefined, fetch: Fetch): Fetch => {
+  if (!tokenProvider) {
+    return fetch;
+  }
+  return <any>((input: string, init: any = {}) => {
+    const send = (refresh: boolean) => {
+      return Promise.resolve(tokenProvider(refresh)).then(authorization => {
+        return fetch(input, { ...init, headers: { ...init.headers, Authorization: authorization } });
+      });
+    };
+    return send(false).then(res => (res.status === 401 ? send(true) : res));
+  });
+};
+
+// clientFetch wraps the fetch of a client with the authentication,
+// compression and tracing of its options.
+export const clientFetch = (options: ClientOptions, fetch: Fetch): Fetch => {
+  return traceFetch(options.otel, compressFetch(options.compression, authFetch(options.tokenProvider, fetch)));
+};
+
+// OpenTelemetry is the part of the @opentelemetry/api package used to trace
+// calls.
+export interface OpenTelemetry {
+  trace: {
+    getTracer(name: string): { startSpan(name: string, options?: object): OpenTelemetrySpan };
+    setSpan(context: any, span: OpenTelemetrySpan): any;
+  };
+  context: { active(): any };
+  propagation: { inject(context: any, carrier: object): void };
+}
+
+export interface OpenTelemetrySpan {
+  setAttribute(key: string, value: string | number): void;
+  setStatus(status: { code: number; message?: string }): void;
+  recordException(err: any): void;
+  end(): void;
+}
+
+// traceFetch wraps the fetch of a client to trace its requests with otel, it
+// returns fetch as-is without it. Each request gets a client span named
+// package.Service/Method with the Twirp error code, the request and response
+// sizes and the duration as attributes, and the span is propagated in the
+// traceparent header. Retried calls get a span per attempt.
+export const traceFetch = (otel: OpenTelemetry | undefined, fetch: Fetch): Fetch => {
+  if (!otel) {
+    return fetch;
+  }
+  const tracer = otel.trace.getTracer("protoc-gen-twirp_ts");
+  return <any>((input: string, init: any = {}) => {
+    const [service, method] = String(input).split("?")[0].split("/").slice(-2);
+    const span = tracer.startSpan(service + "/" + method, {
+      kind: 2, // SpanKind.CLIENT
+      attributes: { "rpc.system": "twirp", "rpc.service": service, "rpc.method": method }
+    });
+    const headers = { ...init.headers };
+    otel.propagation.inject(otel.trace.setSpan(otel.context.active(), span), headers);
+    if (init.body != null) {
+      const size = typeof init.body === "string" ? new TextEncoder().encode(init.body).length : init.body.length;
+      span.setAttribute("rpc.request.size", size);
+    }
+
+    const start = Date.now();
+    const end = (code: string) => {
+      span.setAttribute("twirp.code", code);
+      span.setAttribute("rpc.duration_ms", Date.now() - start);
+      if (code !== "ok") {
+        span.setStatus({ code: 2, message: code }); // SpanStatusCode.ERROR
+      }
+      span.end();
+    };
+    return fetch(input, { ...init, headers }).then(
+      res => {
+        span.setAttribute("http.status_code", res.status);
+        const size = res.headers.get("Content-Length");
+        if (size) {
+          span.setAttribute("rpc.response.size", Number(size));
+        }
+        if (res.ok) {
+          end("ok");
+          return res;
+        }
+        // The error is read from a copy, it's thrown from the response.
+        const clone = (<any>res).clone;
+        const error: Promise<any> = clone ? clone.call(res).json() : Promise.reject();
+        return error.then(
+          err => {
+            end(err && typeof err.code === "string" ? err.code : httpErrorCode(res.status));
+            return res;
+          },
+          () => {
+            end(httpErrorCode(res.status));
+            return res;
+          }
+        );
+      },
+      err => {
+        span.recordException(err);
+        end(toTwirpError(err).code);
+        throw err;
+      }
+    );
+  });
+};
+
+// mergeOptions returns the options of a call to method: options over the
+// client's options for the method over its defaults. Headers are merged, so
+// a call setting a header keeps the default headers of the client.
+export const mergeOptions = (
+  client: ClientOptions,
+  method: string,
+  options: CallOptions
+): CallOptions => {
+  const methods = client.methods || {};
+  const defaults = methods[method] || {};
+  return {
+    credentials: client.credentials,
+    mode: client.mode,
+    requestCache: client.requestCache,
+    keepalive: client.keepalive,
+    priority: client.priority,
+    referrerPolicy: client.referrerPolicy,
+    timeout: client.timeout,
+    retry: client.retry,
+    cache: client.cache,
+    serializer: client.serializer,
+    ...defaults,
+    ...options,
+    headers: { ...client.headers, ...defaults.headers, ...options.headers }
+  };
+};
+
+// withTimeout makes a call with the timeout of options, call is passed the
+// options with a signal aborting it on timeout or when the signal of options
+// aborts. The listener on that signal is removed when the call settles, a
+// long-lived signal shared by many calls would keep them all otherwise.
+export const withTimeout = <T>(
+  options: CallOptions,
+  call: (options: CallOptions) => Promise<T>
+): Promise<T> => {
+  const timeout = options.timeout;
+  if (!timeout) {
+    return call(options);
+  }
+
+  const controller = new AbortController();
+  const signal = options.signal;
+  const abort = () => controller.abort();
+  if (signal) {
+    if (signal.aborted) {
+      controller.abort();
+    }
+    signal.addEventListener("abort", abort, { once: true });
+  }
+  let timedOut = false;
+  const timer = setTimeout(() => {
+    timedOut = true;
+    controller.abort();
+  }, timeout);
+  const done = () => {
+    clearTimeout(timer);
+    if (signal) {
+      signal.removeEventListener("abort", abort);
+    }
+  };
+
+  return call({ ...options, signal: controller.signal }).then(
+    res => {
+      done();
+      return res;
+    },
+    err => {
+      done();
+      throw timedOut ? new DeadlineExceededError(timeout) : err;
+    }
+  );
+};
+
+const defaultRetryableCodes = ["unavailable", "deadline_exceeded"];
+
+// backoff returns the delay before retry n of policy, counting from 0.
+const backoff = (policy: RetryPolicy, n: number): number => {
+  const delay = Math.min(
+    (policy.initialBackoff || 100) * Math.pow(policy.multiplier || 2, n),
+    policy.maxBackoff || 10000
+  );
+  const jitter = policy.jitter == null ? 0.2 : policy.jitter;
+  return delay * (1 + jitter * (2 * Math.random() - 1));
+};
+
+// twirpCall makes a call with the timeout and retry policy of options, call
+// is passed the options of each attempt. It rejects with TwirpErrors.
+export const twirpCall = <T>(
+  options: CallOptions,
+  call: (options: CallOptions) => Promise<T>
+): Promise<T> => {
+  const policy = options.retry;
+  const attempt = (n: number): Promise<T> => {
+    return withTimeout(options, call).catch(e => {
+      const err = toTwirpError(e);
+      const codes = (policy && policy.retryableCodes) || defaultRetryableCodes;
+      const aborted = () => options.signal && options.signal.aborted;
+      if (!policy || n + 1 >= policy.maxAttempts || codes.indexOf(err.code) < 0 || aborted()) {
+        throw err;
+      }
+      return new Promise(resolve => setTimeout(resolve, backoff(policy, n))).then(() => {
+        if (aborted()) {
+          throw err;
+        }
+        return attempt(n + 1);
+      });
+    });
+  };
+  return attempt(0);
+};
+
+interface StreamReader {
+  read(): Promise<{ done?: boolean; value?: Uint8Array }>;
+  cancel(): void;
+}
+
+// streamReader reads the chunks of a response body, a web ReadableStream or
+// a Node stream, e.g. from node-fetch.
+const streamReader = (body: any): StreamReader => {
+  if (body && typeof body.getReader === "function") {
+    const reader = body.getReader();
+    return { read: () => reader.read(), cancel: () => reader.cancel() };
+  }
+  const it = body[Symbol.asyncIterator]();
+  return { read: () => it.next(), cancel: () => it.return && it.return() };
+};
+
+// streamIterable iterates over the values of a response body as it arrives.
+// open makes the call when iteration starts, split returns the complete values
+// of the text read so far, as functions returning or throwing them in order,
+// and the rest, done is set at the end of the body. Breaking out of the
+// iteration or a value throwing cancels the response.
+const streamIterable = <T>(
+  open: () => Promise<Response>,
+  split: (text: string, done: boolean) => [(() => T)[], string]
+): AsyncIterable<T> => {
+  return {
+    [Symbol.asyncIterator](): AsyncIterator<T> {
+      const decoder = new TextDecoder();
+      let reader: StreamReader | undefined;
+      let values: (() => T)[] = [];
+      let buffer = "";
+      let done = false;
+
+      const stop = () => {
+        done = true;
+        values = [];
+        if (reader) {
+          reader.cancel();
+        }
+      };
+
+      const next = (): Promise<IteratorResult<T>> => {
+        const value = values.shift();
+        if (value) {
+          return Promise.resolve().then(() => {
+            try {
+              return { done: false, value: value() };
+            } catch (e) {
+              stop();
+              throw e;
+            }
+          });
+        }
+        if (done) {
+          return Promise.resolve({ done: true, value: <any>undefined });
+        }
+        const chunk = reader
+          ? reader.read()
+          : open().then(res => {
+              reader = streamReader(res.body);
+              return reader.read();
+            });
+        return chunk.then(
+          c => {
+            done = !!c.done;
+            buffer += done ? decoder.decode() : decoder.decode(c.value, { stream: true });
+            [values, buffer] = split(buffer, done);
+            return next();
+          },
+          e => {
+            done = true;
+            throw toTwirpError(e);
+          }
+        );
+      };
+
+      return {
+        next,
+        return(): Promise<IteratorResult<T>> {
+          stop();
+          return Promise.resolve({ done: true, value: <any>undefined });
+        }
+      };
+    }
+  };
+};
+
+// isTwirpErrorJSON reports whether a line of a stream is a Twirp error, an
+// object with only a code, a msg and meta, sent by servers failing after
+// the response started.
+const isTwirpErrorJSON = (v: any): v is TwirpErrorJSON =>
+  v !== null &&
+  typeof v === "object" &&
+  typeof v.code === "string" &&
+  typeof v.msg === "string" &&
+  Object.keys(v).every(k => k === "code" || k === "msg" || k === "meta");
+
+// readTwirpStream iterates over the messages of a server streaming call, sent
+// as newline-delimited JSON, parse converts each message. A Twirp error
+// sent in the stream rejects with a TwirpError.
+export const readTwirpStream = <T>(
+  open: () => Promise<Response>,
+  parse: (m: any) => T
+): AsyncIterable<T> => {
+  return streamIterable(open, (text, done) => {
+    const lines = text.split("\n");
+    const rest = done ? "" : lines.pop() || "";
+    return [
+      lines
+        .filter(l => l.trim() !== "")
+        .map(l => () => {
+          const m = JSON.parse(l);
+          if (isTwirpErrorJSON(m)) {
+            throw new TwirpError(m);
+          }
+          return parse(m);
+        }),
+      rest
+    ];
+  });
+};
+
+// Serializer encodes request messages and decodes response messages, JSON by
+// default, see jsonSerializer. Messages are passed as their JSON
+// representation, e.g. to encode them with superjson or validate them with a
+// schema.
+export interface Serializer {
+  contentType: string;
+  serialize(message: any): string | Uint8Array;
+  deserialize(res: Response): Promise<any>;
+}
+
+export const jsonSerializer: Serializer = {
+  contentType: "application/json",
+  serialize: message => JSON.stringify(message),
+  deserialize: res => res.json()
+};
+
+// readTwirpResponse reads the JSON of the response message of a call with the
+// serializer of options.
+export const readTwirpResponse = (
+  res: Response,
+  options: CallOptions
+): Promise<any> => {
+  return (options.serializer || jsonSerializer).deserialize(res);
+};
+
+// requestInit returns the options of the fetch of a call besides its method,
+// headers and body.
+const requestInit = (options: CallOptions): object => {
+  return {
+    credentials: options.credentials,
+    mode: options.mode,
+    cache: options.requestCache,
+    keepalive: options.keepalive,
+    priority: options.priority,
+    referrerPolicy: options.referrerPolicy,
+    redirect: "manual",
+    signal: options.signal
+  };
+};
+
+export const createTwirpRequest = (
+  body: object = {},
+  headers: object = {},
+  options: CallOptions = {}
+): object => {
+  const serializer = options.serializer || jsonSerializer;
+  return {
+    method: "POST",
+    headers: {
+      ...options.headers,
+      ...headers,
+      "Content-Type": serializer.contentType
+    },
+    body: serializer.serialize(body || {}),
+    ...requestInit(options)
+  };
+};
+
+// Timestamp is a google.protobuf.Timestamp when generated with
+// timestamp=object.
+export interface Timestamp {
+  seconds: number;
+  nanos: number;
+}
+
+// parseTimestamp parses an RFC 3339 timestamp as encoded by jsonpb.
+export const parseTimestamp = (s: string): Timestamp => {
+  const m = /^(.*?)(?:\.(\d{1,9}))?(Z|[+-]\d\d:\d\d)$/i.exec(s);
+  if (!m) {
+    throw new Error("invalid timestamp: " + s);
+  }
+  return {
+    seconds: Math.floor(Date.parse(m[1] + m[3]) / 1000),
+    nanos: m[2] ? Number((m[2] + "00000000").slice(0, 9)) : 0
+  };
+};
+
+// formatTimestamp formats t as an RFC 3339 timestamp with 0, 3, 6 or 9
+// fractional digits.
+export const formatTimestamp = (t: Timestamp): string => {
+  const date = new Date(t.seconds * 1000).toISOString().replace(/\.\d+Z$/, "");
+  let frac = "";
+  if (t.nanos) {
+    frac = ("." + ("00000000" + t.nanos).slice(-9)).replace(/(000)+$/, "");
+  }
+  return date + frac + "Z";
+};
+
+const base64Chars = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789+/";
+
+const base64Encode = (b: Uint8Array): string => {
+  let s = "";
+  for (let i = 0; i < b.length; i += 3) {
+    const n = (b[i] << 16) | ((b[i + 1] || 0) << 8) | (b[i + 2] || 0);
+    s += base64Chars[n >> 18] + base64Chars[(n >> 12) & 63];
+    s += i + 1 < b.length ? base64Chars[(n >> 6) & 63] : "=";
+    s += i + 2 < b.length ? base64Chars[n & 63] : "=";
+  }
+  return s;
+};
+
+// formatBytes returns the proto3 JSON of bytes fields, standard base64 with
+// padding. Strings are taken as base64 already, URL-safe or not.
+export const formatBytes = (b: string | Uint8Array): string => {
+  if (typeof b !== "string") {
+    return base64Encode(b);
+  }
+  const s = b.replace(/-/g, "+").replace(/_/g, "/").replace(/=+$/, "");
+  return s + "==".slice(0, (4 - (s.length % 4)) % 4);
+};
+
+// Int32 and UInt32 are the types of 32-bit integer fields when generated with
+// branded_ints, e.g. <Int32>42.
+export type Int32 = number & { __int32: true };
+export type UInt32 = number & { __uint32: true };
+
+// DeepPartial makes the members of messages optional recursively, see the
+// fromPartial methods of message classes.
+export type DeepPartial<T> = T extends string | number | boolean | Date | Timestamp | ((...args: any[]) => any)
+  ? T
+  : T extends Array<infer U>
+  ? Array<DeepPartial<U>>
+  : T extends object
+  ? { [K in keyof T]?: DeepPartial<T[K]> }
+  : T;
+
+// repeatedEquals and mapEquals compare repeated fields and maps element-wise
+// with eq, see the equals methods of message classes.
+export const repeatedEquals = <T>(a: ReadonlyArray<T>, b: ReadonlyArray<T>, eq: (x: T, y: T) => boolean): boolean => {
+  return a.length === b.length && a.every((x, i) => eq(x, b[i]));
+};
+export const mapEquals = (a: any, b: any, eq: (x: any, y: any) => boolean): boolean => {
+  const keys = Object.keys(a);
+  return keys.length === Object.keys(b).length && keys.every(k => k in b && eq(a[k], b[k]));
+};
+
+// mapValues returns a copy of the map m with its values converted by f, it's
+// shared by the conversions of the map fields of all messages.
+export const mapValues = (m: any, f: (v: any) => any): any => {
+  const acc: any = {};
+  Object.keys(m || {}).forEach(k => {
+    acc[k] = f(m[k]);
+  });
+  return acc;
+};
+
+export type FieldMaskPath<T> = Exclude<keyof T, "toJSON"> & string;
+
+// createFieldMask builds a google.protobuf.FieldMask from field names of the
+// target message interface, e.g. createFieldMask<IUser>("name", "email").
+export const createFieldMask = <T>(...paths: FieldMaskPath<T>[]): string => {
+  return paths.join(",");
+};
+
+// Extension describes a proto2 extension field of messages of type M, see
+// getExtension and setExtension on the extended message.
+export interface Extension<M, T> {
+  name: string;
+  fieldNumber: number;
+  fromJSON(m: any): T | undefined;
+}
+
+// textResponse is the part of a fetch Response read by clients, for the
+// responses of WebSocketTransport and fakes.
+const textResponse = (status: number, headers: { [name: string]: string } = {}, body = "") => {
+  const lower: { [name: string]: string } = {};
+  Object.keys(headers).forEach(name => {
+    lower[name.toLowerCase()] = headers[name];
+  });
+  return {
+    ok: status >= 200 && status < 300,
+    status,
+    headers: { get: (name: string): string | null => lower[name.toLowerCase()] || null },
+    text: () => Promise.resolve(body),
+    json: () => Promise.resolve().then(() => JSON.parse(body || "{}"))
+  };
+};
+
+// twirpErrorStatus are the HTTP statuses of the Twirp error codes.
+const twirpErrorStatus: { [code: string]: number } = {
+  [TwirpErrorCode.Canceled]: 408,
+  [TwirpErrorCode.Unknown]: 500,
+  [TwirpErrorCode.InvalidArgument]: 400,
+  [TwirpErrorCode.Malformed]: 400,
+  [TwirpErrorCode.DeadlineExceeded]: 408,
+  [TwirpErrorCode.NotFound]: 404,
+  [TwirpErrorCode.BadRoute]: 404,
+  [TwirpErrorCode.AlreadyExists]: 409,
+  [TwirpErrorCode.PermissionDenied]: 403,
+  [TwirpErrorCode.Unauthenticated]: 401,
+  [TwirpErrorCode.ResourceExhausted]: 429,
+  [TwirpErrorCode.FailedPrecondition]: 412,
+  [TwirpErrorCode.Aborted]: 409,
+  [TwirpErrorCode.OutOfRange]: 400,
+  [TwirpErrorCode.Unimplemented]: 501,
+  [TwirpErrorCode.Internal]: 500,
+  [TwirpErrorCode.Unavailable]: 503,
+  [TwirpErrorCode.DataLoss]: 500
+};
+
+// servedError converts the errors of served calls to TwirpErrors, other
+// errors than TwirpErrors are internal.
+const servedError = (err: any): TwirpError => {
+  if (err instanceof TwirpError) {
+    return err;
+  }
+  return new TwirpError({ code: TwirpErrorCode.Internal, msg: String((err && err.message) || err) });
+};
+
+// FakeMethod handles the calls of a method of a fake service, from the JSON
+// of the request to its response message.
+export type FakeMethod = (request: any, headers: { [name: string]: string }) => any;
+
+// fakeFetch serves the methods of service in memory, as the fetch of a
+// client. The handler of a method is looked up by name with method, routes
+// without one are bad routes. Errors thrown by handlers are sent as Twirp
+// errors, other errors than TwirpErrors as internal ones. Only JSON requests
+// are served.
+export const fakeFetch = (
+  service: string,
+  method: (name: string) => FakeMethod | undefined
+): Fetch => {
+  return <any>((input: string, init: any = {}) => {
+    const url = String(input).split("?")[0];
+    const route = "/" + service + "/";
+    const name = url.lastIndexOf(route) >= 0 ? url.slice(url.lastIndexOf(route) + route.length) : "";
+    const handler = name ? method(name) : undefined;
+    const response = new Promise<any>(resolve => {
+      if (!handler) {
+        throw new TwirpError({ code: TwirpErrorCode.BadRoute, msg: "no handler for " + url });
+      }
+      if (init.body != null && typeof init.body !== "string") {
+        throw new TwirpError({ code: TwirpErrorCode.Malformed, msg: "fakes only accept JSON requests" });
+      }
+      resolve(handler(JSON.parse(init.body || "{}"), init.headers || {}));
+    });
+    return response.then(
+      m => textResponse(200, { "Content-Type": "application/json" }, JSON.stringify(m === undefined ? {} : m)),
+      err => {
+        const te = servedError(err);
+        const body = JSON.stringify({ code: te.code, msg: te.msg, meta: te.meta });
+        return textResponse(twirpErrorStatus[te.code] || 500, { "Content-Type": "application/json" }, body);
+      }
+    );
+  });
+};
+
+export type Fetch = (
+  input: RequestInfo,
+  init?: RequestInit
+) => Promise<Response>;
+
+// defaultFetch is the fetch of clients created without one, the global fetch
+// is looked up when called so it can be polyfilled later.
+export const defaultFetch: Fetch = (input: RequestInfo, init?: RequestInit) => fetch(input, init);
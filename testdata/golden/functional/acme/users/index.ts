@@ -0,0 +1,44 @@
+/* eslint-disable */
+
+// This file has been generated by https://github.com/horizon-games/protoc-gen-twirp_ts.
+// Do not edit.
+//
+// versions:
+//   protoc-gen-twirp_ts (unknown)
+//   protoc (unknown)
+
+export {
+  User_Role,
+  User_RoleValues,
+  User_RoleNames,
+  createUser,
+  decodeUser,
+  encodeUser,
+  createGetUserRequest,
+  decodeGetUserRequest,
+  encodeGetUserRequest,
+  createListUsersRequest,
+  decodeListUsersRequest,
+  encodeListUsersRequest,
+  createListUsersResponse,
+  decodeListUsersResponse,
+  encodeListUsersResponse,
+  usersGetUser,
+  usersListUsers
+} from "./users";
+export type {
+  IUser,
+  IUserJSON,
+  IGetUserRequest,
+  IGetUserRequestJSON,
+  IListUsersRequest,
+  IListUsersRequestJSON,
+  IListUsersResponse,
+  IListUsersResponseJSON,
+  IUsers,
+  UsersMethods,
+  UsersGetUserRequest,
+  UsersGetUserResponse,
+  UsersListUsersRequest,
+  UsersListUsersResponse
+} from "./users";
@@ -0,0 +1,332 @@
+/* eslint-disable */
+
+// This file has been generated by https://github.com/horizon-games/protoc-gen-twirp_ts.
+// Do not edit.
+//
+// versions:
+//   protoc-gen-twirp_ts (unknown)
+//   protoc (unknown)
+// source: users.proto
+
+import { CallOptions, callTwirpMethod, ClientOptions, readTwirpResponse } from "../../twirp";
+
+/**
+ * User is a registered user.
+ */
+export interface IUser {
+  id?: string;
+  name?: string;
+  balance?: number;
+  score?: number;
+  created?: string;
+  labels?: { [key: string]: string };
+  emails?: string[];
+  nickname?: string | undefined;
+  role?: User_Role;
+  phone?: string;
+  fax?: string;
+
+  toJSON?(): object;
+}
+
+export enum User_Role {
+  MEMBER = "MEMBER",
+  ADMIN = "ADMIN"
+}
+
+export const User_RoleValues: { [name: string]: number } = {
+  MEMBER: 0,
+  ADMIN: 1
+};
+
+export const User_RoleNames: { [value: number]: User_Role } = {
+  0: User_Role.MEMBER,
+  1: User_Role.ADMIN
+};
+
+export interface IUserJSON {
+  id?: string;
+  name?: string;
+  balance?: number;
+  score?: number;
+  created?: string;
+  labels?: { [key: string]: string };
+  emails?: string[];
+  nickname?: string | undefined;
+  role?: User_Role;
+  phone?: string;
+  fax?: string;
+  toJSON?(): object;
+}
+
+// createUser returns an IUser with the members set in m and
+// the zero values of the other fields, nested messages and oneof members are
+// left undefined.
+export const createUser = (m: Partial<IUser> = {}): IUser => {
+  return {
+    id: m.id != null ? m.id : "",
+    name: m.name != null ? m.name : "",
+    balance: m.balance != null ? m.balance : 0,
+    score: m.score != null ? m.score : 0,
+    created: m.created,
+    labels: m.labels != null ? m.labels : {},
+    emails: m.emails != null ? m.emails : [],
+    nickname: m.nickname,
+    role: m.role != null ? m.role : User_Role.MEMBER,
+    phone: m.phone,
+    fax: m.fax
+  };
+};
+
+// decodeUser converts proto3 JSON to an IUser, absent fields
+// get their zero values, except oneof members.
+export const decodeUser = (m: IUserJSON = {}): IUser => {
+  return createUser({
+    id: m["id"],
+    name: m["name"],
+    balance: m["balance"],
+    score: m["score"] == null ? undefined : Number(m["score"]),
+    created: m["created"],
+    labels: m["labels"],
+    emails: (m["emails"] || []).map(String),
+    nickname: m["nickname"] == null ? undefined : m["nickname"],
+    role: (<any>User_Role)[<any>m["role"]] || User_RoleNames[<any>m["role"]],
+    phone: m["phone"],
+    fax: m["fax"]
+  });
+};
+
+// encodeUser converts an IUser to its canonical proto3 JSON.
+export const encodeUser = (m: IUser): IUserJSON => {
+  const json: any = {
+    id: m.id,
+    name: m.name,
+    balance: m.balance,
+    score: m.score,
+    created: m.created,
+    labels: m.labels,
+    emails: m.emails,
+    nickname: m.nickname,
+    role: m.role,
+    phone: m.phone,
+    fax: m.fax
+  };
+  if (json["id"] === undefined) {
+    json["id"] = "";
+  }
+  if (json["name"] === undefined) {
+    json["name"] = "";
+  }
+  if (json["balance"] === undefined) {
+    json["balance"] = 0;
+  }
+  if (json["score"] === undefined) {
+    json["score"] = 0;
+  }
+  if (json["created"] === undefined) {
+    json["created"] = "";
+  }
+  if (json["labels"] === undefined) {
+    json["labels"] = {};
+  }
+  if (json["emails"] === undefined) {
+    json["emails"] = [];
+  }
+  if (json["role"] === undefined) {
+    json["role"] = "MEMBER";
+  }
+  if (json["balance"] != null) {
+    json["balance"] = String(json["balance"]);
+  }
+  if (json["score"] != null) {
+    json["score"] = isFinite(json["score"]) ? json["score"] : String(json["score"]);
+  }
+  if (json["role"] != null) {
+    json["role"] = typeof json["role"] === "number" ? User_RoleNames[json["role"]] : json["role"];
+  }
+  return json;
+};
+
+export interface IGetUserRequest {
+  id?: string;
+
+  toJSON?(): object;
+}
+
+export interface IGetUserRequestJSON {
+  id?: string;
+  toJSON?(): object;
+}
+
+// createGetUserRequest returns an IGetUserRequest with the members set in m and
+// the zero values of the other fields, nested messages and oneof members are
+// left undefined.
+export const createGetUserRequest = (m: Partial<IGetUserRequest> = {}): IGetUserRequest => {
+  return {
+    id: m.id != null ? m.id : ""
+  };
+};
+
+// decodeGetUserRequest converts proto3 JSON to an IGetUserRequest, absent fields
+// get their zero values, except oneof members.
+export const decodeGetUserRequest = (m: IGetUserRequestJSON = {}): IGetUserRequest => {
+  return createGetUserRequest({
+    id: m["id"]
+  });
+};
+
+// encodeGetUserRequest converts an IGetUserRequest to its canonical proto3 JSON.
+export const encodeGetUserRequest = (m: IGetUserRequest): IGetUserRequestJSON => {
+  const json: any = {
+    id: m.id
+  };
+  if (json["id"] === undefined) {
+    json["id"] = "";
+  }
+  return json;
+};
+
+export interface IListUsersRequest {
+  pageSize?: number;
+
+  toJSON?(): object;
+}
+
+export interface IListUsersRequestJSON {
+  page_size?: number;
+  toJSON?(): object;
+}
+
+// createListUsersRequest returns an IListUsersRequest with the members set in m and
+// the zero values of the other fields, nested messages and oneof members are
+// left undefined.
+export const createListUsersRequest = (m: Partial<IListUsersRequest> = {}): IListUsersRequest => {
+  return {
+    pageSize: m.pageSize != null ? m.pageSize : 0
+  };
+};
+
+// decodeListUsersRequest converts proto3 JSON to an IListUsersRequest, absent fields
+// get their zero values, except oneof members.
+export const decodeListUsersRequest = (m: IListUsersRequestJSON = {}): IListUsersRequest => {
+  return createListUsersRequest({
+    pageSize: m["page_size"]
+  });
+};
+
+// encodeListUsersRequest converts an IListUsersRequest to its canonical proto3 JSON.
+export const encodeListUsersRequest = (m: IListUsersRequest): IListUsersRequestJSON => {
+  const json: any = {
+    page_size: m.pageSize
+  };
+  if (json["page_size"] === undefined) {
+    json["page_size"] = 0;
+  }
+  return json;
+};
+
+export interface IListUsersResponse {
+  users?: IUser[];
+
+  toJSON?(): object;
+}
+
+export interface IListUsersResponseJSON {
+  users?: IUserJSON[];
+  toJSON?(): object;
+}
+
+// createListUsersResponse returns an IListUsersResponse with the members set in m and
+// the zero values of the other fields, nested messages and oneof members are
+// left undefined.
+export const createListUsersResponse = (m: Partial<IListUsersResponse> = {}): IListUsersResponse => {
+  return {
+    users: m.users != null ? m.users : []
+  };
+};
+
+// decodeListUsersResponse converts proto3 JSON to an IListUsersResponse, absent fields
+// get their zero values, except oneof members.
+export const decodeListUsersResponse = (m: IListUsersResponseJSON = {}): IListUsersResponse => {
+  return createListUsersResponse({
+    users: (m["users"] || []).map(v => decodeUser(v))
+  });
+};
+
+// encodeListUsersResponse converts an IListUsersResponse to its canonical proto3 JSON.
+export const encodeListUsersResponse = (m: IListUsersResponse): IListUsersResponseJSON => {
+  const json: any = {
+    users: m.users
+  };
+  if (json["users"] === undefined) {
+    json["users"] = [];
+  }
+  if (json["users"] != null) {
+    json["users"] = json["users"].map((v: any) => encodeUser(v));
+  }
+  return json;
+};
+
+// Services
+// UsersGetUserRequest and UsersGetUserResponse are the input
+// and result types of Users.GetUser.
+export type UsersGetUserRequest = IGetUserRequest;
+export type UsersGetUserResponse = IUser;
+
+// UsersListUsersRequest and UsersListUsersResponse are the input
+// and result types of Users.ListUsers.
+export type UsersListUsersRequest = IListUsersRequest;
+export type UsersListUsersResponse = IListUsersResponse;
+
+// UsersMethods maps the Users methods to their input and output
+// types, for generic wrappers over the service. The output of streaming
+// methods is the type of their messages.
+export type UsersMethods = {
+  getUser: { input: UsersGetUserRequest; output: UsersGetUserResponse };
+  listUsers: { input: UsersListUsersRequest; output: UsersListUsersResponse };
+};
+
+export interface IUsers {
+  /**
+   * GetUser returns a user by ID.
+   */
+  getUser: (
+    data: IGetUserRequest,
+    headers?: object,
+    options?: CallOptions
+  ) => Promise<IUser>;
+  listUsers: (
+    data: IListUsersRequest,
+    headers?: object,
+    options?: CallOptions
+  ) => Promise<IListUsersResponse>;
+}
+
+// usersGetUser calls Users.GetUser with the options
+// of a client.
+/**
+ * GetUser returns a user by ID.
+ */
+export const usersGetUser = (
+  client: ClientOptions,
+  params: IGetUserRequest,
+  headers: object = {},
+  options: CallOptions = {}
+): Promise<IUser> => {
+  return callTwirpMethod(client, "acme.users.Users", "GetUser", encodeGetUserRequest(params), headers, options, readTwirpResponse).then(m => {
+    return decodeUser(m);
+  });
+};
+
+// usersListUsers calls Users.ListUsers with the options
+// of a client.
+export const usersListUsers = (
+  client: ClientOptions,
+  params: IListUsersRequest,
+  headers: object = {},
+  options: CallOptions = {}
+): Promise<IListUsersResponse> => {
+  return callTwirpMethod(client, "acme.users.Users", "ListUsers", encodeListUsersRequest(params), headers, options, readTwirpResponse).then(m => {
+    return decodeListUsersResponse(m);
+  });
+};
@@ -0,0 +1,199 @@
+/* eslint-disable */
+
+// This file has been generated by https://github.com/horizon-games/protoc-gen-twirp_ts.
+// Do not edit.
+//
+// versions:
+//   protoc-gen-twirp_ts (unknown)
+//   protoc (unknown)
+// source: names.proto
+
+import { DeepPartial } from "../../twirp";
+
+/**
+ * Names has fields named like the generated members of message classes and
+ * TypeScript keywords.
+ */
+export interface INames {
+  name?: string | undefined;
+  hasName_?: string;
+  clearName_?: string;
+  clone_?: string;
+  equals_?: string;
+  default_?: string;
+  with_?: string;
+
+  toJSON?(): object;
+}
+
+export interface INamesJSON {
+  name?: string | undefined;
+  has_name?: string;
+  clear_name?: string;
+  clone?: string;
+  equals?: string;
+  default?: string;
+  with?: string;
+  toJSON?(): object;
+}
+
+/**
+ * Names has fields named like the generated members of message classes and
+ * TypeScript keywords.
+ */
+export class Names implements INames {
+  private _json: INamesJSON;
+
+  constructor(m?: INames) {
+    this._json = {};
+    if (m) {
+      this._json["name"] = m.name;
+      this._json["has_name"] = m.hasName_;
+      this._json["clear_name"] = m.clearName_;
+      this._json["clone"] = m.clone_;
+      this._json["equals"] = m.equals_;
+      this._json["default"] = m.default_;
+      this._json["with"] = m.with_;
+    }
+  }
+
+  // name (name)
+  public get name(): string | undefined {
+    return this._json.name;
+  }
+  public set name(value: string | undefined) {
+    this._json.name = value;
+  }
+  public hasName(): boolean {
+    return this._json.name != null;
+  }
+  public clearName() {
+    delete this._json.name;
+  }
+
+  // hasName_ (has_name)
+  public get hasName_(): string {
+    return this._json.has_name != null ? this._json.has_name : "";
+  }
+  public set hasName_(value: string) {
+    this._json.has_name = value;
+  }
+
+  // clearName_ (clear_name)
+  public get clearName_(): string {
+    return this._json.clear_name != null ? this._json.clear_name : "";
+  }
+  public set clearName_(value: string) {
+    this._json.clear_name = value;
+  }
+
+  // clone_ (clone)
+  public get clone_(): string {
+    return this._json.clone != null ? this._json.clone : "";
+  }
+  public set clone_(value: string) {
+    this._json.clone = value;
+  }
+
+  // equals_ (equals)
+  public get equals_(): string {
+    return this._json.equals != null ? this._json.equals : "";
+  }
+  public set equals_(value: string) {
+    this._json.equals = value;
+  }
+
+  // default_ (default)
+  public get default_(): string {
+    return this._json.default != null ? this._json.default : "";
+  }
+  public set default_(value: string) {
+    this._json.default = value;
+  }
+
+  // with_ (with)
+  public get with_(): string {
+    return this._json.with != null ? this._json.with : "";
+  }
+  public set with_(value: string) {
+    this._json.with = value;
+  }
+
+  static fromJSON(m: INamesJSON = {}): Names {
+    const v = new Names({
+      name: m["name"] == null ? undefined : m["name"],
+      hasName_: m["has_name"],
+      clearName_: m["clear_name"],
+      clone_: m["clone"],
+      equals_: m["equals"],
+      default_: m["default"],
+      with_: m["with"]
+    });
+    // Preserve unknown fields (and extensions) so they survive a round-trip.
+    const known: string[] = ["name", "has_name", "clear_name", "clone", "equals", "default", "with"];
+    Object.keys(m).forEach(k => {
+      if (known.indexOf(k) < 0) {
+        (<any>v._json)[k] = (<any>m)[k];
+      }
+    });
+    return v;
+  }
+
+  // create returns a Names with the members set in m and the zero values
+  // of the other fields, nested messages and oneof members are left
+  // undefined.
+  static create(m: Partial<INames> = {}): Names {
+    return new Names({
+      name: m.name,
+      hasName_: m.hasName_ != null ? m.hasName_ : "",
+      clearName_: m.clearName_ != null ? m.clearName_ : "",
+      clone_: m.clone_ != null ? m.clone_ : "",
+      equals_: m.equals_ != null ? m.equals_ : "",
+      default_: m.default_ != null ? m.default_ : "",
+      with_: m.with_ != null ? m.with_ : ""
+    });
+  }
+
+  // fromPartial builds a Names from the members that are set in m,
+  // filling zero values for the others, except oneof members, and building
+  // nested messages.
+  static fromPartial(m: DeepPartial<INames> = {}): Names {
+    return new Names({
+      name: m.name,
+      hasName_: m.hasName_ != null ? m.hasName_ : "",
+      clearName_: m.clearName_ != null ? m.clearName_ : "",
+      clone_: m.clone_ != null ? m.clone_ : "",
+      equals_: m.equals_ != null ? m.equals_ : "",
+      default_: m.default_ != null ? m.default_ : "",
+      with_: m.with_ != null ? m.with_ : ""
+    });
+  }
+
+  public toJSON(): object {
+    return this._json;
+  }
+
+  // clone returns a deep copy of the message sharing no state with it, the
+  // nested messages are cloned by their classes.
+  public clone(): Names {
+    const json: any = Object.assign({}, this._json);
+    const m = new Names();
+    m._json = json;
+    return m;
+  }
+
+  // equals reports whether other has the same field values, unset fields
+  // being equal to their zero values.
+  public equals(other: INames): boolean {
+    const o = other instanceof Names ? other : new Names(other);
+    return (
+      this.name === o.name &&
+      this.hasName_ === o.hasName_ &&
+      this.clearName_ === o.clearName_ &&
+      this.clone_ === o.clone_ &&
+      this.equals_ === o.equals_ &&
+      this.default_ === o.default_ &&
+      this.with_ === o.with_
+    );
+  }
+}
@@ -0,0 +1,17 @@
+/* eslint-disable */
+
+// This file has been generated by https://github.com/horizon-games/protoc-gen-twirp_ts.
+// Do not edit.
+//
+// versions:
+//   protoc-gen-twirp_ts (unknown)
+//   protoc (unknown)
+
+export {
+  Names,
+  NamesBuilder
+} from "./names";
+export type {
+  INames,
+  INamesJSON
+} from "./names";
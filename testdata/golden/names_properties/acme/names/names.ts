@@ -0,0 +1,188 @@
+/* eslint-disable */
+
+// This file has been generated by https://github.com/horizon-games/protoc-gen-twirp_ts.
+// Do not edit.
+//
+// versions:
+//   protoc-gen-twirp_ts (unknown)
+//   protoc (unknown)
+// source: names.proto
+
+import { DeepPartial, unknownFields } from "../../twirp";
+
+/**
+ * Names has fields named like the generated members of message classes and
+ * TypeScript keywords.
+ */
+export interface INames {
+  name?: string | undefined;
+  hasName_?: string;
+  has_name?: string;
+  clearName_?: string;
+  clear_name?: string;
+  clone_?: string;
+  equals_?: string;
+  default_?: string;
+  with_?: string;
+
+  toJSON?(): object;
+}
+
+export interface INamesJSON {
+  name?: string | undefined;
+  has_name?: string;
+  clear_name?: string;
+  clone?: string;
+  equals?: string;
+  default?: string;
+  with?: string;
+  toJSON?(): object;
+}
+
+/**
+ * Names has fields named like the generated members of message classes and
+ * TypeScript keywords.
+ */
+export class Names implements INames {
+  public name: string | undefined;
+  public hasName_: string;
+  public clearName_: string;
+  public clone_: string;
+  public equals_: string;
+  public default_: string;
+  public with_: string;
+
+  constructor(m: INames = {}) {
+    this.name = m.name;
+    this.hasName_ = (m.hasName_ !== undefined ? m.hasName_ : m.has_name) != null ? (m.hasName_ !== undefined ? m.hasName_ : m.has_name) : "";
+    this.clearName_ = (m.clearName_ !== undefined ? m.clearName_ : m.clear_name) != null ? (m.clearName_ !== undefined ? m.clearName_ : m.clear_name) : "";
+    this.clone_ = m.clone_ != null ? m.clone_ : "";
+    this.equals_ = m.equals_ != null ? m.equals_ : "";
+    this.default_ = m.default_ != null ? m.default_ : "";
+    this.with_ = m.with_ != null ? m.with_ : "";
+  }
+
+  public hasName(): boolean {
+    return this.name != null;
+  }
+  public clearName() {
+    this.name = undefined;
+  }
+
+  public get has_name(): string {
+    return this.hasName_;
+  }
+  public set has_name(value: string) {
+    this.hasName_ = value;
+  }
+
+  public get clear_name(): string {
+    return this.clearName_;
+  }
+  public set clear_name(value: string) {
+    this.clearName_ = value;
+  }
+
+  static fromJSON(m: INamesJSON = {}): Names {
+    const v = new Names({
+      name: m["name"] == null ? undefined : m["name"],
+      hasName_: m["has_name"],
+      clearName_: m["clear_name"],
+      clone_: m["clone"],
+      equals_: m["equals"],
+      default_: m["default"],
+      with_: m["with"]
+    });
+    // Preserve unknown fields (and extensions) so they survive a round-trip.
+    const known: string[] = ["name", "has_name", "clear_name", "clone", "equals", "default", "with"];
+    Object.keys(m).forEach(k => {
+      if (known.indexOf(k) < 0) {
+        unknownFields(v)[k] = (<any>m)[k];
+      }
+    });
+    return v;
+  }
+
+  // create returns a Names with the members set in m and the zero values
+  // of the other fields, nested messages and oneof members are left
+  // undefined.
+  static create(m: Partial<INames> = {}): Names {
+    return new Names({
+      name: m.name,
+      hasName_: m.hasName_ != null ? m.hasName_ : "",
+      clearName_: m.clearName_ != null ? m.clearName_ : "",
+      clone_: m.clone_ != null ? m.clone_ : "",
+      equals_: m.equals_ != null ? m.equals_ : "",
+      default_: m.default_ != null ? m.default_ : "",
+      with_: m.with_ != null ? m.with_ : ""
+    });
+  }
+
+  // fromPartial builds a Names from the members that are set in m,
+  // filling zero values for the others, except oneof members, and building
+  // nested messages.
+  static fromPartial(m: DeepPartial<INames> = {}): Names {
+    return new Names({
+      name: m.name,
+      hasName_: m.hasName_ != null ? m.hasName_ : "",
+      clearName_: m.clearName_ != null ? m.clearName_ : "",
+      clone_: m.clone_ != null ? m.clone_ : "",
+      equals_: m.equals_ != null ? m.equals_ : "",
+      default_: m.default_ != null ? m.default_ : "",
+      with_: m.with_ != null ? m.with_ : ""
+    });
+  }
+
+  public toJSON(): object {
+    const json: any = Object.assign({}, unknownFields(this), {
+      name: this.name,
+      has_name: this.hasName_ !== "" ? this.hasName_ : undefined,
+      clear_name: this.clearName_ !== "" ? this.clearName_ : undefined,
+      clone: this.clone_ !== "" ? this.clone_ : undefined,
+      equals: this.equals_ !== "" ? this.equals_ : undefined,
+      default: this.default_ !== "" ? this.default_ : undefined,
+      with: this.with_ !== "" ? this.with_ : undefined
+    });
+    return json;
+  }
+
+  // clone returns a deep copy of the message sharing no state with it, the
+  // nested messages are cloned by their classes.
+  public clone(): Names {
+    const json: any = {
+      name: this.name,
+      has_name: this.hasName_ !== "" ? this.hasName_ : undefined,
+      clear_name: this.clearName_ !== "" ? this.clearName_ : undefined,
+      clone: this.clone_ !== "" ? this.clone_ : undefined,
+      equals: this.equals_ !== "" ? this.equals_ : undefined,
+      default: this.default_ !== "" ? this.default_ : undefined,
+      with: this.with_ !== "" ? this.with_ : undefined
+    };
+    const m = new Names({
+      name: json["name"],
+      hasName_: json["has_name"],
+      clearName_: json["clear_name"],
+      clone_: json["clone"],
+      equals_: json["equals"],
+      default_: json["default"],
+      with_: json["with"]
+    });
+    Object.assign(unknownFields(m), unknownFields(this));
+    return m;
+  }
+
+  // equals reports whether other has the same field values, unset fields
+  // being equal to their zero values.
+  public equals(other: INames): boolean {
+    const o = other instanceof Names ? other : new Names(other);
+    return (
+      this.name === o.name &&
+      this.hasName_ === o.hasName_ &&
+      this.clearName_ === o.clearName_ &&
+      this.clone_ === o.clone_ &&
+      this.equals_ === o.equals_ &&
+      this.default_ === o.default_ &&
+      this.with_ === o.with_
+    );
+  }
+}
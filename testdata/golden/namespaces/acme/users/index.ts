@@ -0,0 +1,44 @@
+/* eslint-disable */
+
+// This file has been generated by https://github.com/horizon-games/protoc-gen-twirp_ts.
+// Do not edit.
+//
+// versions:
+//   protoc-gen-twirp_ts (unknown)
+//   protoc (unknown)
+
+export {
+  User_Role,
+  User_RoleValues,
+  User_RoleNames,
+  User,
+  GetUserRequest,
+  ListUsersRequest,
+  ListUsersResponse,
+  Users
+} from "./users";
+export type {
+  IUser,
+  IUserJSON,
+  IGetUserRequest,
+  IGetUserRequestJSON,
+  IListUsersRequest,
+  IListUsersRequestJSON,
+  IListUsersResponse,
+  IListUsersResponseJSON,
+  IUsers,
+  UsersMethods,
+  UsersGetUserRequest,
+  UsersGetUserResponse,
+  UsersListUsersRequest,
+  UsersListUsersResponse
+} from "./users";
+
+import { User as _acme_users_User, GetUserRequest as _acme_users_GetUserRequest, ListUsersRequest as _acme_users_ListUsersRequest, ListUsersResponse as _acme_users_ListUsersResponse } from "./users";
+
+export namespace acme.users {
+  export import User = _acme_users_User;
+  export import GetUserRequest = _acme_users_GetUserRequest;
+  export import ListUsersRequest = _acme_users_ListUsersRequest;
+  export import ListUsersResponse = _acme_users_ListUsersResponse;
+}
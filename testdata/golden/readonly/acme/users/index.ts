@@ -0,0 +1,39 @@
+/* eslint-disable */
+
+// This file has been generated by https://github.com/horizon-games/protoc-gen-twirp_ts.
+// Do not edit.
+//
+// versions:
+//   protoc-gen-twirp_ts (unknown)
+//   protoc (unknown)
+
+export {
+  User_Role,
+  User_RoleValues,
+  User_RoleNames,
+  User,
+  GetUserRequest,
+  ListUsersRequest,
+  ListUsersResponse,
+  Users
+} from "./users";
+export type {
+  IUser,
+  IUserJSON,
+  ReadonlyIUser,
+  IGetUserRequest,
+  IGetUserRequestJSON,
+  ReadonlyIGetUserRequest,
+  IListUsersRequest,
+  IListUsersRequestJSON,
+  ReadonlyIListUsersRequest,
+  IListUsersResponse,
+  IListUsersResponseJSON,
+  ReadonlyIListUsersResponse,
+  IUsers,
+  UsersMethods,
+  UsersGetUserRequest,
+  UsersGetUserResponse,
+  UsersListUsersRequest,
+  UsersListUsersResponse
+} from "./users";
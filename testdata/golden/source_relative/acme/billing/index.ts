@@ -0,0 +1,16 @@
+/* eslint-disable */
+
+// This file has been generated by https://github.com/horizon-games/protoc-gen-twirp_ts.
+// Do not edit.
+//
+// versions:
+//   protoc-gen-twirp_ts (unknown)
+//   protoc (unknown)
+
+export {
+  Invoice
+} from "./types";
+export type {
+  IInvoice,
+  IInvoiceJSON
+} from "./types";
@@ -0,0 +1,124 @@
+/* eslint-disable */
+
+// This file has been generated by https://github.com/horizon-games/protoc-gen-twirp_ts.
+// Do not edit.
+//
+// versions:
+//   protoc-gen-twirp_ts (unknown)
+//   protoc (unknown)
+// source: acme/billing/types.proto
+
+import { Money } from "../../acme/common";
+import { DeepPartial } from "../../twirp";
+
+export interface IInvoice {
+  id?: string;
+  total?: Money;
+
+  toJSON?(): object;
+}
+
+export interface IInvoiceJSON {
+  id?: string;
+  total?: Money;
+  toJSON?(): object;
+}
+
+export class Invoice implements IInvoice {
+  private _json: IInvoiceJSON;
+
+  constructor(m?: IInvoice) {
+    this._json = {};
+    if (m) {
+      this._json["id"] = m.id;
+      this._json["total"] = m.total;
+    }
+  }
+
+  // id (id)
+  public get id(): string {
+    return this._json.id != null ? this._json.id : "";
+  }
+  public set id(value: string) {
+    this._json.id = value;
+  }
+
+  // total (total)
+  public get total(): Money | undefined {
+    return this._json.total;
+  }
+  public set total(value: Money | undefined) {
+    this._json.total = value;
+  }
+  public hasTotal(): boolean {
+    return this._json.total != null;
+  }
+  public clearTotal() {
+    delete this._json.total;
+  }
+
+  static fromJSON(m: IInvoiceJSON = {}): Invoice {
+    const v = new Invoice({
+      id: m["id"],
+      total: m["total"] == null ? undefined : Money.fromJSON(m["total"])
+    });
+    // Preserve unknown fields (and extensions) so they survive a round-trip.
+    const known: string[] = ["id", "total"];
+    Object.keys(m).forEach(k => {
+      if (known.indexOf(k) < 0) {
+        (<any>v._json)[k] = (<any>m)[k];
+      }
+    });
+    return v;
+  }
+
+  // create returns a Invoice with the members set in m and the zero values
+  // of the other fields, nested messages and oneof members are left
+  // undefined.
+  static create(m: Partial<IInvoice> = {}): Invoice {
+    return new Invoice({
+      id: m.id != null ? m.id : "",
+      total: m.total
+    });
+  }
+
+  // fromPartial builds a Invoice from the members that are set in m,
+  // filling zero values for the others, except oneof members, and building
+  // nested messages.
+  static fromPartial(m: DeepPartial<IInvoice> = {}): Invoice {
+    return new Invoice({
+      id: m.id != null ? m.id : "",
+      total: m.total == null ? undefined : Money.fromPartial(m.total)
+    });
+  }
+
+  public toJSON(): object {
+    const json: any = Object.assign({}, this._json);
+    if (json["total"] != null) {
+      json["total"] = (json["total"] instanceof Money ? json["total"] : new Money(json["total"])).toJSON();
+    }
+    return json;
+  }
+
+  // clone returns a deep copy of the message sharing no state with it, the
+  // nested messages are cloned by their classes.
+  public clone(): Invoice {
+    const json: any = Object.assign({}, this._json);
+    if (json["total"] != null) {
+      json["total"] = (json["total"] instanceof Money ? json["total"] : new Money(json["total"])).clone();
+    }
+    const m = new Invoice();
+    m._json = json;
+    return m;
+  }
+
+  // equals reports whether other has the same field values, unset fields
+  // being equal to their zero values.
+  public equals(other: IInvoice): boolean {
+    const o = other instanceof Invoice ? other : new Invoice(other);
+    return (
+      this.id === o.id &&
+      (this.total == null || o.total == null ? this.total == o.total : (this.total instanceof Money ? this.total : new Money(this.total)).equals(o.total))
+    );
+  }
+}
@@ -0,0 +1,114 @@
+/* eslint-disable */
+
+// This file has been generated by https://github.com/horizon-games/protoc-gen-twirp_ts.
+// Do not edit.
+//
+// versions:
+//   protoc-gen-twirp_ts (unknown)
+//   protoc (unknown)
+// source: acme/common/types.proto
+
+import { DeepPartial } from "../../twirp";
+
+export interface IMoney {
+  currency?: string;
+  units?: number;
+
+  toJSON?(): object;
+}
+
+export interface IMoneyJSON {
+  currency?: string;
+  units?: number;
+  toJSON?(): object;
+}
+
+export class Money implements IMoney {
+  private _json: IMoneyJSON;
+
+  constructor(m?: IMoney) {
+    this._json = {};
+    if (m) {
+      this._json["currency"] = m.currency;
+      this._json["units"] = m.units;
+    }
+  }
+
+  // currency (currency)
+  public get currency(): string {
+    return this._json.currency != null ? this._json.currency : "";
+  }
+  public set currency(value: string) {
+    this._json.currency = value;
+  }
+
+  // units (units)
+  public get units(): number {
+    return this._json.units != null ? this._json.units : 0;
+  }
+  public set units(value: number) {
+    this._json.units = value;
+  }
+
+  static fromJSON(m: IMoneyJSON = {}): Money {
+    const v = new Money({
+      currency: m["currency"],
+      units: m["units"]
+    });
+    // Preserve unknown fields (and extensions) so they survive a round-trip.
+    const known: string[] = ["currency", "units"];
+    Object.keys(m).forEach(k => {
+      if (known.indexOf(k) < 0) {
+        (<any>v._json)[k] = (<any>m)[k];
+      }
+    });
+    return v;
+  }
+
+  // create returns a Money with the members set in m and the zero values
+  // of the other fields, nested messages and oneof members are left
+  // undefined.
+  static create(m: Partial<IMoney> = {}): Money {
+    return new Money({
+      currency: m.currency != null ? m.currency : "",
+      units: m.units != null ? m.units : 0
+    });
+  }
+
+  // fromPartial builds a Money from the members that are set in m,
+  // filling zero values for the others, except oneof members, and building
+  // nested messages.
+  static fromPartial(m: DeepPartial<IMoney> = {}): Money {
+    return new Money({
+      currency: m.currency != null ? m.currency : "",
+      units: m.units != null ? m.units : 0
+    });
+  }
+
+  public toJSON(): object {
+    const json: any = Object.assign({}, this._json);
+    if (json["units"] != null) {
+      json["units"] = String(json["units"]);
+    }
+    return json;
+  }
+
+  // clone returns a deep copy of the message sharing no state with it, the
+  // nested messages are cloned by their classes.
+  public clone(): Money {
+    const json: any = Object.assign({}, this._json);
+    const m = new Money();
+    m._json = json;
+    return m;
+  }
+
+  // equals reports whether other has the same field values, unset fields
+  // being equal to their zero values.
+  public equals(other: IMoney): boolean {
+    const o = other instanceof Money ? other : new Money(other);
+    return (
+      this.currency === o.currency &&
+      String(this.units) === String(o.units)
+    );
+  }
+}
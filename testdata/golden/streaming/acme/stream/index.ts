@@ -0,0 +1,24 @@
+/* eslint-disable */
+
+// This file has been generated by https://github.com/horizon-games/protoc-gen-twirp_ts.
+// Do not edit.
+//
+// versions:
+//   protoc-gen-twirp_ts (unknown)
+//   protoc (unknown)
+
+export {
+  WatchRequest,
+  Event,
+  Events
+} from "./stream";
+export type {
+  IWatchRequest,
+  IWatchRequestJSON,
+  IEvent,
+  IEventJSON,
+  IEvents,
+  EventsMethods,
+  EventsWatchRequest,
+  EventsWatchResponse
+} from "./stream";
@@ -0,0 +1,269 @@
+/* eslint-disable */
+
+// This file has been generated by https://github.com/horizon-games/protoc-gen-twirp_ts.
+// Do not edit.
+//
+// versions:
+//   protoc-gen-twirp_ts (unknown)
+//   protoc (unknown)
+// source: stream.proto
+
+import { CallOptions, clientFetch, ClientOptions, createTwirpRequest, DeepPartial, defaultFetch, Fetch, mergeOptions, preconnect, readTwirpStream, resolveBaseURL, throwTwirpError, twirpCall } from "../../twirp";
+
+export interface IWatchRequest {
+  topic?: string;
+
+  toJSON?(): object;
+}
+
+export interface IWatchRequestJSON {
+  topic?: string;
+  toJSON?(): object;
+}
+
+export class WatchRequest implements IWatchRequest {
+  private _json: IWatchRequestJSON;
+
+  constructor(m?: IWatchRequest) {
+    this._json = {};
+    if (m) {
+      this._json["topic"] = m.topic;
+    }
+  }
+
+  // topic (topic)
+  public get topic(): string {
+    return this._json.topic != null ? this._json.topic : "";
+  }
+  public set topic(value: string) {
+    this._json.topic = value;
+  }
+
+  static fromJSON(m: IWatchRequestJSON = {}): WatchRequest {
+    const v = new WatchRequest({
+      topic: m["topic"]
+    });
+    // Preserve unknown fields (and extensions) so they survive a round-trip.
+    const known: string[] = ["topic"];
+    Object.keys(m).forEach(k => {
+      if (known.indexOf(k) < 0) {
+        (<any>v._json)[k] = (<any>m)[k];
+      }
+    });
+    return v;
+  }
+
+  // create returns a WatchRequest with the members set in m and the zero values
+  // of the other fields, nested messages and oneof members are left
+  // undefined.
+  static create(m: Partial<IWatchRequest> = {}): WatchRequest {
+    return new WatchRequest({
+      topic: m.topic != null ? m.topic : ""
+    });
+  }
+
+  // fromPartial builds a WatchRequest from the members that are set in m,
+  // filling zero values for the others, except oneof members, and building
+  // nested messages.
+  static fromPartial(m: DeepPartial<IWatchRequest> = {}): WatchRequest {
+    return new WatchRequest({
+      topic: m.topic != null ? m.topic : ""
+    });
+  }
+
+  public toJSON(): object {
+    return this._json;
+  }
+
+  // clone returns a deep copy of the message sharing no state with it, the
+  // nested messages are cloned by their classes.
+  public clone(): WatchRequest {
+    const json: any = Object.assign({}, this._json);
+    const m = new WatchRequest();
+    m._json = json;
+    return m;
+  }
+
+  // equals reports whether other has the same field values, unset fields
+  // being equal to their zero values.
+  public equals(other: IWatchRequest): boolean {
+    const o = other instanceof WatchRequest ? other : new WatchRequest(other);
+    return this.topic === o.topic;
+  }
+}
+
+export interface IEvent {
+  topic?: string;
+  data?: string;
+
+  toJSON?(): object;
+}
+
+export interface IEventJSON {
+  topic?: string;
+  data?: string;
+  toJSON?(): object;
+}
+
+export class Event implements IEvent {
+  private _json: IEventJSON;
+
+  constructor(m?: IEvent) {
+    this._json = {};
+    if (m) {
+      this._json["topic"] = m.topic;
+      this._json["data"] = m.data;
+    }
+  }
+
+  // topic (topic)
+  public get topic(): string {
+    return this._json.topic != null ? this._json.topic : "";
+  }
+  public set topic(value: string) {
+    this._json.topic = value;
+  }
+
+  // data (data)
+  public get data(): string {
+    return this._json.data != null ? this._json.data : "";
+  }
+  public set data(value: string) {
+    this._json.data = value;
+  }
+
+  static fromJSON(m: IEventJSON = {}): Event {
+    const v = new Event({
+      topic: m["topic"],
+      data: m["data"]
+    });
+    // Preserve unknown fields (and extensions) so they survive a round-trip.
+    const known: string[] = ["topic", "data"];
+    Object.keys(m).forEach(k => {
+      if (known.indexOf(k) < 0) {
+        (<any>v._json)[k] = (<any>m)[k];
+      }
+    });
+    return v;
+  }
+
+  // create returns a Event with the members set in m and the zero values
+  // of the other fields, nested messages and oneof members are left
+  // undefined.
+  static create(m: Partial<IEvent> = {}): Event {
+    return new Event({
+      topic: m.topic != null ? m.topic : "",
+      data: m.data != null ? m.data : ""
+    });
+  }
+
+  // fromPartial builds a Event from the members that are set in m,
+  // filling zero values for the others, except oneof members, and building
+  // nested messages.
+  static fromPartial(m: DeepPartial<IEvent> = {}): Event {
+    return new Event({
+      topic: m.topic != null ? m.topic : "",
+      data: m.data != null ? m.data : ""
+    });
+  }
+
+  public toJSON(): object {
+    return this._json;
+  }
+
+  // clone returns a deep copy of the message sharing no state with it, the
+  // nested messages are cloned by their classes.
+  public clone(): Event {
+    const json: any = Object.assign({}, this._json);
+    const m = new Event();
+    m._json = json;
+    return m;
+  }
+
+  // equals reports whether other has the same field values, unset fields
+  // being equal to their zero values.
+  public equals(other: IEvent): boolean {
+    const o = other instanceof Event ? other : new Event(other);
+    return (
+      this.topic === o.topic &&
+      this.data === o.data
+    );
+  }
+}
+
+// Services
+// EventsWatchRequest and EventsWatchResponse are the input
+// and result types of Events.Watch.
+export type EventsWatchRequest = WatchRequest;
+export type EventsWatchResponse = Event;
+
+// EventsMethods maps the Events methods to their input and output
+// types, for generic wrappers over the service. The output of streaming
+// methods is the type of their messages.
+export type EventsMethods = {
+  watch: { input: EventsWatchRequest; output: EventsWatchResponse };
+};
+
+export interface IEvents {
+  /**
+   * Watch streams the events of a topic.
+   */
+  watch: (
+    data: WatchRequest,
+    headers?: object,
+    options?: CallOptions
+  ) => AsyncIterable<Event>;
+}
+
+export class Events implements IEvents {
+  private hostname: string;
+  private fetch: Fetch;
+  private options: ClientOptions;
+  private path: string;
+
+  constructor(options: ClientOptions);
+  constructor(hostname: string, fetch: Fetch, options?: ClientOptions);
+  constructor(hostname: string | ClientOptions, fetch?: Fetch, options: ClientOptions = {}) {
+    if (typeof hostname !== "string") {
+      options = hostname;
+      hostname = options.baseURL == null ? resolveBaseURL("acme.stream.Events") : options.baseURL;
+    }
+    this.hostname = hostname;
+    this.fetch = clientFetch(options, fetch || options.fetch || defaultFetch);
+    this.options = options;
+    this.path = (options.pathPrefix == null ? "/twirp" : options.pathPrefix) + "/acme.stream.Events/";
+  }
+
+  // warmup opens a connection to the server ahead of the first call, see
+  // preconnect.
+  public warmup(): void {
+    preconnect(this.hostname, this.options);
+  }
+
+  private url(name: string): string {
+    return this.hostname + this.path + name;
+  }
+
+  private fetchFor(options: CallOptions): Fetch {
+    return options.fetch ? clientFetch(this.options, options.fetch) : this.fetch;
+  }
+
+  /**
+   * Watch streams the events of a topic.
+   */
+  public watch(
+    params: WatchRequest,
+    headers: object = {},
+    options: CallOptions = {}
+  ): AsyncIterable<Event> {
+    const open = () => {
+      return twirpCall(mergeOptions(this.options, "Watch", options), callOptions =>
+        this.fetchFor(callOptions)(
+          this.url("Watch"),
+          createTwirpRequest(params, headers, callOptions)
+        ).then(res => (res.ok ? res : throwTwirpError(res)))
+      );
+    };
+    return readTwirpStream(open, m => Event.fromJSON(m));
+  }
+}
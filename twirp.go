@@ -3,52 +3,1863 @@ package main
 var twirpFileName = "twirp.ts"
 
 // based on https://github.com/larrymyers/protoc-gen-twirp_typescript/blob/master/example/ts_client/twirp.ts
-var twirpSource = `/* eslint-disable */
+var twirpTemplate = `{{with lintHeader}}{{.}}
 
-// This file has been generated by https://github.com/horizon-games/protoc-gen-twirp_ts.
-// Do not edit.
+{{end -}}
+{{banner ""}}
+{{- with .FetchModule}}
+
+import {{if eq $.FetchExport "default"}}fetch{{else if eq $.FetchExport "fetch"}}{ fetch }{{else}}{ {{$.FetchExport}} as fetch }{{end}} from "{{.}}";
+{{- end}}
+{{- if and .Server (eq .ServerFramework "fastify")}}
+
+import { FastifyInstance } from "fastify";
+{{- end}}
+{{- if .Msw}}
+
+import { rest } from "msw";
+{{- end}}
+{{- if .Vue}}
+
+import { Ref, shallowRef } from "vue";
+{{- end}}
+
+// TwirpErrorCode are the error codes of the Twirp protocol.
+export enum TwirpErrorCode {
+  Canceled = "canceled",
+  Unknown = "unknown",
+  InvalidArgument = "invalid_argument",
+  Malformed = "malformed",
+  DeadlineExceeded = "deadline_exceeded",
+  NotFound = "not_found",
+  BadRoute = "bad_route",
+  AlreadyExists = "already_exists",
+  PermissionDenied = "permission_denied",
+  Unauthenticated = "unauthenticated",
+  ResourceExhausted = "resource_exhausted",
+  FailedPrecondition = "failed_precondition",
+  Aborted = "aborted",
+  OutOfRange = "out_of_range",
+  Unimplemented = "unimplemented",
+  Internal = "internal",
+  Unavailable = "unavailable",
+  DataLoss = "data_loss"
+}
 
 export interface TwirpErrorJSON {
   code: string;
   msg: string;
-  meta: {
+  meta?: {
     [index: string]: string;
   };
 }
 
-export class TwirpError extends Error {
-  code: string;
-  meta: {
-    [index: string]: string;
-  };
+// TwirpError is the error generated client methods reject with, M is the
+// type of its meta, see the error_meta_option parameter.
+export class TwirpError<M extends object = { [index: string]: string }> extends Error {
+  code: TwirpErrorCode;
+  msg: string;
+  meta: M;
 
   constructor(te: TwirpErrorJSON) {
     super(te.msg);
+    // Keep instanceof working when compiled to ES5.
+    Object.setPrototypeOf(this, new.target.prototype);
 
-    this.code = te.code;
-    this.meta = te.meta;
+    this.name = "TwirpError";
+    this.code = <TwirpErrorCode>te.code;
+    this.msg = te.msg;
+    this.meta = <M>(<any>te.meta || {});
   }
 }
 
-export const throwTwirpError = (resp: Response) => {
-  return resp.json().then((err: TwirpErrorJSON) => {
+// httpErrorCode returns the error code of an HTTP status, for errors that
+// aren't Twirp errors, e.g. from a proxy. Twirp v5 servers send
+// resource_exhausted errors with the status 403, see the twirp_version
+// parameter.
+const httpErrorCode = (status: number): TwirpErrorCode => {
+  switch (status) {
+    case 401:
+      return TwirpErrorCode.Unauthenticated;
+    case 403:
+      return TwirpErrorCode.{{if eq .TwirpVersion "v5"}}ResourceExhausted{{else}}PermissionDenied{{end}};
+    case 404:
+      return TwirpErrorCode.BadRoute;
+    case 429:
+    case 502:
+    case 503:
+    case 504:
+      return TwirpErrorCode.Unavailable;
+  }
+  return status >= 300 && status <= 400 ? TwirpErrorCode.Internal : TwirpErrorCode.Unknown;
+};
+
+{{- if eq .TwirpVersion "v7"}}
+
+// isTwirpErrorCode reports whether code is an error code of the Twirp
+// protocol.
+const isTwirpErrorCode = (code: string): boolean =>
+  Object.keys(TwirpErrorCode).some(name => (<any>TwirpErrorCode)[name] === code);
+{{- end}}
+
+export const throwTwirpError = (resp: {{if .FetchModule}}FetchResponse{{else}}Response{{end}}): Promise<never> => {
+  return resp.text().then(text => {
+    let err: TwirpErrorJSON | undefined;
+    try {
+      err = JSON.parse(text);
+    } catch (e) {
+      // Not a Twirp error, see below.
+    }
+    if (!err || typeof err.code !== "string") {
+      const meta: { [index: string]: string } = {
+        http_error_from_intermediary: "true",
+        status_code: String(resp.status),
+        body: text
+      };
+      err = {
+        code: httpErrorCode(resp.status),
+        msg: "HTTP status " + resp.status + " without a Twirp error",
+        meta
+      };
+      {{- if eq .TwirpVersion "v7"}}
+      // Redirects aren't followed, see createTwirpRequest. Browsers hide
+      // them in opaque responses with the status 0.
+      if (resp.status === 0 || (resp.status >= 300 && resp.status < 400)) {
+        err.code = TwirpErrorCode.Internal;
+        err.msg = "unexpected redirect" + (resp.status ? " with HTTP status " + resp.status : "");
+        meta.location = resp.headers.get("Location") || "";
+      }
+      {{- end}}
+    }
+    {{- if eq .TwirpVersion "v7"}}
+    // Like the Twirp v7 clients, codes of other versions of the protocol are
+    // internal errors, Twirp v5 clients keep them.
+    if (!isTwirpErrorCode(err.code)) {
+      err = {
+        code: TwirpErrorCode.Internal,
+        msg: "invalid Twirp error code " + err.code + " with HTTP status " + resp.status,
+        meta: {
+          status_code: String(resp.status),
+          body: text
+        }
+      };
+    }
+    {{- end}}
     throw new TwirpError(err);
   });
 };
 
+// toTwirpError converts the errors of calls to TwirpErrors: aborted calls are
+// canceled, other failed requests unavailable and other errors internal.
+export const toTwirpError = (err: any): TwirpError => {
+  if (err instanceof TwirpError) {
+    return err;
+  }
+  const msg = String((err && err.message) || err);
+  if (err && err.name === "AbortError") {
+    return new TwirpError({ code: TwirpErrorCode.Canceled, msg });
+  }
+  if (err instanceof TypeError) {
+    return new TwirpError({ code: TwirpErrorCode.Unavailable, msg });
+  }
+  return new TwirpError({ code: TwirpErrorCode.Internal, msg });
+};
+
+// DeadlineExceededError is the error of calls that took longer than their
+// timeout.
+export class DeadlineExceededError extends TwirpError {
+  constructor(timeout: number) {
+    super({
+      code: TwirpErrorCode.DeadlineExceeded,
+      msg: "call exceeded its " + timeout + "ms timeout"
+    });
+    this.name = "DeadlineExceededError";
+  }
+}
+
+// RetryPolicy retries failed calls with exponential backoff.
+export interface RetryPolicy {
+  // maxAttempts is the maximum number of attempts, including the first.
+  maxAttempts: number;
+  // initialBackoff is the delay before the first retry in milliseconds,
+  // 100 by default. It's multiplied by multiplier, 2 by default, for each
+  // further retry, up to maxBackoff, 10000 by default.
+  initialBackoff?: number;
+  multiplier?: number;
+  maxBackoff?: number;
+  // jitter randomizes the delays by up to this fraction, 0.2 by default.
+  jitter?: number;
+  // retryableCodes are the Twirp error codes retried, unavailable and
+  // deadline_exceeded by default.
+  retryableCodes?: string[];
+}
+
+// CacheStore stores the cached results of calls as JSON, e.g. in memory, see
+// MemoryCacheStore, or in localStorage. It may return promises.
+export interface CacheStore {
+  get(key: string): string | undefined | Promise<string | undefined>;
+  set(key: string, value: string, ttl: number): void | Promise<void>;
+}
+
+// CacheOptions cache the results of the methods without side effects, marked
+// with option idempotency_level = NO_SIDE_EFFECTS, for ttl milliseconds. The
+// results are keyed by method and request, not by headers. A ttl of 0
+// disables the cache.
+export interface CacheOptions {
+  ttl: number;
+  // store is a MemoryCacheStore shared by all clients by default.
+  store?: CacheStore;
+}
+
+// MemoryCacheStore is a CacheStore in memory.
+export class MemoryCacheStore implements CacheStore {
+  private entries: { [key: string]: { value: string; expires: number } } = {};
+
+  public get(key: string): string | undefined {
+    const entry = this.entries[key];
+    if (entry && entry.expires < Date.now()) {
+      delete this.entries[key];
+      return undefined;
+    }
+    return entry && entry.value;
+  }
+
+  public set(key: string, value: string, ttl: number) {
+    this.entries[key] = { value, expires: Date.now() + ttl };
+  }
+}
+
+const defaultCacheStore = new MemoryCacheStore();
+
+// cachedCall returns the cached JSON result of a call to method with request,
+// or makes the call and caches its result with the cache of options.
+export const cachedCall = (
+  options: CallOptions,
+  method: string,
+  request: object,
+  call: () => Promise<any>
+): Promise<any> => {
+  const cache = options.cache;
+  if (!cache || !cache.ttl) {
+    return call();
+  }
+  const store = cache.store || defaultCacheStore;
+  const ttl = cache.ttl;
+  const key = method + ":" + JSON.stringify(request || {});
+  return Promise.resolve(store.get(key)).then(cached => {
+    if (cached !== undefined) {
+      return JSON.parse(cached);
+    }
+    return call().then(json => {
+      return Promise.resolve(store.set(key, JSON.stringify(json), ttl)).then(() => json);
+    });
+  });
+};
+
+// CallOptions are the per-call options of service client methods.
+export interface CallOptions {
+  // headers are sent with the request, merged over the headers of the
+  // client, the headers argument of methods overrides them.
+  headers?: object;
+  {{- if not .Edge}}
+  // credentials, mode and requestCache are the credentials, mode and cache
+  // options of the fetch of the request, e.g. "include" to send cookies
+  // cross-origin. requestCache is the HTTP cache mode, cache caches responses.
+  credentials?: "omit" | "same-origin" | "include";
+  mode?: "cors" | "no-cors" | "same-origin";
+  requestCache?: "default" | "no-store" | "reload" | "no-cache" | "force-cache" | "only-if-cached";
+  // keepalive lets the request outlive the page, e.g. for analytics sent
+  // during unload, with a body of up to 64KB. priority and referrerPolicy
+  // are the priority hint and referrer policy of the request.
+  keepalive?: boolean;
+  priority?: "high" | "low" | "auto";
+  referrerPolicy?: "" | "no-referrer" | "no-referrer-when-downgrade" | "origin" | "origin-when-cross-origin" | "same-origin" | "strict-origin" | "strict-origin-when-cross-origin" | "unsafe-url";
+  {{- end}}
+  // fetch makes the request instead of the fetch of the client, e.g. with
+  // a service binding from the environment of a Cloudflare Worker.
+  fetch?: Fetch;
+  // timeout rejects calls with a DeadlineExceededError after as many
+  // milliseconds, aborting their request. It applies to each attempt.
+  timeout?: number;
+  retry?: RetryPolicy;
+  cache?: CacheOptions;
+  // signal aborts the request, e.g. from an AbortController.
+  signal?: AbortSignal;
+  // serializer encodes requests and decodes responses, see Serializer.
+  serializer?: Serializer;
+  {{- if .Protobuf}}
+  // contentType is the encoding of the request, JSON by default. The response
+  // is requested in the same encoding and decoded according to its own
+  // Content-Type.
+  contentType?: ContentType;
+  {{- end}}
+}
+
+// ClientOptions are the options of service clients, the defaults of the
+// per-call options, which methods overrides by method name, e.g. "GetUser".
+export interface ClientOptions {
+  // baseURL is the URL of the server, e.g. "https://api.example.com".
+  baseURL?: string;
+  // fetch is the fetch implementation, the global fetch by default.
+  fetch?: Fetch;
+  // pathPrefix is the prefix of the routes of services, "/twirp" by default.
+  pathPrefix?: string;
+  headers?: object;
+  {{- if not .Edge}}
+  credentials?: "omit" | "same-origin" | "include";
+  mode?: "cors" | "no-cors" | "same-origin";
+  requestCache?: "default" | "no-store" | "reload" | "no-cache" | "force-cache" | "only-if-cached";
+  keepalive?: boolean;
+  priority?: "high" | "low" | "auto";
+  referrerPolicy?: "" | "no-referrer" | "no-referrer-when-downgrade" | "origin" | "origin-when-cross-origin" | "same-origin" | "strict-origin" | "strict-origin-when-cross-origin" | "unsafe-url";
+  {{- end}}
+  timeout?: number;
+  retry?: RetryPolicy;
+  cache?: CacheOptions;
+  serializer?: Serializer;
+  {{- if .Protobuf}}
+  contentType?: ContentType;
+  {{- end}}
+  methods?: { [method: string]: CallOptions };
+  // batch collects the calls of batchable methods made without headers or
+  // options into calls of their batch method, see Batcher.
+  batch?: BatchOptions;
+  // otel traces calls with OpenTelemetry, it's the @opentelemetry/api
+  // package, see traceFetch.
+  otel?: OpenTelemetry;
+  // compression gzips large request bodies, see compressFetch.
+  compression?: CompressionOptions;
+  // tokenProvider provides the Authorization header of requests, see
+  // authFetch.
+  tokenProvider?: TokenProvider;
+}
+
+// baseURLs are the base URLs set with setBaseURL, by service or package.
+const baseURLs: { [name: string]: string } = {};
+
+// setBaseURL sets the base URL of the clients created without a baseURL of a
+// service, e.g. "acme.users.Users", or of the services of a package and its
+// subpackages, e.g. "acme". The empty name sets the base URL of all services.
+export const setBaseURL = (name: string, baseURL: string) => {
+  baseURLs[name] = baseURL;
+};
+
+// resolveBaseURL returns the base URL of the clients of service created
+// without a baseURL, the one set for the service or else for its innermost
+// package. It's "" when none is set, relative to the origin of the page.
+{{- if ne .BaseURLEnv "none"}}
+// Each name is looked up in the environment too, after setBaseURL, e.g.
+// {{.BaseURLEnvPrefix}}_ACME_USERS_USERS for acme.users.Users, {{.BaseURLEnvPrefix}}_ACME for acme and
+// {{.BaseURLEnvPrefix}} for all services.
+{{- end}}
+export const resolveBaseURL = (service: string): string => {
+  for (let name = service; ; name = name.slice(0, Math.max(name.lastIndexOf("."), 0))) {
+    if (baseURLs[name] != null) {
+      return baseURLs[name];
+    }
+    {{- if ne .BaseURLEnv "none"}}
+    const key = "{{.BaseURLEnvPrefix}}" + (name ? "_" + name.toUpperCase().replace(/\./g, "_") : "");
+    {{- if eq .BaseURLEnv "process"}}
+    const env: { [key: string]: string | undefined } = typeof process !== "undefined" ? process.env : {};
+    {{- else}}
+    const env: { [key: string]: string | undefined } = (import.meta as any).env || {};
+    {{- end}}
+    if (env[key] != null) {
+      return env[key] as string;
+    }
+    {{- end}}
+    if (!name) {
+      return "";
+    }
+  }
+};
+
+// preconnected are the origins preconnect hinted.
+const preconnected: { [origin: string]: boolean } = {};
+
+// preconnect hints browsers to open a connection to the origin of baseURL
+// ahead of the first request, with a <link rel="preconnect"> in the head of
+// the document. It does nothing elsewhere and for relative base URLs.
+export const preconnect = (baseURL: string, options: ClientOptions = {}) => {
+  if (typeof document === "undefined" || !/^https?:\/\//i.test(baseURL)) {
+    return;
+  }
+  const origin = new URL(baseURL).origin;
+  if (preconnected[origin]) {
+    return;
+  }
+  preconnected[origin] = true;
+  const link = document.createElement("link");
+  link.rel = "preconnect";
+  link.href = origin;
+  // Requests with and without credentials use separate connections.
+  link.crossOrigin = {{if .Edge}}"anonymous"{{else}}options.credentials === "include" ? "use-credentials" : "anonymous"{{end}};
+  document.head.appendChild(link);
+};
+
+// BatchOptions configure the batching of calls: calls made within delay
+// milliseconds of the first one, 10 by default, are sent together, up to
+// maxSize calls.
+export interface BatchOptions {
+  delay?: number;
+  maxSize?: number;
+}
+
+{{if .BatchOption -}}
+// Batcher collects calls into batches sent by send, which resolves with the
+// results of the requests in the same order, and fans the results out to the
+// calls.
+export class Batcher<Q, R> {
+  private queue: { request: Q; resolve: (res: R) => void; reject: (err: any) => void }[] = [];
+  private timer: any;
+
+  constructor(private options: BatchOptions, private send: (requests: Q[]) => Promise<ReadonlyArray<R>>) {}
+
+  public call(request: Q): Promise<R> {
+    return new Promise<R>((resolve, reject) => {
+      this.queue.push({ request, resolve, reject });
+      if (this.options.maxSize && this.queue.length >= this.options.maxSize) {
+        this.flush();
+      } else if (!this.timer) {
+        this.timer = setTimeout(() => this.flush(), this.options.delay == null ? 10 : this.options.delay);
+      }
+    });
+  }
+
+  private flush() {
+    clearTimeout(this.timer);
+    this.timer = undefined;
+    const queue = this.queue;
+    this.queue = [];
+    this.send(queue.map(c => c.request)).then(
+      results => {
+        queue.forEach((c, i) => {
+          if (i < results.length) {
+            c.resolve(results[i]);
+          } else {
+            c.reject(
+              new TwirpError({
+                code: TwirpErrorCode.Internal,
+                msg: "batch returned " + results.length + " results for " + queue.length + " requests"
+              })
+            );
+          }
+        });
+      },
+      err => queue.forEach(c => c.reject(err))
+    );
+  }
+}
+
+{{end -}}
+// CompressionOptions configure the compression of request bodies: bodies of
+// at least threshold bytes, 1024 by default, are sent gzipped.
+export interface CompressionOptions {
+  threshold?: number;
+}
+
+// CompressionStream is missing from older DOM typings.
+declare const CompressionStream: any;
+
+// compressFetch wraps fetch to gzip the bodies of requests with the
+// Content-Encoding header, where CompressionStream is available.
+export const compressFetch = (options: CompressionOptions | undefined, fetch: Fetch): Fetch => {
+  if (!options || typeof CompressionStream === "undefined") {
+    return fetch;
+  }
+  const threshold = options.threshold == null ? 1024 : options.threshold;
+  return <any>((input: string, init: any = {}) => {
+    const body = init.body;
+    const bytes = typeof body === "string" ? new TextEncoder().encode(body) : body;
+    if (!bytes || bytes.length < threshold) {
+      return fetch(input, init);
+    }
+    const stream = new Blob([bytes]).stream().pipeThrough(new CompressionStream("gzip"));
+    return new Response(stream).arrayBuffer().then(compressed => {
+      const headers = {{objectLiteral "...init.headers" "\"Content-Encoding\": \"gzip\""}};
+      return fetch(input, {{objectLiteral "...init" "headers" "body: new Uint8Array(compressed)"}});
+    });
+  });
+};
+
+// TokenProvider returns the Authorization header of requests, e.g. "Bearer "
+// and an access token. refresh is set after a request was rejected as
+// unauthenticated with the previous one.
+export type TokenProvider = (refresh: boolean) => string | Promise<string>;
+
+// authFetch wraps fetch to set the Authorization header of requests from
+// tokenProvider. A request rejected with a 401 is sent once more with a
+// refreshed header.
+export const authFetch = (tokenProvider: TokenProvider | undefined, fetch: Fetch): Fetch => {
+  if (!tokenProvider) {
+    return fetch;
+  }
+  return <any>((input: string, init: any = {}) => {
+    const send = (refresh: boolean) => {
+      return Promise.resolve(tokenProvider(refresh)).then(authorization => {
+        return fetch(input, {{objectLiteral "...init" (printf "headers: %s" (objectLiteral "...init.headers" "Authorization: authorization"))}});
+      });
+    };
+    return send(false).then(res => (res.status === 401 ? send(true) : res));
+  });
+};
+
+// clientFetch wraps the fetch of a client with the authentication,
+// compression and tracing of its options.
+export const clientFetch = (options: ClientOptions, fetch: Fetch): Fetch => {
+  return traceFetch(options.otel, compressFetch(options.compression, authFetch(options.tokenProvider, fetch)));
+};
+
+// OpenTelemetry is the part of the @opentelemetry/api package used to trace
+// calls.
+export interface OpenTelemetry {
+  trace: {
+    getTracer(name: string): { startSpan(name: string, options?: object): OpenTelemetrySpan };
+    setSpan(context: any, span: OpenTelemetrySpan): any;
+  };
+  context: { active(): any };
+  propagation: { inject(context: any, carrier: object): void };
+}
+
+export interface OpenTelemetrySpan {
+  setAttribute(key: string, value: string | number): void;
+  setStatus(status: { code: number; message?: string }): void;
+  recordException(err: any): void;
+  end(): void;
+}
+
+// traceFetch wraps the fetch of a client to trace its requests with otel, it
+// returns fetch as-is without it. Each request gets a client span named
+// package.Service/Method with the Twirp error code, the request and response
+// sizes and the duration as attributes, and the span is propagated in the
+// traceparent header. Retried calls get a span per attempt.
+export const traceFetch = (otel: OpenTelemetry | undefined, fetch: Fetch): Fetch => {
+  if (!otel) {
+    return fetch;
+  }
+  const tracer = otel.trace.getTracer("protoc-gen-twirp_ts");
+  return <any>((input: string, init: any = {}) => {
+    const [service, method] = String(input).split("?")[0].split("/").slice(-2);
+    const span = tracer.startSpan(service + "/" + method, {
+      kind: 2, // SpanKind.CLIENT
+      attributes: { "rpc.system": "twirp", "rpc.service": service, "rpc.method": method }
+    });
+    const headers = {{objectLiteral "...init.headers"}};
+    otel.propagation.inject(otel.trace.setSpan(otel.context.active(), span), headers);
+    if (init.body != null) {
+      const size = typeof init.body === "string" ? new TextEncoder().encode(init.body).length : init.body.length;
+      span.setAttribute("rpc.request.size", size);
+    }
+
+    const start = Date.now();
+    const end = (code: string) => {
+      span.setAttribute("twirp.code", code);
+      span.setAttribute("rpc.duration_ms", Date.now() - start);
+      if (code !== "ok") {
+        span.setStatus({ code: 2, message: code }); // SpanStatusCode.ERROR
+      }
+      span.end();
+    };
+    return fetch(input, {{objectLiteral "...init" "headers"}}).then(
+      res => {
+        span.setAttribute("http.status_code", res.status);
+        const size = res.headers.get("Content-Length");
+        if (size) {
+          span.setAttribute("rpc.response.size", Number(size));
+        }
+        if (res.ok) {
+          end("ok");
+          return res;
+        }
+        // The error is read from a copy, it's thrown from the response.
+        const clone = (<any>res).clone;
+        const error: Promise<any> = clone ? clone.call(res).json() : Promise.reject();
+        return error.then(
+          err => {
+            end(err && typeof err.code === "string" ? err.code : httpErrorCode(res.status));
+            return res;
+          },
+          () => {
+            end(httpErrorCode(res.status));
+            return res;
+          }
+        );
+      },
+      err => {
+        span.recordException(err);
+        end(toTwirpError(err).code);
+        throw err;
+      }
+    );
+  });
+};
+
+// mergeOptions returns the options of a call to method: options over the
+// client's options for the method over its defaults. Headers are merged, so
+// a call setting a header keeps the default headers of the client.
+export const mergeOptions = (
+  client: ClientOptions,
+  method: string,
+  options: CallOptions
+): CallOptions => {
+  const methods = client.methods || {};
+  const defaults = methods[method] || {};
+  return {{if ne .Target "esnext"}}{{assign}}({{end}}{
+    {{- if not .Edge}}
+    credentials: client.credentials,
+    mode: client.mode,
+    requestCache: client.requestCache,
+    keepalive: client.keepalive,
+    priority: client.priority,
+    referrerPolicy: client.referrerPolicy,
+    {{- end}}
+    timeout: client.timeout,
+    retry: client.retry,
+    cache: client.cache,
+    serializer: client.serializer,
+    {{- if .Protobuf}}
+    contentType: client.contentType,
+    {{- end}}
+    {{- if eq .Target "esnext"}}
+    ...defaults,
+    ...options,
+    headers: { ...client.headers, ...defaults.headers, ...options.headers }
+  };
+    {{- else}}
+  }, defaults, options, {
+    headers: {{assign}}({}, client.headers, defaults.headers, options.headers)
+  });
+    {{- end}}
+};
+
+// withTimeout makes a call with the timeout of options, call is passed the
+// options with a signal aborting it on timeout or when the signal of options
+// aborts. The listener on that signal is removed when the call settles, a
+// long-lived signal shared by many calls would keep them all otherwise.
+export const withTimeout = <T>(
+  options: CallOptions,
+  call: (options: CallOptions) => Promise<T>
+): Promise<T> => {
+  const timeout = options.timeout;
+  if (!timeout) {
+    return call(options);
+  }
+
+  const controller = new AbortController();
+  const signal = options.signal;
+  const abort = () => controller.abort();
+  if (signal) {
+    if (signal.aborted) {
+      controller.abort();
+    }
+    signal.addEventListener("abort", abort, { once: true });
+  }
+  let timedOut = false;
+  const timer = setTimeout(() => {
+    timedOut = true;
+    controller.abort();
+  }, timeout);
+  const done = () => {
+    clearTimeout(timer);
+    if (signal) {
+      signal.removeEventListener("abort", abort);
+    }
+  };
+
+  return call({{objectLiteral "...options" "signal: controller.signal"}}).then(
+    res => {
+      done();
+      return res;
+    },
+    err => {
+      done();
+      throw timedOut ? new DeadlineExceededError(timeout) : err;
+    }
+  );
+};
+
+const defaultRetryableCodes = ["unavailable", "deadline_exceeded"];
+
+// backoff returns the delay before retry n of policy, counting from 0.
+const backoff = (policy: RetryPolicy, n: number): number => {
+  const delay = Math.min(
+    (policy.initialBackoff || 100) * Math.pow(policy.multiplier || 2, n),
+    policy.maxBackoff || 10000
+  );
+  const jitter = policy.jitter == null ? 0.2 : policy.jitter;
+  return delay * (1 + jitter * (2 * Math.random() - 1));
+};
+
+// twirpCall makes a call with the timeout and retry policy of options, call
+// is passed the options of each attempt. It rejects with TwirpErrors.
+export const twirpCall = <T>(
+  options: CallOptions,
+  call: (options: CallOptions) => Promise<T>
+): Promise<T> => {
+  const policy = options.retry;
+  const attempt = (n: number): Promise<T> => {
+    return withTimeout(options, call).catch(e => {
+      const err = toTwirpError(e);
+      const codes = (policy && policy.retryableCodes) || defaultRetryableCodes;
+      const aborted = () => options.signal && options.signal.aborted;
+      if (!policy || n + 1 >= policy.maxAttempts || codes.indexOf(err.code) < 0 || aborted()) {
+        throw err;
+      }
+      return new Promise(resolve => setTimeout(resolve, backoff(policy, n))).then(() => {
+        if (aborted()) {
+          throw err;
+        }
+        return attempt(n + 1);
+      });
+    });
+  };
+  return attempt(0);
+};
+
+{{if eq .Target "esnext" -}}
+interface StreamReader {
+  read(): Promise<{ done?: boolean; value?: Uint8Array }>;
+  cancel(): void;
+}
+
+// streamReader reads the chunks of a response body, a web ReadableStream or
+// a Node stream, e.g. from node-fetch.
+const streamReader = (body: any): StreamReader => {
+  if (body && typeof body.getReader === "function") {
+    const reader = body.getReader();
+    return { read: () => reader.read(), cancel: () => reader.cancel() };
+  }
+  const it = body[Symbol.asyncIterator]();
+  return { read: () => it.next(), cancel: () => it.return && it.return() };
+};
+
+// streamIterable iterates over the values of a response body as it arrives.
+// open makes the call when iteration starts, split returns the complete values
+// of the text read so far, as functions returning or throwing them in order,
+// and the rest, done is set at the end of the body. Breaking out of the
+// iteration or a value throwing cancels the response.
+const streamIterable = <T>(
+  open: () => Promise<{{if .FetchModule}}FetchResponse{{else}}Response{{end}}>,
+  split: (text: string, done: boolean) => [(() => T)[], string]
+): AsyncIterable<T> => {
+  return {
+    [Symbol.asyncIterator](): AsyncIterator<T> {
+      const decoder = new TextDecoder();
+      let reader: StreamReader | undefined;
+      let values: (() => T)[] = [];
+      let buffer = "";
+      let done = false;
+
+      const stop = () => {
+        done = true;
+        values = [];
+        if (reader) {
+          reader.cancel();
+        }
+      };
+
+      const next = (): Promise<IteratorResult<T>> => {
+        const value = values.shift();
+        if (value) {
+          return Promise.resolve().then(() => {
+            try {
+              return { done: false, value: value() };
+            } catch (e) {
+              stop();
+              throw e;
+            }
+          });
+        }
+        if (done) {
+          return Promise.resolve({ done: true, value: <any>undefined });
+        }
+        const chunk = reader
+          ? reader.read()
+          : open().then(res => {
+              reader = streamReader(res.body);
+              return reader.read();
+            });
+        return chunk.then(
+          c => {
+            done = !!c.done;
+            buffer += done ? decoder.decode() : decoder.decode(c.value, { stream: true });
+            [values, buffer] = split(buffer, done);
+            return next();
+          },
+          e => {
+            done = true;
+            throw toTwirpError(e);
+          }
+        );
+      };
+
+      return {
+        next,
+        return(): Promise<IteratorResult<T>> {
+          stop();
+          return Promise.resolve({ done: true, value: <any>undefined });
+        }
+      };
+    }
+  };
+};
+
+// isTwirpErrorJSON reports whether a line of a stream is a Twirp error, an
+// object with only a code, a msg and meta, sent by servers failing after
+// the response started.
+const isTwirpErrorJSON = (v: any): v is TwirpErrorJSON =>
+  v !== null &&
+  typeof v === "object" &&
+  typeof v.code === "string" &&
+  typeof v.msg === "string" &&
+  Object.keys(v).every(k => k === "code" || k === "msg" || k === "meta");
+
+// readTwirpStream iterates over the messages of a server streaming call, sent
+// as newline-delimited JSON, parse converts each message. A Twirp error
+// sent in the stream rejects with a TwirpError.
+export const readTwirpStream = <T>(
+  open: () => Promise<{{if .FetchModule}}FetchResponse{{else}}Response{{end}}>,
+  parse: (m: any) => T
+): AsyncIterable<T> => {
+  return streamIterable(open, (text, done) => {
+    const lines = text.split("\n");
+    const rest = done ? "" : lines.pop() || "";
+    return [
+      lines
+        .filter(l => l.trim() !== "")
+        .map(l => () => {
+          const m = JSON.parse(l);
+          if (isTwirpErrorJSON(m)) {
+            throw new TwirpError(m);
+          }
+          return parse(m);
+        }),
+      rest
+    ];
+  });
+};
+
+{{end -}}
+{{if .SubscribeOption -}}
+// readServerSentEvents iterates over the messages of a server-sent events
+// stream, the data of its message events, parse converts each message. An
+// error event rejects with the Twirp error of its data.
+export const readServerSentEvents = <T>(
+  open: () => Promise<{{if .FetchModule}}FetchResponse{{else}}Response{{end}}>,
+  parse: (m: any) => T
+): AsyncIterable<T> => {
+  return streamIterable(open, (text, done) => {
+    const events = text.replace(/\r\n?/g, "\n").split("\n\n");
+    const rest = done ? "" : events.pop() || "";
+    const values: (() => T)[] = [];
+    events.forEach(event => {
+      let type = "message";
+      const data: string[] = [];
+      event.split("\n").forEach(line => {
+        const i = line.indexOf(":");
+        const field = i < 0 ? line : line.slice(0, i);
+        const value = i < 0 ? "" : line.slice(i + 1).replace(/^ /, "");
+        if (field === "event") {
+          type = value;
+        } else if (field === "data") {
+          data.push(value);
+        }
+      });
+      if (data.length === 0) {
+        return;
+      }
+      const json = data.join("\n");
+      if (type === "error") {
+        values.push(() => {
+          throw new TwirpError(JSON.parse(json));
+        });
+      } else if (type === "message") {
+        values.push(() => parse(JSON.parse(json)));
+      }
+    });
+    return [values, rest];
+  });
+};
+
+{{end -}}
+// Serializer encodes request messages and decodes response messages, JSON by
+// default, see jsonSerializer. Messages are passed as their JSON
+// representation, e.g. to encode them with superjson or validate them with a
+// schema.
+export interface Serializer {
+  contentType: string;
+  serialize(message: any): string | Uint8Array;
+  deserialize(res: {{if .FetchModule}}FetchResponse{{else}}Response{{end}}): Promise<any>;
+}
+
+export const jsonSerializer: Serializer = {
+  contentType: "application/json",
+  serialize: message => JSON.stringify(message),
+  deserialize: res => res.json()
+};
+
+// readTwirpResponse reads the JSON of the response message of a call with the
+// serializer of options
+{{- if .Protobuf}}, or decodes it with codec when its Content-Type is the
+// protobuf wire format
+{{- end}}.
+export const readTwirpResponse = (
+  res: {{if .FetchModule}}FetchResponse{{else}}Response{{end}},
+  options: CallOptions
+  {{- if .Protobuf}},
+  codec?: MessageCodec
+  {{- end}}
+): Promise<any> => {
+  {{- if .Protobuf}}
+  if (codec && /^application\/(x-)?protobuf\b/i.test(res.headers.get("Content-Type") || "")) {
+    return res.arrayBuffer().then(b => decodeMessage(codec, new Uint8Array(b)));
+  }
+  {{- end}}
+  return (options.serializer || jsonSerializer).deserialize(res);
+};
+
+// requestInit returns the options of the fetch of a call besides its method,
+// headers and body.
+const requestInit = (options: CallOptions): object => {
+  return {
+    {{- if not .Edge}}
+    credentials: options.credentials,
+    mode: options.mode,
+    cache: options.requestCache,
+    keepalive: options.keepalive,
+    priority: options.priority,
+    referrerPolicy: options.referrerPolicy,
+    {{- end}}
+    {{- if eq .TwirpVersion "v7"}}
+    redirect: "manual",
+    {{- end}}
+    signal: options.signal
+  };
+};
+
 export const createTwirpRequest = (
   body: object = {},
-  headers: object = {}
+  headers: object = {},
+  options: CallOptions = {}
+  {{- if .Protobuf}},
+  codec?: MessageCodec
+  {{- end}}
 ): object => {
-  return {
+  {{- if .Protobuf}}
+  if (codec && options.contentType === "application/protobuf") {
+    return {{if ne .Target "esnext"}}{{assign}}({{end}}{
+      method: "POST",
+      headers: {{objectLiteral "Accept: \"application/protobuf\"" "...options.headers" "...headers" "\"Content-Type\": \"application/protobuf\""}},
+      body: encodeMessage(codec, body),
+      {{- if eq .Target "esnext"}}
+      ...requestInit(options)
+    };
+      {{- else}}
+    }, requestInit(options));
+      {{- end}}
+  }
+  {{- end}}
+  const serializer = options.serializer || jsonSerializer;
+  return {{if ne .Target "esnext"}}{{assign}}({{end}}{
     method: "POST",
-    headers: { ...headers, "Content-Type": "application/json" },
-    body: JSON.stringify(body || {})
+    {{- if eq .Target "esnext"}}
+    headers: {
+      {{- if .Protobuf}}
+      Accept: serializer.contentType,
+      {{- end}}
+      ...options.headers,
+      ...headers,
+      "Content-Type": serializer.contentType
+    },
+    body: serializer.serialize(body || {}),
+    ...requestInit(options)
+  };
+    {{- else}}
+    headers: {{assign}}({ {{- if .Protobuf}} Accept: serializer.contentType {{end -}} }, options.headers, headers, { "Content-Type": serializer.contentType }),
+    body: serializer.serialize(body || {})
+  }, requestInit(options));
+    {{- end}}
+};
+
+{{if eq .Mode "functional" -}}
+// callTwirpMethod calls method of service with the options of a client, for
+// the call functions generated with mode=functional. read reads the response
+// of each attempt, see twirpCall.
+export const callTwirpMethod = <T>(
+  client: ClientOptions,
+  service: string,
+  method: string,
+  body: object,
+  headers: object,
+  options: CallOptions,
+  read: (res: {{if .FetchModule}}FetchResponse{{else}}Response{{end}}, options: CallOptions) => Promise<T>
+): Promise<T> => {
+  const hostname = client.baseURL == null ? resolveBaseURL(service) : client.baseURL;
+  const url = hostname + (client.pathPrefix == null ? "/twirp" : client.pathPrefix) + "/" + service + "/" + method;
+  return twirpCall(mergeOptions(client, method, options), callOptions =>
+    clientFetch(client, callOptions.fetch || client.fetch || defaultFetch)(
+      url,
+      createTwirpRequest(body, headers, callOptions)
+    ).then(res => (res.ok ? read(res, callOptions) : throwTwirpError(res)))
+  );
+};
+
+{{end -}}
+{{if .HTTPGet -}}
+// createTwirpGetRequest creates the GET request of a method without side
+// effects, its message is sent in the query string, see queryURL.
+export const createTwirpGetRequest = (headers: object = {}, options: CallOptions = {}): object => {
+  return {{if ne .Target "esnext"}}{{assign}}({{end}}{
+    method: "GET",
+    headers: {{if .Protobuf}}{{objectLiteral "Accept: options.contentType || \"application/json\"" "...options.headers" "...headers"}}{{else}}{{objectLiteral "...options.headers" "...headers"}}{{end}},
+    {{- if eq .Target "esnext"}}
+    ...requestInit(options)
+  };
+    {{- else}}
+  }, requestInit(options));
+    {{- end}}
+};
+
+// QueryFields describe the fields of a message encoded in query strings, the
+// fields of nested messages are encoded in dotted paths with their own
+// QueryFields, e.g. filter.active=true. The other fields are described by the
+// kind of their values when they aren't single strings, "number", "boolean"
+// or "json", followed by [] for repeated fields, see decodeQuery.
+export interface QueryFields {
+  [name: string]: (() => QueryFields) | string;
+}
+
+// queryURL returns url with the JSON of message as its query string, see
+// encodeQuery.
+export const queryURL = (url: string, message: object = {}, fields: QueryFields = {}): string => {
+  const query: string[] = [];
+  encodeQuery(JSON.parse(JSON.stringify(message)), fields, "", query);
+  return query.length > 0 ? url + "?" + query.join("&") : url;
+};
+
+// encodeQuery appends the JSON of a message to query, a parameter per field
+// named after its path, repeated for repeated fields. The fields of nested
+// messages described by fields are encoded as parameters too, other values
+// than strings, numbers and booleans as JSON, e.g. maps and lists of
+// messages. Timestamps and enums are sent like their JSON, as RFC 3339
+// strings and names.
+const encodeQuery = (json: any, fields: QueryFields, prefix: string, query: string[]) => {
+  Object.keys(json).forEach(name => {
+    const value = json[name];
+    const field = queryField(fields, name);
+    if (typeof field === "function" && value && typeof value === "object" && !Array.isArray(value)) {
+      encodeQuery(value, field(), prefix + name + ".", query);
+      return;
+    }
+    (Array.isArray(value) ? value : [value]).forEach((v: any) => {
+      if (v != null) {
+        query.push(encodeURIComponent(prefix + name) + "=" + encodeURIComponent(typeof v === "object" ? JSON.stringify(v) : String(v)));
+      }
+    });
+  });
+};
+
+// queryField returns the description of the field name in fields.
+const queryField = (fields: QueryFields, name: string): QueryFields[string] | undefined =>
+  Object.prototype.hasOwnProperty.call(fields, name) ? fields[name] : undefined;
+{{- if or .Server .Fakes .Msw}}
+
+// decodeQuery returns the JSON of a message encoded in a query string by
+// queryURL, fields describe its fields. Values of fields without a kind are
+// strings, parameters of unknown nested messages are left out.
+const decodeQuery = (query: string, fields: QueryFields = {}): any => {
+  const json: any = {};
+  query.split("&").forEach(param => {
+    if (param === "") {
+      return;
+    }
+    const i = param.indexOf("=");
+    const decode = (s: string) => {
+      try {
+        return decodeURIComponent(s.replace(/\+/g, " "));
+      } catch (e) {
+        throw new TwirpError({ code: TwirpErrorCode.Malformed, msg: "the query string is malformed" });
+      }
+    };
+    const path = decode(i < 0 ? param : param.slice(0, i)).split(".");
+    const value = i < 0 ? "" : decode(param.slice(i + 1));
+    if (path.indexOf("__proto__") >= 0) {
+      return;
+    }
+    let m = json;
+    let f = fields;
+    for (let j = 0; j < path.length - 1; j++) {
+      const field = queryField(f, path[j]);
+      if (typeof field !== "function") {
+        return;
+      }
+      if (!Object.prototype.hasOwnProperty.call(m, path[j])) {
+        m[path[j]] = {};
+      }
+      m = m[path[j]];
+      f = field();
+    }
+    const name = path[path.length - 1];
+    const field = queryField(f, name);
+    const kind = typeof field === "string" ? field.replace(/\[\]$/, "") : "string";
+    let v: any = value;
+    if (kind === "number") {
+      v = Number(value);
+    } else if (kind === "boolean") {
+      v = value === "true";
+    } else if (kind === "json") {
+      try {
+        v = JSON.parse(value);
+      } catch (e) {
+        // Strings of google.protobuf.Value are sent as-is.
+      }
+    }
+    if (typeof field === "string" && /\[\]$/.test(field)) {
+      m[name] = (Object.prototype.hasOwnProperty.call(m, name) ? m[name] : []).concat([v]);
+    } else {
+      m[name] = v;
+    }
+  });
+  return json;
+};
+{{- end}}
+
+{{end -}}
+// Timestamp is a google.protobuf.Timestamp when generated with
+// timestamp=object.
+export interface Timestamp {
+  seconds: number;
+  nanos: number;
+}
+
+// parseTimestamp parses an RFC 3339 timestamp as encoded by jsonpb.
+export const parseTimestamp = (s: string): Timestamp => {
+  const m = /^(.*?)(?:\.(\d{1,9}))?(Z|[+-]\d\d:\d\d)$/i.exec(s);
+  if (!m) {
+    throw new Error("invalid timestamp: " + s);
+  }
+  return {
+    seconds: Math.floor(Date.parse(m[1] + m[3]) / 1000),
+    nanos: m[2] ? Number((m[2] + "00000000").slice(0, 9)) : 0
+  };
+};
+
+// formatTimestamp formats t as an RFC 3339 timestamp with 0, 3, 6 or 9
+// fractional digits.
+export const formatTimestamp = (t: Timestamp): string => {
+  const date = new Date(t.seconds * 1000).toISOString().replace(/\.\d+Z$/, "");
+  let frac = "";
+  if (t.nanos) {
+    frac = ("." + ("00000000" + t.nanos).slice(-9)).replace(/(000)+$/, "");
+  }
+  return date + frac + "Z";
+};
+
+const base64Chars = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789+/";
+
+const base64Encode = (b: Uint8Array): string => {
+  let s = "";
+  for (let i = 0; i < b.length; i += 3) {
+    const n = (b[i] << 16) | ((b[i + 1] || 0) << 8) | (b[i + 2] || 0);
+    s += base64Chars[n >> 18] + base64Chars[(n >> 12) & 63];
+    s += i + 1 < b.length ? base64Chars[(n >> 6) & 63] : "=";
+    s += i + 2 < b.length ? base64Chars[n & 63] : "=";
+  }
+  return s;
+};
+
+// formatBytes returns the proto3 JSON of bytes fields, standard base64 with
+// padding. Strings are taken as base64 already, URL-safe or not.
+export const formatBytes = (b: string | Uint8Array): string => {
+  if (typeof b !== "string") {
+    return base64Encode(b);
+  }
+  const s = b.replace(/-/g, "+").replace(/_/g, "/").replace(/=+$/, "");
+  return s + "==".slice(0, (4 - (s.length % 4)) % 4);
+};
+
+// Int32 and UInt32 are the types of 32-bit integer fields when generated with
+// branded_ints, e.g. <Int32>42.
+export type Int32 = number & { __int32: true };
+export type UInt32 = number & { __uint32: true };
+
+// DeepPartial makes the members of messages optional recursively, see the
+// fromPartial methods of message classes.
+export type DeepPartial<T> = T extends string | number | boolean | Date | Timestamp | ((...args: any[]) => any)
+  ? T
+  : T extends Array<infer U>
+  ? Array<DeepPartial<U>>
+  : T extends object
+  ? { [K in keyof T]?: DeepPartial<T[K]> }
+  : T;
+
+{{if eq .Mode "classes" -}}
+// repeatedEquals and mapEquals compare repeated fields and maps element-wise
+// with eq, see the equals methods of message classes.
+export const repeatedEquals = <T>(a: ReadonlyArray<T>, b: ReadonlyArray<T>, eq: (x: T, y: T) => boolean): boolean => {
+  return a.length === b.length && a.every((x, i) => eq(x, b[i]));
+};
+export const mapEquals = (a: any, b: any, eq: (x: any, y: any) => boolean): boolean => {
+  const keys = Object.keys(a);
+  return keys.length === Object.keys(b).length && keys.every(k => k in b && eq(a[k], b[k]));
+};
+
+{{end -}}
+{{if eq .Target "es5" -}}
+// assign copies the properties of sources to target like Object.assign, which
+// ES5 lacks.
+export const assign = (target: any, ...sources: any[]): any => {
+  sources.forEach(source => {
+    Object.keys(source || {}).forEach(k => {
+      target[k] = source[k];
+    });
+  });
+  return target;
+};
+
+{{end -}}
+{{if .Immutable -}}
+// deepFreeze freezes v and the objects it references, for the messages of
+// immutable=true. Frozen objects are skipped, and typed arrays, which can't
+// be frozen.
+export const deepFreeze = <T>(v: T): T => {
+  if (v && typeof v === "object" && !Object.isFrozen(v) && !ArrayBuffer.isView(v)) {
+    Object.freeze(v);
+    Object.keys(v).forEach(k => deepFreeze((<any>v)[k]));
+  }
+  return v;
+};
+
+{{end -}}
+{{if and (eq .Mode "classes") (eq .ClassStyle "properties") -}}
+// unknownFields returns the unknown fields and extensions of a message with
+// class_style=properties. They're kept in a non-enumerable property, out of
+// spreads and debugger views of the message.
+export const unknownFields = (m: object): { [key: string]: any } => {
+  if (!(<any>m)._unknown) {
+    Object.defineProperty(m, "_unknown", { value: {}, writable: true });
+  }
+  return (<any>m)._unknown;
+};
+
+{{end -}}
+{{if or (eq .Mode "classes") (eq .Mode "functional") -}}
+// mapValues returns a copy of the map m with its values converted by f, it's
+// shared by the conversions of the map fields of all messages.
+export const mapValues = (m: any, f: (v: any) => any): any => {
+  const acc: any = {};
+  Object.keys(m || {}).forEach(k => {
+    acc[k] = f(m[k]);
+  });
+  return acc;
+};
+
+{{end -}}
+export type FieldMaskPath<T> = Exclude<keyof T, "toJSON"> & string;
+
+// createFieldMask builds a google.protobuf.FieldMask from field names of the
+// target message interface, e.g. createFieldMask<IUser>("name", "email").
+export const createFieldMask = <T>(...paths: FieldMaskPath<T>[]): string => {
+  return paths.join(",");
+};
+
+// Extension describes a proto2 extension field of messages of type M, see
+// getExtension and setExtension on the extended message.
+export interface Extension<M, T> {
+  name: string;
+  fieldNumber: number;
+  fromJSON(m: any): T | undefined;
+}
+
+{{if or .WebSocket .Fakes -}}
+// textResponse is the part of a fetch Response read by clients, for the
+// responses of WebSocketTransport and fakes.
+const textResponse = (status: number, headers: { [name: string]: string } = {}, body = "") => {
+  const lower: { [name: string]: string } = {};
+  Object.keys(headers).forEach(name => {
+    lower[name.toLowerCase()] = headers[name];
+  });
+  return {
+    ok: status >= 200 && status < 300,
+    status,
+    headers: { get: (name: string): string | null => lower[name.toLowerCase()] || null },
+    text: () => Promise.resolve(body),
+    json: () => Promise.resolve().then(() => JSON.parse(body || "{}"))
+  };
+};
+
+{{end -}}
+{{if or .Fakes .Server .Msw -}}
+// twirpErrorStatus are the HTTP statuses of the Twirp error codes.
+const twirpErrorStatus: { [code: string]: number } = {
+  [TwirpErrorCode.Canceled]: 408,
+  [TwirpErrorCode.Unknown]: 500,
+  [TwirpErrorCode.InvalidArgument]: 400,
+  [TwirpErrorCode.Malformed]: 400,
+  [TwirpErrorCode.DeadlineExceeded]: 408,
+  [TwirpErrorCode.NotFound]: 404,
+  [TwirpErrorCode.BadRoute]: 404,
+  [TwirpErrorCode.AlreadyExists]: 409,
+  [TwirpErrorCode.PermissionDenied]: 403,
+  [TwirpErrorCode.Unauthenticated]: 401,
+  [TwirpErrorCode.ResourceExhausted]: {{if eq .TwirpVersion "v5"}}403{{else}}429{{end}},
+  [TwirpErrorCode.FailedPrecondition]: 412,
+  [TwirpErrorCode.Aborted]: 409,
+  [TwirpErrorCode.OutOfRange]: 400,
+  [TwirpErrorCode.Unimplemented]: 501,
+  [TwirpErrorCode.Internal]: 500,
+  [TwirpErrorCode.Unavailable]: 503,
+  [TwirpErrorCode.DataLoss]: 500
+};
+
+// servedError converts the errors of served calls to TwirpErrors, other
+// errors than TwirpErrors are internal.
+const servedError = (err: any): TwirpError => {
+  if (err instanceof TwirpError) {
+    return err;
+  }
+  return new TwirpError({ code: TwirpErrorCode.Internal, msg: String((err && err.message) || err) });
+};
+
+{{end -}}
+{{if or .Fakes .Msw -}}
+// FakeMethod handles the calls of a method of a fake service, from the JSON
+// of the request to its response message.
+export type FakeMethod = (request: any, headers: { [name: string]: string }) => any;
+
+{{end -}}
+{{if .Fakes -}}
+// fakeFetch serves the methods of service in memory, as the fetch of a
+// client. The handler of a method is looked up by name with method, routes
+// without one are bad routes. Errors thrown by handlers are sent as Twirp
+// errors, other errors than TwirpErrors as internal ones. Only JSON requests
+// are served.
+{{- if .HTTPGet}} GET requests are served for the methods query returns the
+// QueryFields of, from their query string.
+{{- end}}
+export const fakeFetch = (
+  service: string,
+  method: (name: string) => FakeMethod | undefined
+  {{- if .HTTPGet}},
+  query?: (name: string) => QueryFields | undefined
+  {{- end}}
+): Fetch => {
+  return <any>((input: string, init: any = {}) => {
+    const url = String(input).split("?")[0];
+    const route = "/" + service + "/";
+    const name = url.lastIndexOf(route) >= 0 ? url.slice(url.lastIndexOf(route) + route.length) : "";
+    const handler = name ? method(name) : undefined;
+    {{- if .HTTPGet}}
+    const fields = init.method === "GET" && query ? query(name) : undefined;
+    {{- end}}
+    const response = new Promise<any>(resolve => {
+      if (!handler{{if .HTTPGet}} || init.method === "GET" && !fields{{end}}) {
+        throw new TwirpError({ code: TwirpErrorCode.BadRoute, msg: "no handler for " + {{if .HTTPGet}}(init.method || "POST") + " " + {{end}}url });
+      }
+      {{- if .HTTPGet}}
+      if (fields) {
+        resolve(handler(decodeQuery(String(input).split("?")[1] || "", fields), init.headers || {}));
+        return;
+      }
+      {{- end}}
+      if (init.body != null && typeof init.body !== "string") {
+        throw new TwirpError({ code: TwirpErrorCode.Malformed, msg: "fakes only accept JSON requests" });
+      }
+      resolve(handler(JSON.parse(init.body || "{}"), init.headers || {}));
+    });
+    return response.then(
+      m => textResponse(200, { "Content-Type": "application/json" }, JSON.stringify(m === undefined ? {} : m)),
+      err => {
+        const te = servedError(err);
+        const body = JSON.stringify({ code: te.code, msg: te.msg, meta: te.meta });
+        return textResponse(twirpErrorStatus[te.code] || 500, { "Content-Type": "application/json" }, body);
+      }
+    );
+  });
+};
+
+{{end -}}
+{{if .Msw -}}
+// mswHandlers creates the Mock Service Worker handlers of the methods in
+// names under path, for the ones served by method. Errors thrown by methods
+// are sent as Twirp errors, other errors than TwirpErrors as internal ones.
+{{- if .HTTPGet}} The
+// methods query returns the QueryFields of are stubbed on GET, their request
+// read from the query string.
+{{- end}}
+export const mswHandlers = (
+  path: string,
+  names: string[],
+  method: (name: string) => FakeMethod | undefined
+  {{- if .HTTPGet}},
+  query?: (name: string) => QueryFields | undefined
+  {{- end}}
+) => {
+  const served = names.map(name => ({ name, serve: method(name) }));
+  return served.filter((m): m is { name: string; serve: FakeMethod } => m.serve !== undefined).map(({ name, serve }) => {
+    {{- if .HTTPGet}}
+    const fields = query && query(name);
+    return (fields ? rest.get : rest.post)(path + name, (req, res, ctx) => {
+      const request = fields ? Promise.resolve().then(() => decodeQuery(req.url.search.slice(1), fields)) : req.json();
+      const response = request.then(m => serve(m, req.headers.all()));
+    {{- else}}
+    return rest.post(path + name, (req, res, ctx) => {
+      const response = req.json().then(m => serve(m, req.headers.all()));
+    {{- end}}
+      return response.then(
+        m => res(ctx.json(m === undefined ? {} : m)),
+        err => {
+          const te = servedError(err);
+          return res(ctx.status(twirpErrorStatus[te.code] || 500), ctx.json({ code: te.code, msg: te.msg, meta: te.meta }));
+        }
+      );
+    });
+  });
+};
+
+{{end -}}
+{{if .Server -}}
+// ServerContext is passed to the methods of services served by a handler,
+// see twirpHandler.
+export interface ServerContext {
+  // method is the name of the called method, e.g. "GetUser".
+  method: string;
+  headers: { [name: string]: string | string[] | undefined };
+}
+
+// ServerOptions are the options of the handlers of services.
+export interface ServerOptions {
+  // pathPrefix is the prefix of the routes of the service, /twirp by
+  // default.
+  pathPrefix?: string;
+  // onError is called with the errors of calls, e.g. to log internal ones.
+  onError?: (err: TwirpError, ctx: ServerContext) => void;
+}
+
+// ServerRequest is the part of an http.IncomingMessage read by handlers,
+// body is the request parsed by Express' body parsers if any.
+export interface ServerRequest {
+  method?: string;
+  url?: string;
+  headers: { [name: string]: string | string[] | undefined };
+  body?: any;
+  setEncoding?(encoding: string): void;
+  on(event: string, listener: (chunk?: any) => void): any;
+}
+
+// ServerResponse is the part of an http.ServerResponse written by handlers.
+export interface ServerResponse {
+  statusCode: number;
+  setHeader(name: string, value: string): any;
+  end(body?: string): any;
+}
+
+// ServerMethod serves a method with the JSON of the request, resolving its
+// response message.
+export type ServerMethod = (request: any, ctx: ServerContext) => Promise<any>;
+
+// readServerRequest reads the JSON of the body of req.
+const readServerRequest = (req: ServerRequest): Promise<any> => {
+  if (req.body !== undefined) {
+    return Promise.resolve().then(() => (typeof req.body === "string" ? JSON.parse(req.body || "{}") : req.body));
+  }
+  return new Promise((resolve, reject) => {
+    let body = "";
+    if (req.setEncoding) {
+      req.setEncoding("utf8");
+    }
+    req.on("data", chunk => {
+      body += chunk;
+    });
+    req.on("error", reject);
+    req.on("end", () => {
+      try {
+        resolve(JSON.parse(body || "{}"));
+      } catch (e) {
+        reject(new TwirpError({ code: TwirpErrorCode.Malformed, msg: "the request is not valid JSON" }));
+      }
+    });
+  });
+};
+
+// twirpHandler creates an HTTP handler serving the methods of service, for
+// Node's http module and Express. The method serving a route is looked up
+// by name with method. Requests outside the routes of the service are passed
+// to next when given, errors thrown by methods are sent as Twirp errors,
+// other errors than TwirpErrors as internal ones. Only JSON is served.
+{{- if .HTTPGet}} The
+// methods query returns the QueryFields of are also served on GET, their
+// request read from the query string.
+{{- end}}
+export const twirpHandler = (
+  service: string,
+  method: (name: string) => ServerMethod | undefined,
+  options: ServerOptions = {}
+  {{- if .HTTPGet}},
+  query?: (name: string) => QueryFields | undefined
+  {{- end}}
+) => {
+  const prefix = (options.pathPrefix == null ? "/twirp" : options.pathPrefix) + "/" + service + "/";
+  return (req: ServerRequest, res: ServerResponse, next?: (err?: any) => void): void => {
+    const path = (req.url || "").split("?")[0];
+    if (next && path.indexOf(prefix) !== 0) {
+      next();
+      return;
+    }
+    const ctx: ServerContext = { method: path.slice(prefix.length), headers: req.headers };
+    const send = (status: number, body: any) => {
+      res.statusCode = status;
+      res.setHeader("Content-Type", "application/json");
+      res.end(JSON.stringify(body === undefined ? {} : body));
+    };
+    {{- if .HTTPGet}}
+    const fields = req.method === "GET" && query && path.indexOf(prefix) === 0 ? query(ctx.method) : undefined;
+    {{- end}}
+    const call = new Promise<ServerMethod>(resolve => {
+      const handler = path.indexOf(prefix) === 0 ? method(ctx.method) : undefined;
+      if (!handler || req.method !== "POST"{{if .HTTPGet}} && !fields{{end}}) {
+        throw new TwirpError({ code: TwirpErrorCode.BadRoute, msg: "no handler for " + req.method + " " + path });
+      }
+      if ({{if .HTTPGet}}!fields && {{end}}!/^application\/json\b/i.test(String(req.headers["content-type"] || ""))) {
+        throw new TwirpError({ code: TwirpErrorCode.BadRoute, msg: "unsupported Content-Type, expected application/json" });
+      }
+      resolve(handler);
+    });
+    {{- if .HTTPGet}}
+    const request = (): Promise<any> =>
+      fields ? Promise.resolve().then(() => decodeQuery((req.url || "").split("?")[1] || "", fields)) : readServerRequest(req);
+    call.then(handler => request().then(m => handler(m, ctx))).then(
+    {{- else}}
+    call.then(handler => readServerRequest(req).then(m => handler(m, ctx))).then(
+    {{- end}}
+      m => send(200, m),
+      err => {
+        const te = serverError(err, ctx, options);
+        send(twirpErrorStatus[te.code] || 500, { code: te.code, msg: te.msg, meta: te.meta });
+      }
+    );
+  };
+};
+{{- if eq .ServerFramework "fastify"}}
+
+// FastifyPlugin is a Fastify plugin serving a service, see the create plugin
+// functions of services.
+export type FastifyPlugin = (fastify: FastifyInstance, options: ServerOptions, done: (err?: Error) => void) => void;
+
+// fastifyRoutes returns a function registering the routes of the methods of
+// service on fastify. The JSON of requests is validated with the schema of
+// their route, invalid requests are malformed. Errors are sent like
+// twirpHandler does.
+{{- if .HTTPGet}} Methods
+// given the QueryFields of their request are also served on GET, their
+// request read from the query string without validation.
+{{- end}}
+export const fastifyRoutes = (fastify: FastifyInstance, service: string, options: ServerOptions) => {
+  const prefix = (options.pathPrefix == null ? "/twirp" : options.pathPrefix) + "/" + service + "/";
+  const send = (reply: { code(statusCode: number): { send(payload: any): any } }, ctx: ServerContext, call: () => any) => {
+    return new Promise<any>(resolve => resolve(call())).then(
+      m => reply.code(200).send(m === undefined ? {} : m),
+      err => {
+        const te = serverError(err, ctx, options);
+        return reply.code(twirpErrorStatus[te.code] || 500).send({ code: te.code, msg: te.msg, meta: te.meta });
+      }
+    );
+  };
+  return <B>(
+    name: string,
+    schema: object,
+    method: (body: B, ctx: ServerContext) => any
+    {{- if .HTTPGet}},
+    query?: QueryFields
+    {{- end}}
+  ): void => {
+    fastify.post<{ Body: B }>(prefix + name, { schema: { body: schema }, attachValidation: true }, (req, reply) => {
+      const ctx: ServerContext = { method: name, headers: req.headers };
+      return send(reply, ctx, () => {
+        if (req.validationError) {
+          throw new TwirpError({ code: TwirpErrorCode.Malformed, msg: req.validationError.message });
+        }
+        return method(req.body, ctx);
+      });
+    });
+    {{- if .HTTPGet}}
+    if (query) {
+      fastify.get(prefix + name, (req, reply) => {
+        const ctx: ServerContext = { method: name, headers: req.headers };
+        return send(reply, ctx, () => method(decodeQuery(req.url.split("?")[1] || "", query), ctx));
+      });
+    }
+    {{- end}}
+  };
+};
+{{- end}}
+
+// serverError converts the errors of served calls to TwirpErrors, see
+// servedError, and passes them to the onError option.
+const serverError = (err: any, ctx: ServerContext, options: ServerOptions): TwirpError => {
+  const te = servedError(err);
+  if (options.onError) {
+    options.onError(te, ctx);
+  }
+  return te;
+};
+
+{{end -}}
+{{if .Vue -}}
+// TwirpCallRefs are the reactive state of the calls of a method made with
+// call, see useTwirpCall.
+export interface TwirpCallRefs<A extends any[], R> {
+  // data is the response of the last successful call, error the error of
+  // the last failed one.
+  data: Ref<R | undefined>;
+  error: Ref<TwirpError | undefined>;
+  loading: Ref<boolean>;
+  // call calls the method, resolving its response, or undefined when it
+  // fails.
+  call: (...args: A) => Promise<R | undefined>;
+}
+
+// useTwirpCall is a Vue composable wrapping a method of a client in reactive
+// refs, see the use functions of services. Only the last call updates them.
+export const useTwirpCall = <A extends any[], R>(method: (...args: A) => Promise<R>): TwirpCallRefs<A, R> => {
+  const data = shallowRef<R | undefined>(undefined);
+  const error = shallowRef<TwirpError | undefined>(undefined);
+  const loading = shallowRef(false);
+  let last = 0;
+  const call = (...args: A) => {
+    const id = ++last;
+    loading.value = true;
+    error.value = undefined;
+    return method(...args).then(
+      m => {
+        if (id === last) {
+          data.value = m;
+          loading.value = false;
+        }
+        return m;
+      },
+      err => {
+        if (id === last) {
+          error.value = toTwirpError(err);
+          loading.value = false;
+        }
+        return undefined;
+      }
+    );
   };
+  return { data, error, loading, call };
 };
 
+{{end -}}
+{{if .Mocks -}}
+// MockCall is a call made to a mock client, see the Mock classes of services.
+export interface MockCall {
+  method: string;
+  request: any;
+  headers: object;
+  options: CallOptions;
+}
+
+// MockResponse is the canned response of a method of a mock client, either
+// the response itself or a function resolving it from the request, which can
+// throw a TwirpError to fail the call.
+export type MockResponse<Q, R> = R | ((request: Q, headers: object, options: CallOptions) => R | Promise<R>);
+
+export interface MockOptions {
+  // latency is the delay of responses in milliseconds, for all methods or
+  // per method.
+  latency?: number | ((method: string) => number);
+}
+
+// resolveMock records a call of a mock client in calls and resolves it with
+// response after the latency of options. Calls of methods without a
+// response fail as unimplemented.
+export const resolveMock = <Q, R>(
+  calls: MockCall[],
+  options: MockOptions,
+  method: string,
+  response: MockResponse<Q, R> | undefined,
+  request: Q,
+  headers: object,
+  callOptions: CallOptions
+): Promise<R> => {
+  calls.push({ method, request, headers, options: callOptions });
+  const latency = typeof options.latency === "function" ? options.latency(method) : options.latency || 0;
+  return new Promise<void>(resolve => setTimeout(resolve, latency)).then(() => {
+    if (response === undefined) {
+      throw new TwirpError({ code: TwirpErrorCode.Unimplemented, msg: "no mock response for " + method });
+    }
+    if (typeof response === "function") {
+      return (<(request: Q, headers: object, options: CallOptions) => R | Promise<R>>response)(request, headers, callOptions);
+    }
+    return response;
+  });
+};
+
+{{if eq .Target "esnext" -}}
+// mockStream is the AsyncIterable of the messages of a streaming method of a
+// mock client.
+export const mockStream = <T>(messages: Promise<T[]>): AsyncIterable<T> => ({
+  [Symbol.asyncIterator]: () => {
+    let i = 0;
+    return {
+      next: () => messages.then(m => <IteratorResult<T>>(i < m.length ? { done: false, value: m[i++] } : { done: true, value: undefined }))
+    };
+  }
+});
+
+{{end -}}
+
+{{end -}}
+{{if .WebSocket -}}
+// WebSocketLike is the part of the WebSocket API used by WebSocketTransport,
+// implemented by browsers and the ws package.
+export interface WebSocketLike {
+  readyState: number;
+  send(data: string): void;
+  addEventListener(type: string, listener: (event: any) => void): void;
+}
+
+interface WebSocketFrame {
+  id: number;
+  status: number;
+  headers?: { [name: string]: string };
+  body?: string;
+}
+
+// WebSocketTransport multiplexes the calls of clients over a WebSocket, see
+// the withWebSocket constructor of clients. Requests and responses are JSON
+// text frames correlated by id:
+//
+//   {"id": 1, "path": "/twirp/acme.Users/GetUser", "headers": {...}, "body": "{...}"}
+//   {"id": 1, "status": 200, "headers": {...}, "body": "{...}"}
+//
+// Calls fail as unavailable when the socket closes or fails, or was closed
+// before they're made. Only text bodies are supported, calls with binary
+// bodies, e.g. compressed, fail as internal.
+export class WebSocketTransport {
+  private nextId = 1;
+  private pending: { [id: number]: (frame: WebSocketFrame | Error) => void } = {};
+  private opened: Promise<void>;
+  private closed = false;
+
+  constructor(private socket: WebSocketLike) {
+    this.opened = new Promise((resolve, reject) => {
+      if (socket.readyState === 1) {
+        resolve();
+      } else if (socket.readyState > 1) {
+        reject(closedError());
+      }
+      socket.addEventListener("open", () => resolve());
+      socket.addEventListener("close", () => reject(closedError()));
+      socket.addEventListener("error", () => reject(closedError()));
+    });
+    // The calls waiting for the socket handle the rejection.
+    this.opened.catch(() => undefined);
+    socket.addEventListener("message", event => {
+      let frame: WebSocketFrame;
+      try {
+        frame = JSON.parse(String(event.data));
+      } catch (e) {
+        return;
+      }
+      const done = this.pending[frame.id];
+      if (done) {
+        delete this.pending[frame.id];
+        done(frame);
+      }
+    });
+    const fail = () => {
+      this.closed = true;
+      const pending = this.pending;
+      this.pending = {};
+      Object.keys(pending).forEach(id => pending[Number(id)](closedError()));
+    };
+    socket.addEventListener("close", fail);
+    socket.addEventListener("error", fail);
+  }
+
+  // fetch sends a request over the socket, it's the fetch of clients created
+  // with withWebSocket.
+  public fetch: Fetch = <any>((input: string, init: any = {}) => {
+    const id = this.nextId++;
+    const signal: AbortSignal | undefined = init.signal;
+    const abort = () => {
+      if (this.pending[id]) {
+        this.pending[id](abortError());
+      }
+    };
+    return new Promise<WebSocketFrame | Error>(resolve => {
+      if (this.closed || this.socket.readyState > 1) {
+        resolve(closedError());
+        return;
+      }
+      if (init.body != null && typeof init.body !== "string") {
+        resolve(new Error("WebSocketTransport only sends text bodies"));
+        return;
+      }
+      if (signal && signal.aborted) {
+        resolve(abortError());
+        return;
+      }
+      this.pending[id] = frame => {
+        delete this.pending[id];
+        if (signal) {
+          signal.removeEventListener("abort", abort);
+        }
+        resolve(frame);
+      };
+      if (signal) {
+        signal.addEventListener("abort", abort, { once: true });
+      }
+      this.opened.then(
+        () => {
+          if (this.pending[id]) {
+            this.socket.send(JSON.stringify({ id, path: String(input), headers: init.headers, body: init.body }));
+          }
+        },
+        err => {
+          if (this.pending[id]) {
+            this.pending[id](err);
+          }
+        }
+      );
+    }).then(frame => {
+      if (frame instanceof Error) {
+        throw frame;
+      }
+      return textResponse(frame.status, frame.headers, frame.body);
+    });
+  });
+}
+
+// closedError is the error of the calls of a closed WebSocketTransport, a
+// TypeError like the network errors of fetch.
+const closedError = (): Error => new TypeError("WebSocket closed");
+
+const abortError = (): Error => {
+  const err = new Error("the call was aborted");
+  err.name = "AbortError";
+  return err;
+};
+
+{{end -}}
+{{if .FetchModule -}}
+// Fetch is the type of the fetch implementation from {{.FetchModule}}.
+export type Fetch = typeof fetch;
+
+export type FetchResponse = Fetch extends (...args: any[]) => Promise<infer R> ? R : never;
+
+// defaultFetch is the fetch of clients created without one.
+export const defaultFetch: Fetch = fetch;
+{{- else -}}
 export type Fetch = (
   input: RequestInfo,
   init?: RequestInit
 ) => Promise<Response>;
+
+// defaultFetch is the fetch of clients created without one, the global fetch
+// is looked up when called so it can be polyfilled later.
+export const defaultFetch: Fetch = (input: RequestInfo, init?: RequestInit) => fetch(input, init);
+{{- end}}
 `
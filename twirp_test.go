@@ -0,0 +1,77 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestRuntime checks the twirp.ts runtime generated with a parameter for the
+// code of features, the golden tests setting runtime compare all of it.
+func TestRuntime(t *testing.T) {
+	tests := []struct {
+		name      string
+		parameter string
+		want      []string
+	}{
+		{"timeout", "", []string{
+			"export class DeadlineExceededError",
+			`signal.removeEventListener("abort", abort);`,
+		}},
+		{"retry", "", []string{
+			`const defaultRetryableCodes = ["unavailable", "deadline_exceeded"];`,
+			"Math.pow(policy.multiplier || 2, n)",
+		}},
+		{"client options", "", []string{
+			"headers: { ...client.headers, ...defaults.headers, ...options.headers }",
+		}},
+		{"streams", "", []string{
+			"if (isTwirpErrorJSON(m)) {",
+		}},
+		{"OpenTelemetry", "", []string{
+			"export const traceFetch = (otel: OpenTelemetry | undefined, fetch: Fetch): Fetch => {",
+		}},
+		{"cache", "", []string{
+			"export class MemoryCacheStore implements CacheStore {",
+			"export const cachedCall = (",
+		}},
+		{"compression", "", []string{
+			`new CompressionStream("gzip")`,
+			`"Content-Encoding": "gzip"`,
+		}},
+		{"server GET", "server=true,http_get=true", []string{
+			`const fields = req.method === "GET" && query && path.indexOf(prefix) === 0 ? query(ctx.method) : undefined;`,
+			`fields ? Promise.resolve().then(() => decodeQuery((req.url || "").split("?")[1] || "", fields)) : readServerRequest(req);`,
+		}},
+		{"twirp v7 errors", "", []string{
+			`redirect: "manual",`,
+			"if (!isTwirpErrorCode(err.code)) {",
+			"return TwirpErrorCode.PermissionDenied;",
+		}},
+		{"twirp v5 errors", "twirp_version=v5", []string{
+			"return TwirpErrorCode.ResourceExhausted;",
+		}},
+		{"client options es5", "target=es5", []string{
+			"headers: assign({}, client.headers, defaults.headers, options.headers)",
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := generate(request(t, tt.parameter, "users.proto"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var runtime string
+			for _, f := range res.File {
+				if f.GetName() == twirpFileName {
+					runtime = f.GetContent()
+				}
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(runtime, want) {
+					t.Errorf("%s generated with %q doesn't contain %q", twirpFileName, tt.parameter, want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,68 @@
+package main
+
+const (
+	emptyTypeName     = ".google.protobuf.Empty"
+	timestampTypeName = ".google.protobuf.Timestamp"
+)
+
+// timestampType returns the TypeScript type used for google.protobuf.Timestamp.
+func timestampType() string {
+	switch params.Timestamp {
+	case "date":
+		return "Date"
+	case "object":
+		return "Timestamp"
+	}
+	return "string"
+}
+
+// plainJSONType returns the TypeScript type used for google.protobuf
+// well-known types and google.type common types whose proto3 JSON
+// representation is a plain JSON value.
+// Fields of these types are passed through as-is by fromJSON.
+func plainJSONType(typeName string) (string, bool) {
+	switch typeName {
+	case ".google.protobuf.Struct":
+		return "{ [key: string]: any }", true
+	case ".google.protobuf.Value":
+		return "null | boolean | number | string | { [key: string]: any } | any[]", true
+	case ".google.protobuf.ListValue":
+		return "any[]", true
+	case emptyTypeName:
+		return "{}", true
+	case ".google.protobuf.FieldMask":
+		// Comma separated lowerCamelCase paths, see createFieldMask in twirp.ts.
+		return "string", true
+
+	// Wrappers are unwrapped to their value, null when unset.
+	case ".google.protobuf.DoubleValue",
+		".google.protobuf.FloatValue",
+		".google.protobuf.Int32Value",
+		".google.protobuf.UInt32Value":
+		return "number | null", true
+	case ".google.protobuf.Int64Value",
+		".google.protobuf.UInt64Value":
+		return params.LongType + " | null", true
+	case ".google.protobuf.BoolValue":
+		return "boolean | null", true
+	case ".google.protobuf.StringValue",
+		".google.protobuf.BytesValue":
+		return "string | null", true
+
+	// Common types from googleapis, represented by their JSON objects.
+	case ".google.type.Date":
+		return "{ year?: number; month?: number; day?: number }", true
+	case ".google.type.TimeOfDay":
+		return "{ hours?: number; minutes?: number; seconds?: number; nanos?: number }", true
+	case ".google.type.LatLng":
+		return "{ latitude?: number; longitude?: number }", true
+	case ".google.type.Money":
+		return "{ currency_code?: string; units?: " + params.LongType + "; nanos?: number }", true
+	}
+	return "", false
+}
+
+func isPlainJSONType(typeName string) bool {
+	_, ok := plainJSONType(typeName)
+	return ok
+}